@@ -65,9 +65,10 @@ func (server *Server) MessageRespond(msg mongo.OpMessage) {
 // Insert hadles OP_INSERT and 'insert' query of OP_MSG or OP_QUERY.
 func (server *Server) Insert(conn *mongo.Conn, q *mongo.Query) (int32, error) {
 	nInserted := int32(0)
+	writeErrs := mongo.NewWriteException()
 
 	docs := q.Documents()
-	for _, doc := range docs {
+	for n, doc := range docs {
 		// See : The _id Field - Documents (https://docs.mongodb.com/manual/core/document/)
 		docValue, err := doc.LookupErr("_id")
 		if err != nil {
@@ -87,13 +88,20 @@ func (server *Server) Insert(conn *mongo.Conn, q *mongo.Query) (int32, error) {
 			}
 		}
 
-		if !isInserted {
-			server.documents = append(server.documents, doc)
+		if isInserted {
+			dupErr := mongo.NewDuplicateKeyError(q.FullCollectionName(), docValue)
+			writeErrs.AddWriteError(mongo.NewWriteError(int32(n), dupErr))
+			continue
 		}
 
+		server.documents = append(server.documents, doc)
 		nInserted++
 	}
 
+	if writeErrs.HasErrors() {
+		return nInserted, writeErrs
+	}
+
 	if len(docs) != int(nInserted) {
 		return nInserted, mongo.NewQueryError(q)
 	}
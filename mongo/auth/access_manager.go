@@ -0,0 +1,41 @@
+// Copyright (C) 2019 The go-mongo Authors. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package auth
+
+// AccessManager represents a role-based access control manager of users and roles.
+type AccessManager interface {
+	// CreateUser creates a new user.
+	CreateUser(user *User) error
+	// UpdateUser replaces the roles of the existing user.
+	UpdateUser(user *User) error
+	// DropUser removes the specified user.
+	DropUser(name UserName) error
+	// LookupUser returns the specified user.
+	LookupUser(name UserName) (*User, bool)
+	// Users returns the users of the specified database, and returns all users if the database is empty.
+	Users(db string) []*User
+	// GrantRolesToUser grants the specified roles to the user.
+	GrantRolesToUser(name UserName, roles ...RoleName) error
+	// RevokeRolesFromUser revokes the specified roles from the user.
+	RevokeRolesFromUser(name UserName, roles ...RoleName) error
+	// CreateRole creates a new custom role.
+	CreateRole(role *Role) error
+	// LookupRole returns the specified custom or built-in role.
+	LookupRole(name RoleName) (*Role, bool)
+	// Roles returns the custom roles of the specified database, and returns all custom roles if the database is empty.
+	Roles(db string) []*Role
+	// IsAllowed returns true if the user is allowed the specified action on the specified resource.
+	IsAllowed(name UserName, res Resource, action Action) bool
+}
@@ -0,0 +1,214 @@
+// Copyright (C) 2019 The go-mongo Authors. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package auth
+
+import (
+	"slices"
+	"sort"
+	"sync"
+)
+
+type accessManager struct {
+	users map[UserName]*User
+	roles map[RoleName]*Role
+	mutex *sync.RWMutex
+}
+
+// NewAccessManager returns a new AccessManager.
+func NewAccessManager() AccessManager {
+	return &accessManager{
+		users: map[UserName]*User{},
+		roles: map[RoleName]*Role{},
+		mutex: &sync.RWMutex{},
+	}
+}
+
+// CreateUser creates a new user.
+func (mgr *accessManager) CreateUser(user *User) error {
+	mgr.mutex.Lock()
+	defer mgr.mutex.Unlock()
+	if _, ok := mgr.users[user.UserName]; ok {
+		return newErrUserExists(user.UserName)
+	}
+	if err := mgr.validateRoles(user.Roles...); err != nil {
+		return err
+	}
+	mgr.users[user.UserName] = user
+	return nil
+}
+
+// UpdateUser replaces the roles of the existing user.
+func (mgr *accessManager) UpdateUser(user *User) error {
+	mgr.mutex.Lock()
+	defer mgr.mutex.Unlock()
+	if _, ok := mgr.users[user.UserName]; !ok {
+		return newErrUserNotFound(user.UserName)
+	}
+	if err := mgr.validateRoles(user.Roles...); err != nil {
+		return err
+	}
+	mgr.users[user.UserName] = user
+	return nil
+}
+
+// DropUser removes the specified user.
+func (mgr *accessManager) DropUser(name UserName) error {
+	mgr.mutex.Lock()
+	defer mgr.mutex.Unlock()
+	if _, ok := mgr.users[name]; !ok {
+		return newErrUserNotFound(name)
+	}
+	delete(mgr.users, name)
+	return nil
+}
+
+// LookupUser returns the specified user.
+func (mgr *accessManager) LookupUser(name UserName) (*User, bool) {
+	mgr.mutex.RLock()
+	defer mgr.mutex.RUnlock()
+	user, ok := mgr.users[name]
+	if !ok {
+		return nil, false
+	}
+	return NewUser(user.UserName, slices.Clone(user.Roles)...), true
+}
+
+// Users returns the users of the specified database, and returns all users if the database is empty.
+func (mgr *accessManager) Users(db string) []*User {
+	mgr.mutex.RLock()
+	defer mgr.mutex.RUnlock()
+	users := []*User{}
+	for _, user := range mgr.users {
+		if len(db) != 0 && user.DB != db {
+			continue
+		}
+		users = append(users, NewUser(user.UserName, slices.Clone(user.Roles)...))
+	}
+	sort.Slice(users, func(i, j int) bool {
+		return users[i].ID() < users[j].ID()
+	})
+	return users
+}
+
+// GrantRolesToUser grants the specified roles to the user.
+func (mgr *accessManager) GrantRolesToUser(name UserName, roles ...RoleName) error {
+	mgr.mutex.Lock()
+	defer mgr.mutex.Unlock()
+	user, ok := mgr.users[name]
+	if !ok {
+		return newErrUserNotFound(name)
+	}
+	if err := mgr.validateRoles(roles...); err != nil {
+		return err
+	}
+	user.GrantRoles(roles...)
+	return nil
+}
+
+// RevokeRolesFromUser revokes the specified roles from the user.
+func (mgr *accessManager) RevokeRolesFromUser(name UserName, roles ...RoleName) error {
+	mgr.mutex.Lock()
+	defer mgr.mutex.Unlock()
+	user, ok := mgr.users[name]
+	if !ok {
+		return newErrUserNotFound(name)
+	}
+	user.RevokeRoles(roles...)
+	return nil
+}
+
+// CreateRole creates a new custom role.
+func (mgr *accessManager) CreateRole(role *Role) error {
+	mgr.mutex.Lock()
+	defer mgr.mutex.Unlock()
+	if _, ok := mgr.lookupRole(role.RoleName); ok {
+		return newErrRoleExists(role.RoleName)
+	}
+	if err := mgr.validateRoles(role.Roles...); err != nil {
+		return err
+	}
+	mgr.roles[role.RoleName] = role
+	return nil
+}
+
+// LookupRole returns the specified custom or built-in role.
+func (mgr *accessManager) LookupRole(name RoleName) (*Role, bool) {
+	mgr.mutex.RLock()
+	defer mgr.mutex.RUnlock()
+	return mgr.lookupRole(name)
+}
+
+// Roles returns the custom roles of the specified database, and returns all custom roles if the database is empty.
+func (mgr *accessManager) Roles(db string) []*Role {
+	mgr.mutex.RLock()
+	defer mgr.mutex.RUnlock()
+	roles := []*Role{}
+	for _, role := range mgr.roles {
+		if len(db) != 0 && role.DB != db {
+			continue
+		}
+		roles = append(roles, role)
+	}
+	sort.Slice(roles, func(i, j int) bool {
+		return roles[i].String() < roles[j].String()
+	})
+	return roles
+}
+
+// IsAllowed returns true if the user is allowed the specified action on the specified resource.
+func (mgr *accessManager) IsAllowed(name UserName, res Resource, action Action) bool {
+	mgr.mutex.RLock()
+	defer mgr.mutex.RUnlock()
+	user, ok := mgr.users[name]
+	if !ok {
+		return false
+	}
+	visited := map[RoleName]bool{}
+	roleNames := append([]RoleName{}, user.Roles...)
+	for 0 < len(roleNames) {
+		roleName := roleNames[0]
+		roleNames = roleNames[1:]
+		if visited[roleName] {
+			continue
+		}
+		visited[roleName] = true
+		role, ok := mgr.lookupRole(roleName)
+		if !ok {
+			continue
+		}
+		if role.Allows(res, action) {
+			return true
+		}
+		roleNames = append(roleNames, role.Roles...)
+	}
+	return false
+}
+
+func (mgr *accessManager) lookupRole(name RoleName) (*Role, bool) {
+	if role, ok := NewBuiltinRole(name); ok {
+		return role, true
+	}
+	role, ok := mgr.roles[name]
+	return role, ok
+}
+
+func (mgr *accessManager) validateRoles(names ...RoleName) error {
+	for _, name := range names {
+		if _, ok := mgr.lookupRole(name); !ok {
+			return newErrRoleNotFound(name)
+		}
+	}
+	return nil
+}
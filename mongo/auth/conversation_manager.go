@@ -0,0 +1,79 @@
+// Copyright (C) 2019 The go-mongo Authors. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package auth
+
+import (
+	"time"
+
+	"github.com/cybergarage/go-mongo/mongo/auth/sasl"
+)
+
+const (
+	// DefaultConversationTimeout is the default timeout of half-finished SASL conversations.
+	DefaultConversationTimeout = time.Minute
+	// DefaultFailedAttemptLimit is the default number of failed attempts before backing off.
+	DefaultFailedAttemptLimit = 5
+	// DefaultFailedAttemptBackoff is the default initial back-off after the failed attempt limit is reached.
+	DefaultFailedAttemptBackoff = time.Second
+	// MaxFailedAttemptBackoff is the maximum back-off, and failed attempts older than it are forgotten.
+	MaxFailedAttemptBackoff = 5 * time.Minute
+	// MaxFailedAttemptDelay is the maximum delay of the failure responses of a user.
+	MaxFailedAttemptDelay = 10 * time.Second
+)
+
+// Conversation represents a SASL conversation in progress on a connection.
+type Conversation struct {
+	// ID is the conversation ID which is unique in the server.
+	ID int32
+	// ConnID is the ID of the connection which started the conversation.
+	ConnID string
+	// User is the user name requested by the client, which may be empty.
+	User UserName
+	// Context is the SASL mechanism context.
+	Context sasl.SASLContext
+	// Timestamp is the start time of the conversation.
+	Timestamp time.Time
+}
+
+// ConversationManager represents a manager of SASL conversations and failed authentication attempts.
+type ConversationManager interface {
+	// SetConversationTimeout sets the timeout of half-finished conversations.
+	SetConversationTimeout(d time.Duration)
+	// ConversationTimeout returns the timeout of half-finished conversations.
+	ConversationTimeout() time.Duration
+	// SetFailedAttemptLimit sets the number of failed attempts per connection and per user before backing off, and zero disables the limit.
+	SetFailedAttemptLimit(n int)
+	// FailedAttemptLimit returns the number of failed attempts before backing off.
+	FailedAttemptLimit() int
+	// SetFailedAttemptBackoff sets the initial back-off, which doubles on each further failed attempt.
+	SetFailedAttemptBackoff(d time.Duration)
+	// FailedAttemptBackoff returns the initial back-off.
+	FailedAttemptBackoff() time.Duration
+	// StartConversation starts a new conversation of the connection with a new conversation ID, and replaces the previous one.
+	StartConversation(connID string, user UserName, ctx sasl.SASLContext) *Conversation
+	// Conversation returns the conversation of the connection with the specified ID.
+	Conversation(connID string, id int32) (*Conversation, error)
+	// FinishConversation removes the conversation of the connection.
+	FinishConversation(connID string)
+	// CheckAttempt returns an error if the connection is backing off after failed attempts.
+	CheckAttempt(connID string) error
+	// RecordFailure records a failed attempt of the connection and the user, and returns the delay of the failure response.
+	// The delay of the user backs off without locking out the other clients of the user.
+	RecordFailure(connID string, user UserName) time.Duration
+	// RecordSuccess clears the failed attempts of the connection and the user.
+	RecordSuccess(connID string, user UserName)
+	// RemoveConnection removes the conversation and the failed attempts of the closed connection.
+	RemoveConnection(connID string)
+}
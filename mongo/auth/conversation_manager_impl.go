@@ -0,0 +1,233 @@
+// Copyright (C) 2019 The go-mongo Authors. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package auth
+
+import (
+	"sync"
+	"time"
+
+	"github.com/cybergarage/go-mongo/mongo/auth/sasl"
+)
+
+// failedAttempts represents failed authentication attempts of a connection or a user.
+type failedAttempts struct {
+	count       int
+	lastFailure time.Time
+	lockedUntil time.Time
+}
+
+type conversationManager struct {
+	counter       *sasl.Counter
+	conversations map[string]*Conversation
+	connFailures  map[string]*failedAttempts
+	userFailures  map[string]*failedAttempts
+	timeout       time.Duration
+	attemptLimit  int
+	backoff       time.Duration
+	now           func() time.Time
+	mutex         *sync.Mutex
+}
+
+// NewConversationManager returns a new ConversationManager.
+func NewConversationManager() ConversationManager {
+	return &conversationManager{
+		counter:       sasl.NewCounter(),
+		conversations: map[string]*Conversation{},
+		connFailures:  map[string]*failedAttempts{},
+		userFailures:  map[string]*failedAttempts{},
+		timeout:       DefaultConversationTimeout,
+		attemptLimit:  DefaultFailedAttemptLimit,
+		backoff:       DefaultFailedAttemptBackoff,
+		now:           time.Now,
+		mutex:         &sync.Mutex{},
+	}
+}
+
+// SetConversationTimeout sets the timeout of half-finished conversations.
+func (mgr *conversationManager) SetConversationTimeout(d time.Duration) {
+	mgr.mutex.Lock()
+	defer mgr.mutex.Unlock()
+	mgr.timeout = d
+}
+
+// ConversationTimeout returns the timeout of half-finished conversations.
+func (mgr *conversationManager) ConversationTimeout() time.Duration {
+	mgr.mutex.Lock()
+	defer mgr.mutex.Unlock()
+	return mgr.timeout
+}
+
+// SetFailedAttemptLimit sets the number of failed attempts before backing off.
+func (mgr *conversationManager) SetFailedAttemptLimit(n int) {
+	mgr.mutex.Lock()
+	defer mgr.mutex.Unlock()
+	mgr.attemptLimit = n
+}
+
+// FailedAttemptLimit returns the number of failed attempts before backing off.
+func (mgr *conversationManager) FailedAttemptLimit() int {
+	mgr.mutex.Lock()
+	defer mgr.mutex.Unlock()
+	return mgr.attemptLimit
+}
+
+// SetFailedAttemptBackoff sets the initial back-off.
+func (mgr *conversationManager) SetFailedAttemptBackoff(d time.Duration) {
+	mgr.mutex.Lock()
+	defer mgr.mutex.Unlock()
+	mgr.backoff = d
+}
+
+// FailedAttemptBackoff returns the initial back-off.
+func (mgr *conversationManager) FailedAttemptBackoff() time.Duration {
+	mgr.mutex.Lock()
+	defer mgr.mutex.Unlock()
+	return mgr.backoff
+}
+
+// StartConversation starts a new conversation of the connection.
+func (mgr *conversationManager) StartConversation(connID string, user UserName, ctx sasl.SASLContext) *Conversation {
+	conv := &Conversation{
+		ID:        mgr.counter.Inc(),
+		ConnID:    connID,
+		User:      user,
+		Context:   ctx,
+		Timestamp: mgr.now(),
+	}
+	mgr.mutex.Lock()
+	defer mgr.mutex.Unlock()
+	mgr.removeExpired()
+	mgr.conversations[connID] = conv
+	return conv
+}
+
+// Conversation returns the conversation of the connection with the specified ID.
+func (mgr *conversationManager) Conversation(connID string, id int32) (*Conversation, error) {
+	mgr.mutex.Lock()
+	defer mgr.mutex.Unlock()
+	conv, ok := mgr.conversations[connID]
+	if !ok || conv.ID != id {
+		return nil, newErrConversationNotFound(id)
+	}
+	if mgr.isExpired(conv) {
+		delete(mgr.conversations, connID)
+		return nil, newErrConversationExpired(id)
+	}
+	return conv, nil
+}
+
+// FinishConversation removes the conversation of the connection.
+func (mgr *conversationManager) FinishConversation(connID string) {
+	mgr.mutex.Lock()
+	defer mgr.mutex.Unlock()
+	delete(mgr.conversations, connID)
+}
+
+// CheckAttempt returns an error if the connection is backing off after failed attempts.
+func (mgr *conversationManager) CheckAttempt(connID string) error {
+	mgr.mutex.Lock()
+	defer mgr.mutex.Unlock()
+	now := mgr.now()
+	if attempts, ok := mgr.connFailures[connID]; ok && now.Before(attempts.lockedUntil) {
+		return newErrTooManyFailedAttempts(attempts.lockedUntil.Sub(now))
+	}
+	return nil
+}
+
+// RecordFailure records a failed attempt of the connection and the user, and returns the delay of the failure response.
+func (mgr *conversationManager) RecordFailure(connID string, user UserName) time.Duration {
+	mgr.mutex.Lock()
+	defer mgr.mutex.Unlock()
+	var delay time.Duration
+	if len(user.User) != 0 {
+		delay = mgr.backoffOf(mgr.recordFailure(mgr.userFailures, user.ID()), MaxFailedAttemptDelay)
+	}
+	// The back-off of the connection starts after the delayed failure response.
+	attempts := mgr.recordFailure(mgr.connFailures, connID)
+	if backoff := mgr.backoffOf(attempts, MaxFailedAttemptBackoff); 0 < backoff {
+		attempts.lockedUntil = mgr.now().Add(delay + backoff)
+	}
+	return delay
+}
+
+// RecordSuccess clears the failed attempts of the connection and the user.
+func (mgr *conversationManager) RecordSuccess(connID string, user UserName) {
+	mgr.mutex.Lock()
+	defer mgr.mutex.Unlock()
+	delete(mgr.connFailures, connID)
+	if len(user.User) != 0 {
+		delete(mgr.userFailures, user.ID())
+	}
+}
+
+// RemoveConnection removes the conversation and the failed attempts of the closed connection.
+func (mgr *conversationManager) RemoveConnection(connID string) {
+	mgr.mutex.Lock()
+	defer mgr.mutex.Unlock()
+	delete(mgr.conversations, connID)
+	delete(mgr.connFailures, connID)
+}
+
+func (mgr *conversationManager) recordFailure(failures map[string]*failedAttempts, key string) *failedAttempts {
+	now := mgr.now()
+	attempts, ok := failures[key]
+	if !ok || MaxFailedAttemptBackoff < now.Sub(attempts.lastFailure) {
+		attempts = &failedAttempts{
+			count:       0,
+			lastFailure: time.Time{},
+			lockedUntil: time.Time{},
+		}
+		failures[key] = attempts
+	}
+	attempts.count++
+	attempts.lastFailure = now
+	return attempts
+}
+
+// backoffOf returns the back-off of the failed attempts which doubles on each failed attempt after the limit.
+func (mgr *conversationManager) backoffOf(attempts *failedAttempts, maxBackoff time.Duration) time.Duration {
+	if mgr.attemptLimit <= 0 || attempts.count < mgr.attemptLimit {
+		return 0
+	}
+	backoff := mgr.backoff
+	for n := mgr.attemptLimit; n < attempts.count && backoff < maxBackoff; n++ {
+		backoff *= 2
+	}
+	return min(backoff, maxBackoff)
+}
+
+func (mgr *conversationManager) isExpired(conv *Conversation) bool {
+	return 0 < mgr.timeout && mgr.timeout <= mgr.now().Sub(conv.Timestamp)
+}
+
+// removeExpired removes the expired conversations and the forgotten failed attempts.
+func (mgr *conversationManager) removeExpired() {
+	for connID, conv := range mgr.conversations {
+		if mgr.isExpired(conv) {
+			delete(mgr.conversations, connID)
+		}
+	}
+	now := mgr.now()
+	for _, failures := range []map[string]*failedAttempts{mgr.connFailures, mgr.userFailures} {
+		for key, attempts := range failures {
+			if now.Before(attempts.lockedUntil) {
+				continue
+			}
+			if MaxFailedAttemptBackoff < now.Sub(attempts.lastFailure) {
+				delete(failures, key)
+			}
+		}
+	}
+}
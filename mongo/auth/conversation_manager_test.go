@@ -0,0 +1,193 @@
+// Copyright (C) 2022 The go-mongo Authors All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package auth
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+// newTestConversationManager returns a conversation manager of the clock which is advanced by the returned function.
+func newTestConversationManager() (*conversationManager, func(d time.Duration)) {
+	mgr, _ := NewConversationManager().(*conversationManager)
+	now := time.Now()
+	mgr.now = func() time.Time { return now }
+	return mgr, func(d time.Duration) { now = now.Add(d) }
+}
+
+func TestConversationManagerConversation(t *testing.T) {
+	mgr, advance := newTestConversationManager()
+	mgr.SetConversationTimeout(time.Minute)
+
+	user := NewUserName("alice", AdminDatabase)
+	conv := mgr.StartConversation("conn1", user, nil)
+	if conv.ConnID != "conn1" || conv.User != user {
+		t.Errorf("%s (%s) != %s (%s)", conv.ConnID, conv.User, "conn1", user)
+	}
+
+	// Conversations are bound to the connection and the ID
+
+	if _, err := mgr.Conversation("conn1", conv.ID); err != nil {
+		t.Error(err)
+	}
+	if _, err := mgr.Conversation("conn2", conv.ID); !errors.Is(err, ErrConversationNotFound) {
+		t.Errorf("%v != %s", err, ErrConversationNotFound)
+	}
+	if _, err := mgr.Conversation("conn1", conv.ID+1); !errors.Is(err, ErrConversationNotFound) {
+		t.Errorf("%v != %s", err, ErrConversationNotFound)
+	}
+
+	// A new conversation replaces the previous one
+
+	newConv := mgr.StartConversation("conn1", user, nil)
+	if newConv.ID == conv.ID {
+		t.Errorf("%d == %d", newConv.ID, conv.ID)
+	}
+	if _, err := mgr.Conversation("conn1", conv.ID); !errors.Is(err, ErrConversationNotFound) {
+		t.Errorf("%v != %s", err, ErrConversationNotFound)
+	}
+
+	// Half-finished conversations expire after the timeout
+
+	advance(time.Minute)
+	if _, err := mgr.Conversation("conn1", newConv.ID); !errors.Is(err, ErrConversationExpired) {
+		t.Errorf("%v != %s", err, ErrConversationExpired)
+	}
+
+	// Finished and removed conversations are not found
+
+	conv = mgr.StartConversation("conn1", user, nil)
+	mgr.FinishConversation("conn1")
+	if _, err := mgr.Conversation("conn1", conv.ID); !errors.Is(err, ErrConversationNotFound) {
+		t.Errorf("%v != %s", err, ErrConversationNotFound)
+	}
+	conv = mgr.StartConversation("conn1", user, nil)
+	mgr.RemoveConnection("conn1")
+	if _, err := mgr.Conversation("conn1", conv.ID); !errors.Is(err, ErrConversationNotFound) {
+		t.Errorf("%v != %s", err, ErrConversationNotFound)
+	}
+}
+
+func TestConversationManagerFailedAttempts(t *testing.T) {
+	mgr, advance := newTestConversationManager()
+	mgr.SetFailedAttemptLimit(2)
+	mgr.SetFailedAttemptBackoff(time.Second)
+
+	anonymous := NewUserName("", "")
+	alice := NewUserName("alice", AdminDatabase)
+	aliceTest := NewUserName("alice", "test")
+
+	// Failed attempts of the connection back off after the limit, and the back-off doubles
+
+	mgr.RecordFailure("conn1", anonymous)
+	if err := mgr.CheckAttempt("conn1"); err != nil {
+		t.Error(err)
+	}
+	mgr.RecordFailure("conn1", anonymous)
+	if err := mgr.CheckAttempt("conn1"); !errors.Is(err, ErrTooManyFailedAttempts) {
+		t.Errorf("%v != %s", err, ErrTooManyFailedAttempts)
+	}
+	advance(time.Second)
+	if err := mgr.CheckAttempt("conn1"); err != nil {
+		t.Error(err)
+	}
+	mgr.RecordFailure("conn1", anonymous)
+	advance(time.Second)
+	if err := mgr.CheckAttempt("conn1"); !errors.Is(err, ErrTooManyFailedAttempts) {
+		t.Errorf("back-off is not doubled : %v", err)
+	}
+
+	// Other connections from the same host are not locked out
+
+	if err := mgr.CheckAttempt("conn2"); err != nil {
+		t.Error(err)
+	}
+
+	// Failed attempts are removed with the connection
+
+	mgr.RemoveConnection("conn1")
+	if err := mgr.CheckAttempt("conn1"); err != nil {
+		t.Error(err)
+	}
+
+	// Failed attempts of the user delay the failure responses per database from any connections
+
+	if d := mgr.RecordFailure("conn2", alice); d != 0 {
+		t.Errorf("%s != %s", d, time.Duration(0))
+	}
+	if d := mgr.RecordFailure("conn3", alice); d != time.Second {
+		t.Errorf("%s != %s", d, time.Second)
+	}
+	if d := mgr.RecordFailure("conn4", aliceTest); d != 0 {
+		t.Errorf("%s != %s", d, time.Duration(0))
+	}
+	if err := mgr.CheckAttempt("conn3"); err != nil {
+		t.Error(err)
+	}
+
+	// The back-off of the connection starts after the delayed failure response
+
+	if d := mgr.RecordFailure("conn2", alice); d != 2*time.Second {
+		t.Errorf("%s != %s", d, 2*time.Second)
+	}
+	advance(2 * time.Second)
+	if err := mgr.CheckAttempt("conn2"); !errors.Is(err, ErrTooManyFailedAttempts) {
+		t.Errorf("%v != %s", err, ErrTooManyFailedAttempts)
+	}
+	advance(time.Second)
+	if err := mgr.CheckAttempt("conn2"); err != nil {
+		t.Error(err)
+	}
+
+	for n := 0; n < 10; n++ {
+		mgr.RecordFailure("conn5", alice)
+	}
+	if d := mgr.RecordFailure("conn6", alice); d != MaxFailedAttemptDelay {
+		t.Errorf("%s != %s", d, MaxFailedAttemptDelay)
+	}
+
+	// A successful attempt clears the failed attempts
+
+	mgr.RecordSuccess("conn5", alice)
+	if err := mgr.CheckAttempt("conn5"); err != nil {
+		t.Error(err)
+	}
+	if d := mgr.RecordFailure("conn5", alice); d != 0 {
+		t.Errorf("%s != %s", d, time.Duration(0))
+	}
+
+	// Failed attempts are forgotten after the maximum back-off
+
+	advance(MaxFailedAttemptBackoff + time.Second)
+	if d := mgr.RecordFailure("conn5", alice); d != 0 {
+		t.Errorf("%s != %s", d, time.Duration(0))
+	}
+	if err := mgr.CheckAttempt("conn5"); err != nil {
+		t.Error(err)
+	}
+
+	// Zero disables the limit
+
+	mgr.SetFailedAttemptLimit(0)
+	for n := 0; n < 10; n++ {
+		if d := mgr.RecordFailure("conn7", aliceTest); d != 0 {
+			t.Errorf("%s != %s", d, time.Duration(0))
+		}
+	}
+	if err := mgr.CheckAttempt("conn7"); err != nil {
+		t.Error(err)
+	}
+}
@@ -0,0 +1,30 @@
+// Copyright (C) 2019 The go-mongo Authors. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package auth
+
+import (
+	"github.com/cybergarage/go-authenticator/auth"
+)
+
+// CredentialStore represents a store of user credentials.
+type CredentialStore = auth.CredentialStore
+
+// CredentialUpdater represents a credential store which can update the credentials of users.
+type CredentialUpdater interface {
+	// UpdateCredential sets the credential of the specified user with the password.
+	UpdateCredential(name UserName, password string) error
+	// RemoveCredential removes the credential of the specified user.
+	RemoveCredential(name UserName) error
+}
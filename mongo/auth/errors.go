@@ -0,0 +1,62 @@
+// Copyright (C) 2019 The go-mongo Authors. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package auth
+
+import (
+	"errors"
+	"fmt"
+	"time"
+)
+
+var ErrUserNotFound = errors.New("user not found")
+var ErrUserExists = errors.New("user already exists")
+var ErrRoleNotFound = errors.New("role not found")
+var ErrRoleExists = errors.New("role already exists")
+var ErrUnsupportedMechanism = errors.New("unsupported mechanism")
+var ErrConversationNotFound = errors.New("SASL conversation not found")
+var ErrConversationExpired = errors.New("SASL conversation expired")
+var ErrTooManyFailedAttempts = errors.New("too many failed authentication attempts")
+
+func newErrUserNotFound(name UserName) error {
+	return fmt.Errorf("%s %w", name, ErrUserNotFound)
+}
+
+func newErrUserExists(name UserName) error {
+	return fmt.Errorf("%s %w", name, ErrUserExists)
+}
+
+func newErrRoleNotFound(name RoleName) error {
+	return fmt.Errorf("%s %w", name, ErrRoleNotFound)
+}
+
+func newErrRoleExists(name RoleName) error {
+	return fmt.Errorf("%s %w", name, ErrRoleExists)
+}
+
+func newErrUnsupportedMechanism(name string) error {
+	return fmt.Errorf("%s %w", name, ErrUnsupportedMechanism)
+}
+
+func newErrConversationNotFound(id int32) error {
+	return fmt.Errorf("%w (%d)", ErrConversationNotFound, id)
+}
+
+func newErrConversationExpired(id int32) error {
+	return fmt.Errorf("%w (%d)", ErrConversationExpired, id)
+}
+
+func newErrTooManyFailedAttempts(retryAfter time.Duration) error {
+	return fmt.Errorf("%w, retry after %s", ErrTooManyFailedAttempts, retryAfter.Round(time.Millisecond))
+}
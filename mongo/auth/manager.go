@@ -16,11 +16,21 @@ package auth
 
 import (
 	"github.com/cybergarage/go-authenticator/auth"
-	"github.com/cybergarage/go-mongo/mongo/auth/sasl"
+	"github.com/cybergarage/go-mongo/mongo/auth/sasl/oidc"
+	"github.com/cybergarage/go-mongo/mongo/auth/sasl/plain"
 )
 
 // Manager represents an authenticator manager.
 type Manager interface {
 	auth.Manager
-	ConversationCounter() *sasl.Counter
+	// ConversationManager returns the manager of SASL conversations and failed authentication attempts.
+	ConversationManager() ConversationManager
+	// SetPlainVerifier sets the verifier of PLAIN credentials, and PLAIN is supported only when the verifier is set.
+	SetPlainVerifier(verifier plain.Verifier)
+	// PlainVerifier returns the verifier of PLAIN credentials.
+	PlainVerifier() plain.Verifier
+	// SetOIDCVerifier sets the verifier of OIDC tokens, and MONGODB-OIDC is supported only when the verifier is set.
+	SetOIDCVerifier(verifier *oidc.Verifier)
+	// OIDCVerifier returns the verifier of OIDC tokens.
+	OIDCVerifier() *oidc.Verifier
 }
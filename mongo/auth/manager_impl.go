@@ -16,23 +16,102 @@ package auth
 
 import (
 	"github.com/cybergarage/go-authenticator/auth"
-	"github.com/cybergarage/go-mongo/mongo/auth/sasl"
+	"github.com/cybergarage/go-mongo/mongo/auth/sasl/oidc"
+	"github.com/cybergarage/go-mongo/mongo/auth/sasl/plain"
+	"github.com/cybergarage/go-mongo/mongo/auth/sasl/scram"
 )
 
 type manager struct {
 	auth.Manager
-	conversationCounter *sasl.Counter
+	conversationManager ConversationManager
+	plainVerifier       plain.Verifier
+	oidcVerifier        *oidc.Verifier
 }
 
 // NewManager returns a new Manager.
 func NewManager() Manager {
 	return &manager{
 		Manager:             auth.NewManager(),
-		conversationCounter: sasl.NewCounter(),
+		conversationManager: NewConversationManager(),
+		plainVerifier:       nil,
+		oidcVerifier:        nil,
 	}
 }
 
-// ConversationCounter returns a conversation counter.
-func (mgr *manager) ConversationCounter() *sasl.Counter {
-	return mgr.conversationCounter
+// ConversationManager returns the manager of SASL conversations and failed authentication attempts.
+func (mgr *manager) ConversationManager() ConversationManager {
+	return mgr.conversationManager
+}
+
+// SetPlainVerifier sets the verifier of PLAIN credentials.
+func (mgr *manager) SetPlainVerifier(verifier plain.Verifier) {
+	mgr.plainVerifier = verifier
+}
+
+// PlainVerifier returns the verifier of PLAIN credentials.
+func (mgr *manager) PlainVerifier() plain.Verifier {
+	return mgr.plainVerifier
+}
+
+// SetOIDCVerifier sets the verifier of OIDC tokens.
+func (mgr *manager) SetOIDCVerifier(verifier *oidc.Verifier) {
+	mgr.oidcVerifier = verifier
+}
+
+// OIDCVerifier returns the verifier of OIDC tokens.
+func (mgr *manager) OIDCVerifier() *oidc.Verifier {
+	return mgr.oidcVerifier
+}
+
+// Mechanisms returns the mechanisms.
+func (mgr *manager) Mechanisms() []auth.Mechanism {
+	mechs := []auth.Mechanism{}
+	for _, mech := range mgr.Manager.Mechanisms() {
+		if mech.Name() == plain.Mechanism {
+			continue
+		}
+		mechs = append(mechs, mgr.storedCredentialMechanism(mech))
+	}
+	if mgr.plainVerifier != nil {
+		mechs = append(mechs, plain.NewServer(mgr.plainVerifier))
+	}
+	if mgr.oidcVerifier != nil {
+		mechs = append(mechs, oidc.NewServer(mgr.oidcVerifier))
+	}
+	return mechs
+}
+
+// Mechanism returns a mechanism by name.
+func (mgr *manager) Mechanism(name string) (auth.Mechanism, error) {
+	switch name {
+	case plain.Mechanism:
+		if mgr.plainVerifier == nil {
+			return nil, newErrUnsupportedMechanism(name)
+		}
+		return plain.NewServer(mgr.plainVerifier), nil
+	case oidc.Mechanism:
+		if mgr.oidcVerifier == nil {
+			return nil, newErrUnsupportedMechanism(name)
+		}
+		return oidc.NewServer(mgr.oidcVerifier), nil
+	}
+	mech, err := mgr.Manager.Mechanism(name)
+	if err != nil {
+		return nil, err
+	}
+	return mgr.storedCredentialMechanism(mech), nil
+}
+
+// storedCredentialMechanism returns the SCRAM mechanism verifying stored credentials instead of the specified mechanism
+// when the credential store has SCRAM credentials.
+func (mgr *manager) storedCredentialMechanism(mech auth.Mechanism) auth.Mechanism {
+	store, ok := mgr.CredentialStore().(scram.CredentialStore)
+	if !ok {
+		return mech
+	}
+	scramMech, err := scram.NewServer(mech.Name(), store)
+	if err != nil {
+		return mech
+	}
+	return scramMech
 }
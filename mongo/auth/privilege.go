@@ -0,0 +1,147 @@
+// Copyright (C) 2019 The go-mongo Authors. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package auth
+
+// See : Privilege Actions - MongoDB Manual
+// https://www.mongodb.com/docs/manual/reference/privilege-actions/
+
+// Action represents a privilege action.
+type Action string
+
+const (
+	ActionFind                   Action = "find"
+	ActionInsert                 Action = "insert"
+	ActionUpdate                 Action = "update"
+	ActionRemove                 Action = "remove"
+	ActionKillCursors            Action = "killCursors"
+	ActionListCollections        Action = "listCollections"
+	ActionListIndexes            Action = "listIndexes"
+	ActionCollStats              Action = "collStats"
+	ActionDBStats                Action = "dbStats"
+	ActionCreateCollection       Action = "createCollection"
+	ActionDropCollection         Action = "dropCollection"
+	ActionCreateIndex            Action = "createIndex"
+	ActionDropIndex              Action = "dropIndex"
+	ActionDropDatabase           Action = "dropDatabase"
+	ActionRenameCollectionSameDB Action = "renameCollectionSameDB"
+	ActionCollMod                Action = "collMod"
+	ActionCreateUser             Action = "createUser"
+	ActionDropUser               Action = "dropUser"
+	ActionViewUser               Action = "viewUser"
+	ActionChangePassword         Action = "changePassword"
+	ActionChangeCustomData       Action = "changeCustomData"
+	ActionCreateRole             Action = "createRole"
+	ActionDropRole               Action = "dropRole"
+	ActionViewRole               Action = "viewRole"
+	ActionGrantRole              Action = "grantRole"
+	ActionRevokeRole             Action = "revokeRole"
+	ActionKillOp                 Action = "killop"
+	ActionInprog                 Action = "inprog"
+	ActionAny                    Action = "anyAction"
+)
+
+var actions = map[Action]bool{
+	ActionFind:                   true,
+	ActionInsert:                 true,
+	ActionUpdate:                 true,
+	ActionRemove:                 true,
+	ActionKillCursors:            true,
+	ActionListCollections:        true,
+	ActionListIndexes:            true,
+	ActionCollStats:              true,
+	ActionDBStats:                true,
+	ActionCreateCollection:       true,
+	ActionDropCollection:         true,
+	ActionCreateIndex:            true,
+	ActionDropIndex:              true,
+	ActionDropDatabase:           true,
+	ActionRenameCollectionSameDB: true,
+	ActionCollMod:                true,
+	ActionCreateUser:             true,
+	ActionDropUser:               true,
+	ActionViewUser:               true,
+	ActionChangePassword:         true,
+	ActionChangeCustomData:       true,
+	ActionCreateRole:             true,
+	ActionDropRole:               true,
+	ActionViewRole:               true,
+	ActionGrantRole:              true,
+	ActionRevokeRole:             true,
+	ActionKillOp:                 true,
+	ActionInprog:                 true,
+	ActionAny:                    true,
+}
+
+// IsValid returns true if the action is a known privilege action.
+func (action Action) IsValid() bool {
+	return actions[action]
+}
+
+// Resource represents a privilege resource, an empty database or collection matches any database or collection.
+type Resource struct {
+	DB         string
+	Collection string
+}
+
+// NewResource returns a new resource of the specified database and collection.
+func NewResource(db string, collection string) Resource {
+	return Resource{
+		DB:         db,
+		Collection: collection,
+	}
+}
+
+// NewDatabaseResource returns a new resource of all collections in the specified database.
+func NewDatabaseResource(db string) Resource {
+	return NewResource(db, "")
+}
+
+// Contains returns true if the resource contains the specified resource.
+func (res Resource) Contains(other Resource) bool {
+	if len(res.DB) != 0 && res.DB != other.DB {
+		return false
+	}
+	if len(res.Collection) != 0 && res.Collection != other.Collection {
+		return false
+	}
+	return true
+}
+
+// Privilege represents a set of actions on a resource.
+type Privilege struct {
+	Resource Resource
+	Actions  []Action
+}
+
+// NewPrivilege returns a new privilege of the specified resource and actions.
+func NewPrivilege(res Resource, actions ...Action) Privilege {
+	return Privilege{
+		Resource: res,
+		Actions:  actions,
+	}
+}
+
+// Allows returns true if the privilege allows the specified action on the specified resource.
+func (privilege Privilege) Allows(res Resource, action Action) bool {
+	if !privilege.Resource.Contains(res) {
+		return false
+	}
+	for _, a := range privilege.Actions {
+		if a == action || a == ActionAny {
+			return true
+		}
+	}
+	return false
+}
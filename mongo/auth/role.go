@@ -0,0 +1,174 @@
+// Copyright (C) 2019 The go-mongo Authors. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package auth
+
+import (
+	"fmt"
+)
+
+// See : Built-In Roles - MongoDB Manual
+// https://www.mongodb.com/docs/manual/reference/built-in-roles/
+
+const (
+	RoleRead      = "read"
+	RoleReadWrite = "readWrite"
+	RoleDBAdmin   = "dbAdmin"
+	RoleUserAdmin = "userAdmin"
+	RoleRoot      = "root"
+)
+
+const (
+	AdminDatabase    = "admin"
+	ExternalDatabase = "$external"
+)
+
+var readActions = []Action{
+	ActionFind,
+	ActionKillCursors,
+	ActionListCollections,
+	ActionListIndexes,
+	ActionCollStats,
+	ActionDBStats,
+}
+
+var readWriteActions = append([]Action{
+	ActionInsert,
+	ActionUpdate,
+	ActionRemove,
+	ActionCreateCollection,
+	ActionDropCollection,
+	ActionCreateIndex,
+	ActionDropIndex,
+	ActionRenameCollectionSameDB,
+}, readActions...)
+
+var dbAdminActions = []Action{
+	ActionListCollections,
+	ActionListIndexes,
+	ActionCollStats,
+	ActionDBStats,
+	ActionCreateCollection,
+	ActionDropCollection,
+	ActionCreateIndex,
+	ActionDropIndex,
+	ActionDropDatabase,
+	ActionRenameCollectionSameDB,
+	ActionCollMod,
+}
+
+var userAdminActions = []Action{
+	ActionCreateUser,
+	ActionDropUser,
+	ActionViewUser,
+	ActionChangePassword,
+	ActionChangeCustomData,
+	ActionCreateRole,
+	ActionDropRole,
+	ActionViewRole,
+	ActionGrantRole,
+	ActionRevokeRole,
+}
+
+var builtinRoleActions = map[string][]Action{
+	RoleRead:      readActions,
+	RoleReadWrite: readWriteActions,
+	RoleDBAdmin:   dbAdminActions,
+	RoleUserAdmin: userAdminActions,
+}
+
+// RoleName represents a role name which is defined in a database.
+type RoleName struct {
+	Role string
+	DB   string
+}
+
+// NewRoleName returns a new role name of the specified role and database.
+func NewRoleName(role string, db string) RoleName {
+	return RoleName{
+		Role: role,
+		DB:   db,
+	}
+}
+
+// String returns the string description of the role name.
+func (name RoleName) String() string {
+	return fmt.Sprintf("%s@%s", name.Role, name.DB)
+}
+
+// Role represents a role which has privileges and inherited roles.
+type Role struct {
+	RoleName
+	Privileges []Privilege
+	Roles      []RoleName
+	isBuiltin  bool
+}
+
+// NewRole returns a new custom role with the specified privileges and inherited roles.
+func NewRole(name RoleName, privileges []Privilege, roles []RoleName) *Role {
+	return &Role{
+		RoleName:   name,
+		Privileges: privileges,
+		Roles:      roles,
+		isBuiltin:  false,
+	}
+}
+
+// NewBuiltinRole returns the built-in role of the specified name, and returns false if the role is not a built-in role.
+func NewBuiltinRole(name RoleName) (*Role, bool) {
+	if name.Role == RoleRoot {
+		if name.DB != AdminDatabase {
+			return nil, false
+		}
+		role := NewRole(name, []Privilege{NewPrivilege(NewResource("", ""), ActionAny)}, []RoleName{})
+		role.isBuiltin = true
+		return role, true
+	}
+	actions, ok := builtinRoleActions[name.Role]
+	if !ok {
+		return nil, false
+	}
+	role := NewRole(name, []Privilege{NewPrivilege(NewDatabaseResource(name.DB), actions...)}, []RoleName{})
+	role.isBuiltin = true
+	return role, true
+}
+
+// IsBuiltin returns true if the role is a built-in role.
+func (role *Role) IsBuiltin() bool {
+	return role.isBuiltin
+}
+
+// Allows returns true if the privileges of the role allow the specified action on the specified resource.
+func (role *Role) Allows(res Resource, action Action) bool {
+	for _, privilege := range role.Privileges {
+		if privilege.Allows(res, action) {
+			return true
+		}
+	}
+	return false
+}
+
+// BuiltinRoleNames returns the built-in role names of the specified database.
+func BuiltinRoleNames(db string) []RoleName {
+	names := []RoleName{
+		NewRoleName(RoleRead, db),
+		NewRoleName(RoleReadWrite, db),
+		NewRoleName(RoleDBAdmin, db),
+		NewRoleName(RoleUserAdmin, db),
+	}
+	if db == AdminDatabase {
+		names = append(names, NewRoleName(RoleRoot, db))
+	}
+	return names
+}
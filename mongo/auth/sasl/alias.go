@@ -66,3 +66,6 @@ type SASLChallenge = sasl.Challenge
 
 // Salt represents a salt.
 type SASLSalt = sasl.Salt
+
+// SASLContext represents a SASL mechanism context.
+type SASLContext = sasl.Context
@@ -16,17 +16,20 @@ package sasl
 
 import (
 	"math"
+	"sync"
 )
 
-// Counter is a counter.
+// Counter is a counter which is safe for concurrent use.
 type Counter struct {
 	count int32
+	mutex *sync.Mutex
 }
 
 // NewCounter returns a new counter.
 func NewCounter() *Counter {
 	return &Counter{
 		count: 0,
+		mutex: &sync.Mutex{},
 	}
 }
 
@@ -34,11 +37,14 @@ func NewCounter() *Counter {
 func NewCounterWith(count int32) *Counter {
 	return &Counter{
 		count: count,
+		mutex: &sync.Mutex{},
 	}
 }
 
 // Inc increments the counter and returns the new value.
 func (counter *Counter) Inc() int32 {
+	counter.mutex.Lock()
+	defer counter.mutex.Unlock()
 	if counter.count == math.MaxInt32 {
 		counter.count = 0
 	}
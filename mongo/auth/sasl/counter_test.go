@@ -16,6 +16,7 @@ package sasl
 
 import (
 	"math"
+	"sync"
 	"testing"
 )
 
@@ -38,3 +39,33 @@ func TestCounter(t *testing.T) {
 		t.Error("Counter.Inc() is failed")
 	}
 }
+
+func TestCounterConcurrency(t *testing.T) {
+	counter := NewCounter()
+
+	const nGoroutines = 16
+	const nIncrements = 1000
+
+	var wg sync.WaitGroup
+	ids := make(chan int32, nGoroutines*nIncrements)
+	for range nGoroutines {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for range nIncrements {
+				ids <- counter.Inc()
+			}
+		}()
+	}
+	wg.Wait()
+	close(ids)
+
+	seen := map[int32]bool{}
+	for id := range ids {
+		if seen[id] {
+			t.Errorf("duplicate ID : %d", id)
+			return
+		}
+		seen[id] = true
+	}
+}
@@ -0,0 +1,61 @@
+// Copyright (C) 2024 The go-mongo Authors. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package oidc
+
+import (
+	"errors"
+	"fmt"
+)
+
+// ErrInvalidToken is returned when the token is malformed or its signature is invalid.
+var ErrInvalidToken = errors.New("invalid token")
+
+// ErrInvalidClaim is returned when a claim of the token is not acceptable.
+var ErrInvalidClaim = errors.New("invalid claim")
+
+// ErrTokenExpired is returned when the token is expired.
+var ErrTokenExpired = errors.New("token expired")
+
+// ErrKeyNotFound is returned when no key of the key set matches the token.
+var ErrKeyNotFound = errors.New("key not found")
+
+// ErrInvalidKey is returned when a key of the key set is malformed or not supported.
+var ErrInvalidKey = errors.New("invalid key")
+
+// ErrInvalidVerifier is returned when a required setting of the verifier is missing.
+var ErrInvalidVerifier = errors.New("invalid verifier")
+
+// ErrInvalidMessage is returned when the SASL payload is malformed.
+var ErrInvalidMessage = errors.New("invalid MONGODB-OIDC message")
+
+func newErrInvalidToken(format string, args ...any) error {
+	return fmt.Errorf("%w : %s", ErrInvalidToken, fmt.Sprintf(format, args...))
+}
+
+func newErrInvalidClaim(name string, v any) error {
+	return fmt.Errorf("%w : %s (%v)", ErrInvalidClaim, name, v)
+}
+
+func newErrInvalidVerifier(name string) error {
+	return fmt.Errorf("%w : %s is required", ErrInvalidVerifier, name)
+}
+
+func newErrKeyNotFound(kid string) error {
+	return fmt.Errorf("%w : %s", ErrKeyNotFound, kid)
+}
+
+func newErrInvalidKey(kid string, err error) error {
+	return fmt.Errorf("%w : %s (%w)", ErrInvalidKey, kid, err)
+}
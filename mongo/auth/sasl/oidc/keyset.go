@@ -0,0 +1,241 @@
+// Copyright (C) 2024 The go-mongo Authors. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package oidc
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"os"
+	"sort"
+	"sync"
+)
+
+// See : RFC 7517 - JSON Web Key (JWK)
+// https://datatracker.ietf.org/doc/html/rfc7517
+// See : RFC 7518 - JSON Web Algorithms (JWA)
+// https://datatracker.ietf.org/doc/html/rfc7518
+
+const (
+	keyTypeRSA = "RSA"
+	keyTypeEC  = "EC"
+	keyUseSig  = "sig"
+)
+
+// jwk represents a JSON Web Key.
+type jwk struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid,omitempty"`
+	Use string `json:"use,omitempty"`
+	Alg string `json:"alg,omitempty"`
+	N   string `json:"n,omitempty"`
+	E   string `json:"e,omitempty"`
+	Crv string `json:"crv,omitempty"`
+	X   string `json:"x,omitempty"`
+	Y   string `json:"y,omitempty"`
+}
+
+// jwks represents a JSON Web Key Set.
+type jwks struct {
+	Keys []jwk `json:"keys"`
+}
+
+// KeySet represents a set of public keys to verify token signatures.
+type KeySet struct {
+	keys  map[string]crypto.PublicKey
+	mutex *sync.RWMutex
+}
+
+// NewKeySet returns a new empty key set.
+func NewKeySet() *KeySet {
+	return &KeySet{
+		keys:  map[string]crypto.PublicKey{},
+		mutex: &sync.RWMutex{},
+	}
+}
+
+// NewKeySetFromJWKS returns a new key set from the specified JWKS document.
+func NewKeySetFromJWKS(data []byte) (*KeySet, error) {
+	ks := NewKeySet()
+	return ks, ks.SetJWKS(data)
+}
+
+// NewKeySetFromJWKSFile returns a new key set from the specified JWKS file.
+func NewKeySetFromJWKSFile(name string) (*KeySet, error) {
+	data, err := os.ReadFile(name)
+	if err != nil {
+		return nil, err
+	}
+	return NewKeySetFromJWKS(data)
+}
+
+// AddKey adds the specified RSA or ECDSA public key with the key ID.
+func (ks *KeySet) AddKey(kid string, key crypto.PublicKey) error {
+	switch key.(type) {
+	case *rsa.PublicKey, *ecdsa.PublicKey:
+	default:
+		return newErrInvalidKey(kid, fmt.Errorf("unsupported key type %T", key))
+	}
+	ks.mutex.Lock()
+	defer ks.mutex.Unlock()
+	ks.keys[kid] = key
+	return nil
+}
+
+// SetJWKS replaces the keys with the keys of the specified JWKS document. Keys which are not for signatures are ignored.
+func (ks *KeySet) SetJWKS(data []byte) error {
+	var set jwks
+	err := json.Unmarshal(data, &set)
+	if err != nil {
+		return err
+	}
+	keys := map[string]crypto.PublicKey{}
+	for _, k := range set.Keys {
+		if len(k.Use) != 0 && k.Use != keyUseSig {
+			continue
+		}
+		key, err := k.publicKey()
+		if err != nil {
+			return newErrInvalidKey(k.Kid, err)
+		}
+		keys[k.Kid] = key
+	}
+	ks.mutex.Lock()
+	defer ks.mutex.Unlock()
+	ks.keys = keys
+	return nil
+}
+
+// LoadJWKSFile replaces the keys with the keys of the specified JWKS file.
+func (ks *KeySet) LoadJWKSFile(name string) error {
+	data, err := os.ReadFile(name)
+	if err != nil {
+		return err
+	}
+	return ks.SetJWKS(data)
+}
+
+// Key returns the key of the specified key ID. If the key ID is empty, it returns the key only when the set has a single key.
+func (ks *KeySet) Key(kid string) (crypto.PublicKey, error) {
+	ks.mutex.RLock()
+	defer ks.mutex.RUnlock()
+	if len(kid) == 0 && len(ks.keys) == 1 {
+		for _, key := range ks.keys {
+			return key, nil
+		}
+	}
+	key, ok := ks.keys[kid]
+	if !ok {
+		return nil, newErrKeyNotFound(kid)
+	}
+	return key, nil
+}
+
+// JWKS returns the JWKS document of the keys.
+func (ks *KeySet) JWKS() ([]byte, error) {
+	ks.mutex.RLock()
+	defer ks.mutex.RUnlock()
+	kids := make([]string, 0, len(ks.keys))
+	for kid := range ks.keys {
+		kids = append(kids, kid)
+	}
+	sort.Strings(kids)
+	set := jwks{Keys: []jwk{}}
+	for _, kid := range kids {
+		k, err := newJWK(kid, ks.keys[kid])
+		if err != nil {
+			return nil, err
+		}
+		set.Keys = append(set.Keys, k)
+	}
+	return json.Marshal(set)
+}
+
+func newJWK(kid string, key crypto.PublicKey) (jwk, error) {
+	enc := base64.RawURLEncoding
+	switch key := key.(type) {
+	case *rsa.PublicKey:
+		return jwk{
+			Kty: keyTypeRSA,
+			Kid: kid,
+			Use: keyUseSig,
+			N:   enc.EncodeToString(key.N.Bytes()),
+			E:   enc.EncodeToString(big.NewInt(int64(key.E)).Bytes()),
+		}, nil
+	case *ecdsa.PublicKey:
+		size := (key.Curve.Params().BitSize + 7) / 8
+		return jwk{
+			Kty: keyTypeEC,
+			Kid: kid,
+			Use: keyUseSig,
+			Crv: key.Curve.Params().Name,
+			X:   enc.EncodeToString(key.X.FillBytes(make([]byte, size))),
+			Y:   enc.EncodeToString(key.Y.FillBytes(make([]byte, size))),
+		}, nil
+	}
+	return jwk{}, newErrInvalidKey(kid, fmt.Errorf("unsupported key type %T", key))
+}
+
+func (k jwk) publicKey() (crypto.PublicKey, error) {
+	enc := base64.RawURLEncoding
+	switch k.Kty {
+	case keyTypeRSA:
+		n, err := enc.DecodeString(k.N)
+		if err != nil {
+			return nil, err
+		}
+		e, err := enc.DecodeString(k.E)
+		if err != nil {
+			return nil, err
+		}
+		exp := new(big.Int).SetBytes(e)
+		if len(n) == 0 || !exp.IsInt64() || exp.Int64() < 3 || exp.Int64() > 1<<31-1 {
+			return nil, errors.New("invalid RSA key")
+		}
+		return &rsa.PublicKey{N: new(big.Int).SetBytes(n), E: int(exp.Int64())}, nil
+	case keyTypeEC:
+		var curve elliptic.Curve
+		switch k.Crv {
+		case "P-256":
+			curve = elliptic.P256()
+		case "P-384":
+			curve = elliptic.P384()
+		case "P-521":
+			curve = elliptic.P521()
+		default:
+			return nil, fmt.Errorf("unsupported curve %s", k.Crv)
+		}
+		x, err := enc.DecodeString(k.X)
+		if err != nil {
+			return nil, err
+		}
+		y, err := enc.DecodeString(k.Y)
+		if err != nil {
+			return nil, err
+		}
+		key := &ecdsa.PublicKey{Curve: curve, X: new(big.Int).SetBytes(x), Y: new(big.Int).SetBytes(y)}
+		if !curve.IsOnCurve(key.X, key.Y) { // nolint:staticcheck
+			return nil, errors.New("invalid EC key")
+		}
+		return key, nil
+	}
+	return nil, fmt.Errorf("unsupported key type %s", k.Kty)
+}
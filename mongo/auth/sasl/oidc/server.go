@@ -0,0 +1,214 @@
+// Copyright (C) 2024 The go-mongo Authors. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package oidc
+
+import (
+	"fmt"
+
+	"github.com/cybergarage/go-mongo/mongo/auth/sasl/scram"
+	"github.com/cybergarage/go-sasl/sasl/mech"
+	"go.mongodb.org/mongo-driver/x/bsonx/bsoncore"
+)
+
+// MongoDB : Authentication - MONGODB-OIDC
+// https://github.com/mongodb/specifications/blob/master/source/auth/auth.md#mongodb-oidc
+
+const (
+	// Mechanism is the mechanism name of MONGODB-OIDC.
+	Mechanism = "MONGODB-OIDC"
+	// UsernameID is the context key of the authenticated principal, which is shared with SCRAM mechanisms.
+	UsernameID = scram.UsernameID
+	// ExpirationID is the context key of the expiration time of the authenticated token.
+	ExpirationID = "expiration"
+)
+
+const (
+	jwtElement           = "jwt"
+	issuerElement        = "issuer"
+	clientIDElement      = "clientId"
+	requestScopesElement = "requestScopes"
+)
+
+// Server represents a MONGODB-OIDC server mechanism which verifies tokens with the verifier.
+type Server struct {
+	verifier *Verifier
+}
+
+// NewServer returns a new MONGODB-OIDC server mechanism with the specified verifier.
+func NewServer(verifier *Verifier) *Server {
+	return &Server{
+		verifier: verifier,
+	}
+}
+
+// Verifier returns the token verifier.
+func (server *Server) Verifier() *Verifier {
+	return server.verifier
+}
+
+// Name returns the mechanism name.
+func (server *Server) Name() string {
+	return Mechanism
+}
+
+// Type returns the mechanism type.
+func (server *Server) Type() mech.Type {
+	return mech.Server
+}
+
+// SetOptions sets the mechanism options before starting.
+func (server *Server) SetOptions(...mech.Option) error {
+	return nil
+}
+
+// Start returns the initial context.
+func (server *Server) Start(...mech.Option) (mech.Context, error) {
+	return &serverContext{
+		Store:  mech.NewStore(),
+		server: server,
+		step:   0,
+		done:   false,
+	}, nil
+}
+
+type serverContext struct {
+	mech.Store
+	server *Server
+	step   int
+	done   bool
+}
+
+// Mechanism returns the mechanism.
+func (ctx *serverContext) Mechanism() mech.Mechanism {
+	return ctx.server
+}
+
+// Step returns the current step number.
+func (ctx *serverContext) Step() int {
+	return ctx.step
+}
+
+// Done returns true if the context is completed.
+func (ctx *serverContext) Done() bool {
+	return ctx.done
+}
+
+// Dispose disposes the context.
+func (ctx *serverContext) Dispose() error {
+	return nil
+}
+
+// Next handles the client payload. A payload with a token completes the conversation,
+// and a payload without a token is answered with the identity provider information.
+func (ctx *serverContext) Next(params ...mech.Parameter) (mech.Response, error) {
+	if ctx.done || 1 < ctx.step {
+		return nil, fmt.Errorf("invalid step : %d", ctx.step)
+	}
+	payload, err := payloadFrom(params...)
+	if err != nil {
+		return nil, err
+	}
+
+	var doc bsoncore.Document
+	if 0 < len(payload) {
+		doc = bsoncore.Document(payload)
+		if err := doc.Validate(); err != nil {
+			return nil, fmt.Errorf("%w : %w", ErrInvalidMessage, err)
+		}
+	}
+
+	jwt, hasJWT := "", false
+	if doc != nil {
+		if v, err := doc.LookupErr(jwtElement); err == nil {
+			jwt, hasJWT = v.StringValueOK()
+			if !hasJWT {
+				return nil, ErrInvalidMessage
+			}
+		}
+	}
+
+	if !hasJWT {
+		// Only the first step may request the identity provider information.
+		if ctx.step != 0 {
+			return nil, ErrInvalidMessage
+		}
+		ctx.step++
+		return ctx.idpInfo(), nil
+	}
+
+	verifier := ctx.server.verifier
+	token, err := verifier.Verify(jwt)
+	if err != nil {
+		return nil, err
+	}
+	principal, err := verifier.Principal(token)
+	if err != nil {
+		return nil, err
+	}
+
+	ctx.SetValue(UsernameID, principal)
+	ctx.SetValue(ExpirationID, verifier.Expiration(token))
+	ctx.step++
+	ctx.done = true
+
+	return response{}, nil
+}
+
+func (ctx *serverContext) idpInfo() response {
+	verifier := ctx.server.verifier
+	idx, doc := bsoncore.AppendDocumentStart(nil)
+	doc = bsoncore.AppendStringElement(doc, issuerElement, verifier.Issuer())
+	if clientID := verifier.ClientID(); 0 < len(clientID) {
+		doc = bsoncore.AppendStringElement(doc, clientIDElement, clientID)
+	}
+	if scopes := verifier.RequestScopes(); 0 < len(scopes) {
+		aidx, arr := bsoncore.AppendArrayStart(nil)
+		for n, scope := range scopes {
+			arr = bsoncore.AppendStringElement(arr, fmt.Sprintf("%d", n), scope)
+		}
+		arr, _ = bsoncore.AppendArrayEnd(arr, aidx)
+		doc = bsoncore.AppendArrayElement(doc, requestScopesElement, arr)
+	}
+	doc, _ = bsoncore.AppendDocumentEnd(doc, idx)
+	return response(doc)
+}
+
+func payloadFrom(params ...mech.Parameter) ([]byte, error) {
+	if len(params) == 0 {
+		return []byte{}, nil
+	}
+	switch v := params[0].(type) {
+	case []byte:
+		return v, nil
+	case mech.Payload:
+		return v, nil
+	case string:
+		return []byte(v), nil
+	}
+	return nil, ErrInvalidMessage
+}
+
+// response represents a MONGODB-OIDC server response.
+type response []byte
+
+// Bytes returns the response bytes.
+func (res response) Bytes() []byte {
+	return res
+}
+
+// String returns the response as a string.
+func (res response) String() string {
+	return string(res)
+}
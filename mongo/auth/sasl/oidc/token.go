@@ -0,0 +1,275 @@
+// Copyright (C) 2024 The go-mongo Authors. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package oidc
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"strings"
+	"time"
+)
+
+// See : RFC 7519 - JSON Web Token (JWT)
+// https://datatracker.ietf.org/doc/html/rfc7519
+// See : RFC 7515 - JSON Web Signature (JWS)
+// https://datatracker.ietf.org/doc/html/rfc7515
+
+const (
+	// IssuerClaim is the name of the issuer claim.
+	IssuerClaim = "iss"
+	// SubjectClaim is the name of the subject claim.
+	SubjectClaim = "sub"
+	// AudienceClaim is the name of the audience claim.
+	AudienceClaim = "aud"
+	// ExpirationClaim is the name of the expiration time claim.
+	ExpirationClaim = "exp"
+	// NotBeforeClaim is the name of the not before claim.
+	NotBeforeClaim = "nbf"
+	// IssuedAtClaim is the name of the issued at claim.
+	IssuedAtClaim = "iat"
+)
+
+// Claims represents the claims of a token.
+type Claims map[string]any
+
+// String returns the string claim of the specified name.
+func (claims Claims) String(name string) (string, bool) {
+	v, ok := claims[name].(string)
+	return v, ok
+}
+
+// Time returns the NumericDate claim of the specified name.
+func (claims Claims) Time(name string) (time.Time, bool) {
+	v, ok := claims[name]
+	if !ok {
+		return time.Time{}, false
+	}
+	var secs float64
+	switch v := v.(type) {
+	case float64:
+		secs = v
+	case json.Number:
+		f, err := v.Float64()
+		if err != nil {
+			return time.Time{}, false
+		}
+		secs = f
+	case int:
+		secs = float64(v)
+	case int64:
+		secs = float64(v)
+	default:
+		return time.Time{}, false
+	}
+	sec := int64(secs)
+	nsec := int64((secs - float64(sec)) * float64(time.Second))
+	return time.Unix(sec, nsec), true
+}
+
+// Audience returns the audience claim, which is a string or an array of strings.
+func (claims Claims) Audience() []string {
+	switch v := claims[AudienceClaim].(type) {
+	case string:
+		return []string{v}
+	case []string:
+		return v
+	case []any:
+		auds := []string{}
+		for _, e := range v {
+			if s, ok := e.(string); ok {
+				auds = append(auds, s)
+			}
+		}
+		return auds
+	}
+	return []string{}
+}
+
+// header represents a JOSE header.
+type header struct {
+	Alg string `json:"alg"`
+	Typ string `json:"typ,omitempty"`
+	Kid string `json:"kid,omitempty"`
+}
+
+// algorithm represents a JWS signature algorithm.
+type algorithm struct {
+	hash  crypto.Hash
+	ec    bool
+	curve elliptic.Curve
+}
+
+var algorithms = map[string]algorithm{
+	"RS256": {hash: crypto.SHA256, ec: false, curve: nil},
+	"RS384": {hash: crypto.SHA384, ec: false, curve: nil},
+	"RS512": {hash: crypto.SHA512, ec: false, curve: nil},
+	"ES256": {hash: crypto.SHA256, ec: true, curve: elliptic.P256()},
+	"ES384": {hash: crypto.SHA384, ec: true, curve: elliptic.P384()},
+	"ES512": {hash: crypto.SHA512, ec: true, curve: elliptic.P521()},
+}
+
+// Token represents a parsed and verified token.
+type Token struct {
+	Raw    string
+	KeyID  string
+	Claims Claims
+}
+
+// ParseToken parses the specified compact serialized token, and verifies the signature with the key set.
+// Only the RS256/384/512 and ES256/384/512 algorithms are accepted.
+func ParseToken(raw string, keys *KeySet) (*Token, error) {
+	parts := strings.Split(raw, ".")
+	if len(parts) != 3 {
+		return nil, newErrInvalidToken("malformed token")
+	}
+	enc := base64.RawURLEncoding
+
+	hdrBytes, err := enc.DecodeString(parts[0])
+	if err != nil {
+		return nil, newErrInvalidToken("malformed header")
+	}
+	var hdr header
+	if err := json.Unmarshal(hdrBytes, &hdr); err != nil {
+		return nil, newErrInvalidToken("malformed header")
+	}
+	alg, ok := algorithms[hdr.Alg]
+	if !ok {
+		return nil, newErrInvalidToken("unsupported algorithm %q", hdr.Alg)
+	}
+
+	sig, err := enc.DecodeString(parts[2])
+	if err != nil {
+		return nil, newErrInvalidToken("malformed signature")
+	}
+	key, err := keys.Key(hdr.Kid)
+	if err != nil {
+		return nil, err
+	}
+	h := alg.hash.New()
+	h.Write([]byte(parts[0] + "." + parts[1]))
+	digest := h.Sum(nil)
+	if err := verifySignature(alg, key, digest, sig); err != nil {
+		return nil, err
+	}
+
+	claimBytes, err := enc.DecodeString(parts[1])
+	if err != nil {
+		return nil, newErrInvalidToken("malformed claims")
+	}
+	var claims Claims
+	if err := json.Unmarshal(claimBytes, &claims); err != nil {
+		return nil, newErrInvalidToken("malformed claims")
+	}
+
+	return &Token{
+		Raw:    raw,
+		KeyID:  hdr.Kid,
+		Claims: claims,
+	}, nil
+}
+
+func verifySignature(alg algorithm, key crypto.PublicKey, digest []byte, sig []byte) error {
+	switch key := key.(type) {
+	case *rsa.PublicKey:
+		if alg.ec {
+			return newErrInvalidToken("algorithm does not match the key")
+		}
+		if err := rsa.VerifyPKCS1v15(key, alg.hash, digest, sig); err != nil {
+			return newErrInvalidToken("invalid signature")
+		}
+		return nil
+	case *ecdsa.PublicKey:
+		if !alg.ec || key.Curve != alg.curve {
+			return newErrInvalidToken("algorithm does not match the key")
+		}
+		size := (key.Curve.Params().BitSize + 7) / 8
+		if len(sig) != 2*size {
+			return newErrInvalidToken("invalid signature")
+		}
+		r := new(big.Int).SetBytes(sig[:size])
+		s := new(big.Int).SetBytes(sig[size:])
+		if !ecdsa.Verify(key, digest, r, s) {
+			return newErrInvalidToken("invalid signature")
+		}
+		return nil
+	}
+	return newErrInvalidToken("unsupported key type %T", key)
+}
+
+// NewSignedToken returns a compact serialized token of the claims signed by the specified RSA or ECDSA private key.
+// It is intended for issuing tokens locally, such as in tests, without an identity provider.
+func NewSignedToken(signer crypto.Signer, kid string, claims Claims) (string, error) {
+	var algName string
+	switch key := signer.Public().(type) {
+	case *rsa.PublicKey:
+		algName = "RS256"
+	case *ecdsa.PublicKey:
+		switch key.Curve.Params().BitSize {
+		case 256:
+			algName = "ES256"
+		case 384:
+			algName = "ES384"
+		case 521:
+			algName = "ES512"
+		default:
+			return "", newErrInvalidKey(kid, fmt.Errorf("unsupported curve %s", key.Curve.Params().Name))
+		}
+	default:
+		return "", newErrInvalidKey(kid, fmt.Errorf("unsupported key type %T", key))
+	}
+	alg := algorithms[algName]
+
+	hdrBytes, err := json.Marshal(header{Alg: algName, Typ: "JWT", Kid: kid})
+	if err != nil {
+		return "", err
+	}
+	claimBytes, err := json.Marshal(claims)
+	if err != nil {
+		return "", err
+	}
+	enc := base64.RawURLEncoding
+	signingInput := enc.EncodeToString(hdrBytes) + "." + enc.EncodeToString(claimBytes)
+
+	h := alg.hash.New()
+	h.Write([]byte(signingInput))
+	digest := h.Sum(nil)
+
+	var sig []byte
+	switch key := signer.(type) {
+	case *ecdsa.PrivateKey:
+		r, s, err := ecdsa.Sign(rand.Reader, key, digest)
+		if err != nil {
+			return "", err
+		}
+		size := (key.Curve.Params().BitSize + 7) / 8
+		sig = make([]byte, 2*size)
+		r.FillBytes(sig[:size])
+		s.FillBytes(sig[size:])
+	default:
+		sig, err = signer.Sign(rand.Reader, digest, alg.hash)
+		if err != nil {
+			return "", err
+		}
+	}
+
+	return signingInput + "." + enc.EncodeToString(sig), nil
+}
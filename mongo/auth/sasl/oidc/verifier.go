@@ -0,0 +1,206 @@
+// Copyright (C) 2024 The go-mongo Authors. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package oidc
+
+import (
+	"slices"
+	"time"
+)
+
+const (
+	// DefaultClockSkew is the default allowed clock skew for time claims.
+	DefaultClockSkew = time.Minute
+)
+
+// VerifierOption represents an option of the verifier.
+type VerifierOption func(*Verifier)
+
+// Verifier represents a token verifier which checks signatures with the key set, and the issuer, audience and time claims.
+type Verifier struct {
+	keySet         *KeySet
+	issuer         string
+	audience       string
+	principalClaim string
+	clientID       string
+	requestScopes  []string
+	clockSkew      time.Duration
+	now            func() time.Time
+}
+
+// WithKeySet sets the key set to verify token signatures.
+func WithKeySet(keySet *KeySet) VerifierOption {
+	return func(v *Verifier) {
+		v.keySet = keySet
+	}
+}
+
+// WithIssuer sets the expected issuer. The issuer is also advertised to clients.
+func WithIssuer(issuer string) VerifierOption {
+	return func(v *Verifier) {
+		v.issuer = issuer
+	}
+}
+
+// WithAudience sets the expected audience.
+func WithAudience(audience string) VerifierOption {
+	return func(v *Verifier) {
+		v.audience = audience
+	}
+}
+
+// WithPrincipalClaim sets the claim name which is mapped to the authenticated principal. The default is the subject claim.
+func WithPrincipalClaim(name string) VerifierOption {
+	return func(v *Verifier) {
+		v.principalClaim = name
+	}
+}
+
+// WithClientID sets the client ID advertised to clients.
+func WithClientID(clientID string) VerifierOption {
+	return func(v *Verifier) {
+		v.clientID = clientID
+	}
+}
+
+// WithRequestScopes sets the request scopes advertised to clients.
+func WithRequestScopes(scopes ...string) VerifierOption {
+	return func(v *Verifier) {
+		v.requestScopes = scopes
+	}
+}
+
+// WithClockSkew sets the allowed clock skew for time claims.
+func WithClockSkew(skew time.Duration) VerifierOption {
+	return func(v *Verifier) {
+		v.clockSkew = skew
+	}
+}
+
+// WithClock sets the clock function used to check time claims.
+func WithClock(now func() time.Time) VerifierOption {
+	return func(v *Verifier) {
+		v.now = now
+	}
+}
+
+// NewVerifier returns a new token verifier with the specified options.
+// The issuer and audience are required, and an error is returned if either is not set.
+func NewVerifier(opts ...VerifierOption) (*Verifier, error) {
+	v := &Verifier{
+		keySet:         NewKeySet(),
+		issuer:         "",
+		audience:       "",
+		principalClaim: SubjectClaim,
+		clientID:       "",
+		requestScopes:  []string{},
+		clockSkew:      DefaultClockSkew,
+		now:            time.Now,
+	}
+	for _, opt := range opts {
+		opt(v)
+	}
+	if len(v.issuer) == 0 {
+		return nil, newErrInvalidVerifier(IssuerClaim)
+	}
+	if len(v.audience) == 0 {
+		return nil, newErrInvalidVerifier(AudienceClaim)
+	}
+	return v, nil
+}
+
+// KeySet returns the key set.
+func (v *Verifier) KeySet() *KeySet {
+	return v.keySet
+}
+
+// Issuer returns the expected issuer.
+func (v *Verifier) Issuer() string {
+	return v.issuer
+}
+
+// Audience returns the expected audience.
+func (v *Verifier) Audience() string {
+	return v.audience
+}
+
+// PrincipalClaim returns the claim name which is mapped to the principal.
+func (v *Verifier) PrincipalClaim() string {
+	return v.principalClaim
+}
+
+// ClientID returns the client ID advertised to clients.
+func (v *Verifier) ClientID() string {
+	return v.clientID
+}
+
+// RequestScopes returns the request scopes advertised to clients.
+func (v *Verifier) RequestScopes() []string {
+	return v.requestScopes
+}
+
+// Verify verifies the specified token, and returns the parsed token.
+// The token must have the expected issuer and audience, and an expiration time.
+func (v *Verifier) Verify(raw string) (*Token, error) {
+	token, err := ParseToken(raw, v.keySet)
+	if err != nil {
+		return nil, err
+	}
+	claims := token.Claims
+
+	iss, _ := claims.String(IssuerClaim)
+	if iss != v.issuer {
+		return nil, newErrInvalidClaim(IssuerClaim, iss)
+	}
+
+	auds := claims.Audience()
+	if !slices.Contains(auds, v.audience) {
+		return nil, newErrInvalidClaim(AudienceClaim, auds)
+	}
+
+	now := v.now()
+	exp, ok := claims.Time(ExpirationClaim)
+	if !ok {
+		return nil, newErrInvalidClaim(ExpirationClaim, claims[ExpirationClaim])
+	}
+	if !now.Before(exp.Add(v.clockSkew)) {
+		return nil, ErrTokenExpired
+	}
+	if nbf, ok := claims.Time(NotBeforeClaim); ok {
+		if now.Add(v.clockSkew).Before(nbf) {
+			return nil, newErrInvalidClaim(NotBeforeClaim, nbf)
+		}
+	}
+
+	if _, err := v.Principal(token); err != nil {
+		return nil, err
+	}
+
+	return token, nil
+}
+
+// Principal returns the principal of the specified token.
+func (v *Verifier) Principal(token *Token) (string, error) {
+	principal, ok := token.Claims.String(v.principalClaim)
+	if !ok || len(principal) == 0 {
+		return "", newErrInvalidClaim(v.principalClaim, token.Claims[v.principalClaim])
+	}
+	return principal, nil
+}
+
+// Expiration returns the time until which the specified token is accepted, including the clock skew.
+func (v *Verifier) Expiration(token *Token) time.Time {
+	exp, _ := token.Claims.Time(ExpirationClaim)
+	return exp.Add(v.clockSkew)
+}
@@ -0,0 +1,340 @@
+// Copyright (C) 2024 The go-mongo Authors. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package oidc
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha512"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"go.mongodb.org/mongo-driver/x/bsonx/bsoncore"
+)
+
+const (
+	testIssuer   = "https://issuer.example.com"
+	testAudience = "go-mongo"
+)
+
+func newTestClaims(exp time.Time) Claims {
+	return Claims{
+		IssuerClaim:     testIssuer,
+		AudienceClaim:   []string{"other", testAudience},
+		SubjectClaim:    "alice",
+		"email":         "alice@example.com",
+		ExpirationClaim: float64(exp.UnixNano()) / float64(time.Second),
+	}
+}
+
+func newTestSigners(t *testing.T) map[string]crypto.Signer {
+	t.Helper()
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ec256Key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ec521Key, err := ecdsa.GenerateKey(elliptic.P521(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return map[string]crypto.Signer{
+		"rsa":   rsaKey,
+		"ec256": ec256Key,
+		"ec521": ec521Key,
+	}
+}
+
+func TestVerifier(t *testing.T) {
+	signers := newTestSigners(t)
+
+	keys := NewKeySet()
+	for kid, signer := range signers {
+		if err := keys.AddKey(kid, signer.Public()); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// Round trip the key set through a JWKS file.
+
+	data, err := keys.JWKS()
+	if err != nil {
+		t.Fatal(err)
+	}
+	jwksFile := filepath.Join(t.TempDir(), "jwks.json")
+	if err := os.WriteFile(jwksFile, data, 0o600); err != nil {
+		t.Fatal(err)
+	}
+	keys, err = NewKeySetFromJWKSFile(jwksFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	verifier, err := NewVerifier(
+		WithKeySet(keys),
+		WithIssuer(testIssuer),
+		WithAudience(testAudience),
+		WithClockSkew(0),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	exp := time.Now().Add(time.Hour)
+
+	t.Run("valid", func(t *testing.T) {
+		for kid, signer := range signers {
+			jwt, err := NewSignedToken(signer, kid, newTestClaims(exp))
+			if err != nil {
+				t.Fatal(err)
+			}
+			token, err := verifier.Verify(jwt)
+			if err != nil {
+				t.Errorf("%s: %s", kid, err)
+				continue
+			}
+			principal, err := verifier.Principal(token)
+			if err != nil || principal != "alice" {
+				t.Errorf("%s: %s != %s (%v)", kid, principal, "alice", err)
+			}
+			if d := verifier.Expiration(token).Sub(exp); d < -time.Millisecond || time.Millisecond < d {
+				t.Errorf("%s: %v != %v", kid, verifier.Expiration(token), exp)
+			}
+		}
+	})
+
+	t.Run("principal claim", func(t *testing.T) {
+		verifier, err := NewVerifier(
+			WithKeySet(keys),
+			WithIssuer(testIssuer),
+			WithAudience(testAudience),
+			WithPrincipalClaim("email"),
+		)
+		if err != nil {
+			t.Fatal(err)
+		}
+		jwt, err := NewSignedToken(signers["rsa"], "rsa", newTestClaims(exp))
+		if err != nil {
+			t.Fatal(err)
+		}
+		token, err := verifier.Verify(jwt)
+		if err != nil {
+			t.Fatal(err)
+		}
+		principal, _ := verifier.Principal(token)
+		if principal != "alice@example.com" {
+			t.Errorf("%s != %s", principal, "alice@example.com")
+		}
+	})
+
+	t.Run("invalid", func(t *testing.T) {
+		otherSigners := newTestSigners(t)
+
+		tests := []struct {
+			name   string
+			signer crypto.Signer
+			kid    string
+			claims func(Claims)
+			err    error
+		}{
+			{"expired", signers["rsa"], "rsa", func(c Claims) { c[ExpirationClaim] = time.Now().Add(-time.Second).Unix() }, ErrTokenExpired},
+			{"no expiration", signers["rsa"], "rsa", func(c Claims) { delete(c, ExpirationClaim) }, ErrInvalidClaim},
+			{"not before", signers["rsa"], "rsa", func(c Claims) { c[NotBeforeClaim] = time.Now().Add(time.Hour).Unix() }, ErrInvalidClaim},
+			{"issuer", signers["rsa"], "rsa", func(c Claims) { c[IssuerClaim] = "https://other.example.com" }, ErrInvalidClaim},
+			{"audience", signers["ec256"], "ec256", func(c Claims) { c[AudienceClaim] = "other" }, ErrInvalidClaim},
+			{"no subject", signers["ec256"], "ec256", func(c Claims) { delete(c, SubjectClaim) }, ErrInvalidClaim},
+			{"unknown key", signers["rsa"], "unknown", func(c Claims) {}, ErrKeyNotFound},
+			{"rsa signature", otherSigners["rsa"], "rsa", func(c Claims) {}, ErrInvalidToken},
+			{"ec signature", otherSigners["ec256"], "ec256", func(c Claims) {}, ErrInvalidToken},
+			{"key mismatch", signers["rsa"], "ec256", func(c Claims) {}, ErrInvalidToken},
+		}
+
+		for _, test := range tests {
+			t.Run(test.name, func(t *testing.T) {
+				claims := newTestClaims(exp)
+				test.claims(claims)
+				jwt, err := NewSignedToken(test.signer, test.kid, claims)
+				if err != nil {
+					t.Fatal(err)
+				}
+				_, err = verifier.Verify(jwt)
+				if !errors.Is(err, test.err) {
+					t.Errorf("%v != %v", err, test.err)
+				}
+			})
+		}
+
+		t.Run("curve mismatch", func(t *testing.T) {
+			// Sign an ES384 token with the P-256 key whose signature size matches ES256.
+			enc := base64.RawURLEncoding
+			claimBytes, err := json.Marshal(newTestClaims(exp))
+			if err != nil {
+				t.Fatal(err)
+			}
+			signingInput := enc.EncodeToString([]byte(`{"alg":"ES384","kid":"ec256"}`)) + "." + enc.EncodeToString(claimBytes)
+			digest := sha512.Sum384([]byte(signingInput))
+			key, _ := signers["ec256"].(*ecdsa.PrivateKey)
+			r, s, err := ecdsa.Sign(rand.Reader, key, digest[:])
+			if err != nil {
+				t.Fatal(err)
+			}
+			sig := make([]byte, 64)
+			r.FillBytes(sig[:32])
+			s.FillBytes(sig[32:])
+			jwt := signingInput + "." + enc.EncodeToString(sig)
+			if _, err := verifier.Verify(jwt); !errors.Is(err, ErrInvalidToken) {
+				t.Errorf("%v != %v", err, ErrInvalidToken)
+			}
+		})
+
+		for _, jwt := range []string{
+			"",
+			"a.b",
+			"eyJhbGciOiJub25lIn0.eyJzdWIiOiJhbGljZSJ9.",
+		} {
+			if _, err := verifier.Verify(jwt); !errors.Is(err, ErrInvalidToken) {
+				t.Errorf("%q: %v != %v", jwt, err, ErrInvalidToken)
+			}
+		}
+	})
+}
+
+func TestVerifierOptions(t *testing.T) {
+	tests := []struct {
+		name string
+		opts []VerifierOption
+	}{
+		{"no issuer", []VerifierOption{WithAudience(testAudience)}},
+		{"no audience", []VerifierOption{WithIssuer(testIssuer)}},
+		{"empty issuer", []VerifierOption{WithIssuer(""), WithAudience(testAudience)}},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if _, err := NewVerifier(test.opts...); !errors.Is(err, ErrInvalidVerifier) {
+				t.Errorf("%v != %v", err, ErrInvalidVerifier)
+			}
+		})
+	}
+}
+
+func TestServer(t *testing.T) {
+	signer, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keys := NewKeySet()
+	if err := keys.AddKey("", signer.Public()); err != nil {
+		t.Fatal(err)
+	}
+	verifier, err := NewVerifier(
+		WithKeySet(keys),
+		WithIssuer(testIssuer),
+		WithAudience(testAudience),
+		WithClientID("client"),
+		WithRequestScopes("openid"),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	server := NewServer(verifier)
+
+	exp := time.Now().Add(time.Hour)
+	jwt, err := NewSignedToken(signer, "", newTestClaims(exp))
+	if err != nil {
+		t.Fatal(err)
+	}
+	jwtPayload := func() []byte {
+		idx, doc := bsoncore.AppendDocumentStart(nil)
+		doc = bsoncore.AppendStringElement(doc, jwtElement, jwt)
+		doc, _ = bsoncore.AppendDocumentEnd(doc, idx)
+		return doc
+	}
+
+	verifyAuthenticated := func(t *testing.T, ctx interface {
+		Done() bool
+		Value(string) (any, bool)
+	}) {
+		t.Helper()
+		if !ctx.Done() {
+			t.Fatal("not done")
+		}
+		if v, _ := ctx.Value(UsernameID); v != "alice" {
+			t.Errorf("%v != %s", v, "alice")
+		}
+		if v, ok := ctx.Value(ExpirationID); !ok || !v.(time.Time).After(exp) {
+			t.Errorf("%v <= %v", v, exp)
+		}
+	}
+
+	t.Run("one step", func(t *testing.T) {
+		ctx, err := server.Start()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := ctx.Next(jwtPayload()); err != nil {
+			t.Fatal(err)
+		}
+		verifyAuthenticated(t, ctx)
+	})
+
+	t.Run("two step", func(t *testing.T) {
+		ctx, err := server.Start()
+		if err != nil {
+			t.Fatal(err)
+		}
+		idx, doc := bsoncore.AppendDocumentStart(nil)
+		doc = bsoncore.AppendStringElement(doc, "n", "alice")
+		doc, _ = bsoncore.AppendDocumentEnd(doc, idx)
+		res, err := ctx.Next(doc)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if ctx.Done() {
+			t.Fatal("done")
+		}
+		info := bsoncore.Document(res.Bytes())
+		if v, _ := info.Lookup(issuerElement).StringValueOK(); v != testIssuer {
+			t.Errorf("%s != %s", v, testIssuer)
+		}
+		if v, _ := info.Lookup(clientIDElement).StringValueOK(); v != "client" {
+			t.Errorf("%s != %s", v, "client")
+		}
+		if _, err := ctx.Next(jwtPayload()); err != nil {
+			t.Fatal(err)
+		}
+		verifyAuthenticated(t, ctx)
+	})
+
+	t.Run("invalid payload", func(t *testing.T) {
+		ctx, err := server.Start()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := ctx.Next([]byte{0x01}); !errors.Is(err, ErrInvalidMessage) {
+			t.Errorf("%v != %v", err, ErrInvalidMessage)
+		}
+	})
+}
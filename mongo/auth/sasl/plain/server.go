@@ -0,0 +1,181 @@
+// Copyright (C) 2024 The go-mongo Authors. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package plain
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/cybergarage/go-mongo/mongo/auth/sasl/scram"
+	"github.com/cybergarage/go-sasl/sasl/mech"
+)
+
+// See : RFC 4616 - The PLAIN Simple Authentication and Security Layer (SASL) Mechanism
+// https://datatracker.ietf.org/doc/html/rfc4616
+// MongoDB : Authentication - PLAIN
+// https://github.com/mongodb/specifications/blob/master/source/auth/auth.md#sasl-plain
+
+const (
+	// Mechanism is the mechanism name of PLAIN.
+	Mechanism = "PLAIN"
+	// UsernameID is the context key of the authenticated username, which is shared with SCRAM mechanisms.
+	UsernameID = scram.UsernameID
+)
+
+// ErrInvalidMessage is returned when the PLAIN message is malformed.
+var ErrInvalidMessage = errors.New("invalid PLAIN message")
+
+// ErrAuthenticationFailed is returned when the verifier rejects the credential.
+var ErrAuthenticationFailed = errors.New("authentication failed")
+
+// Verifier represents a verifier of PLAIN credentials, such as an external directory.
+type Verifier interface {
+	// VerifyPlain returns true if the specified password is valid for the user.
+	VerifyPlain(username string, password string) (bool, error)
+}
+
+// Server represents a PLAIN server mechanism which passes credentials to the verifier.
+type Server struct {
+	verifier Verifier
+}
+
+// NewServer returns a new PLAIN server mechanism with the specified verifier.
+func NewServer(verifier Verifier) *Server {
+	return &Server{
+		verifier: verifier,
+	}
+}
+
+// Name returns the mechanism name.
+func (server *Server) Name() string {
+	return Mechanism
+}
+
+// Type returns the mechanism type.
+func (server *Server) Type() mech.Type {
+	return mech.Server
+}
+
+// SetOptions sets the mechanism options before starting.
+func (server *Server) SetOptions(...mech.Option) error {
+	return nil
+}
+
+// Start returns the initial context.
+func (server *Server) Start(...mech.Option) (mech.Context, error) {
+	return &serverContext{
+		Store:  mech.NewStore(),
+		server: server,
+		step:   0,
+	}, nil
+}
+
+type serverContext struct {
+	mech.Store
+	server *Server
+	step   int
+}
+
+// Mechanism returns the mechanism.
+func (ctx *serverContext) Mechanism() mech.Mechanism {
+	return ctx.server
+}
+
+// Step returns the current step number.
+func (ctx *serverContext) Step() int {
+	return ctx.step
+}
+
+// Done returns true if the context is completed.
+func (ctx *serverContext) Done() bool {
+	return ctx.step == 1
+}
+
+// Dispose disposes the context.
+func (ctx *serverContext) Dispose() error {
+	return nil
+}
+
+// Next verifies the PLAIN message, and returns an empty response.
+func (ctx *serverContext) Next(params ...mech.Parameter) (mech.Response, error) {
+	if ctx.step != 0 {
+		return nil, fmt.Errorf("invalid step : %d", ctx.step)
+	}
+	if len(params) == 0 {
+		return nil, ErrInvalidMessage
+	}
+	var payload []byte
+	switch v := params[0].(type) {
+	case []byte:
+		payload = v
+	case mech.Payload:
+		payload = v
+	case string:
+		payload = []byte(v)
+	default:
+		return nil, ErrInvalidMessage
+	}
+
+	// message = [authzid] UTF8NUL authcid UTF8NUL passwd
+
+	fields := strings.Split(string(payload), "\x00")
+	if len(fields) != 3 {
+		return nil, ErrInvalidMessage
+	}
+	authzid, authcid, passwd := fields[0], fields[1], fields[2]
+	if len(authcid) == 0 || len(passwd) == 0 {
+		return nil, ErrInvalidMessage
+	}
+	// Acting as another identity is not supported.
+	if len(authzid) != 0 && authzid != authcid {
+		return nil, ErrAuthenticationFailed
+	}
+
+	ok, err := ctx.server.verifier.VerifyPlain(authcid, passwd)
+	if err != nil {
+		return nil, fmt.Errorf("%w : %w", ErrAuthenticationFailed, err)
+	}
+	if !ok {
+		return nil, ErrAuthenticationFailed
+	}
+
+	ctx.SetValue(UsernameID, authcid)
+	ctx.step++
+
+	return response{}, nil
+}
+
+// response represents an empty PLAIN server response.
+type response struct{}
+
+// Bytes returns the response bytes.
+func (res response) Bytes() []byte {
+	return []byte{}
+}
+
+// String returns the response as a string.
+func (res response) String() string {
+	return ""
+}
+
+// UsernameFrom returns the authentication identity of the specified PLAIN message.
+func UsernameFrom(payload []byte) (string, bool) {
+	fields := strings.Split(string(payload), "\x00")
+	if len(fields) != 3 || len(fields[1]) == 0 {
+		return "", false
+	}
+	return fields[1], true
+}
@@ -0,0 +1,127 @@
+// Copyright (C) 2024 The go-mongo Authors. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package scram
+
+import (
+	"crypto/hmac"
+	"errors"
+
+	"github.com/cybergarage/go-sasl/sasl/scram"
+	"github.com/cybergarage/go-sasl/sasl/util/rand"
+)
+
+// See : RFC 5802 - Salted Challenge Response Authentication Mechanism (SCRAM)
+// https://datatracker.ietf.org/doc/html/rfc5802
+
+const (
+	// SHA1 is the mechanism name of SCRAM-SHA-1.
+	SHA1 = "SCRAM-SHA-1"
+	// SHA256 is the mechanism name of SCRAM-SHA-256.
+	SHA256 = "SCRAM-SHA-256"
+	// MinIterationCount is the minimum iteration count recommended by RFC 7677.
+	MinIterationCount = 4096
+	// DefaultIterationCount is the default iteration count of SCRAM credentials as mongod does.
+	DefaultIterationCount = 15000
+	// DefaultSaltLength is the default salt length in bytes.
+	DefaultSaltLength = 16
+)
+
+// ErrInvalidIterationCount is returned when the iteration count is less than the minimum.
+var ErrInvalidIterationCount = errors.New("invalid iteration count")
+
+// HashFunc represents a hash function.
+type HashFunc = scram.HashFunc
+
+// Credential represents a SCRAM credential which is stored instead of the cleartext password.
+type Credential struct {
+	Salt           []byte `json:"salt"`
+	IterationCount int    `json:"iterationCount"`
+	StoredKey      []byte `json:"storedKey"`
+	ServerKey      []byte `json:"serverKey"`
+}
+
+// CredentialStore represents a store of SCRAM credentials.
+type CredentialStore interface {
+	// LookupSCRAMCredential looks up the SCRAM credential of the specified mechanism and user in the database.
+	LookupSCRAMCredential(mechanism string, db string, username string) (*Credential, bool, error)
+}
+
+// HashFuncFor returns the hash function of the specified mechanism.
+func HashFuncFor(mechanism string) (HashFunc, bool) {
+	switch mechanism {
+	case SHA1:
+		return scram.HashSHA1(), true
+	case SHA256:
+		return scram.HashSHA256(), true
+	}
+	return nil, false
+}
+
+// NewCredential returns a new SCRAM credential of the specified password with a random salt.
+func NewCredential(h HashFunc, password string, iterationCount int) (*Credential, error) {
+	salt, err := rand.NewSalt(DefaultSaltLength)
+	if err != nil {
+		return nil, err
+	}
+	return NewCredentialWithSalt(h, password, salt, iterationCount)
+}
+
+// NewCredentialWithSalt returns a new SCRAM credential of the specified password and salt.
+func NewCredentialWithSalt(h HashFunc, password string, salt []byte, iterationCount int) (*Credential, error) {
+	if iterationCount < MinIterationCount {
+		return nil, ErrInvalidIterationCount
+	}
+	saltedPassword, err := scram.SaltedPassword(h, password, salt, iterationCount)
+	if err != nil {
+		return nil, err
+	}
+	return &Credential{
+		Salt:           salt,
+		IterationCount: iterationCount,
+		StoredKey:      scram.StoredKey(h, scram.ClientKey(h, saltedPassword)),
+		ServerKey:      scram.ServerKey(h, saltedPassword),
+	}, nil
+}
+
+// newFakeCredential returns a credential of an unknown user which never verifies a proof.
+// The salt is derived from the user not to be distinguished from the stored credentials by repeated requests.
+func newFakeCredential(h HashFunc, key []byte, mechanism string, db string, username string, iterationCount int) *Credential {
+	seed := []byte(mechanism + "\x00" + db + "\x00" + username)
+	return &Credential{
+		Salt:           scram.HMAC(h, key, append([]byte("salt\x00"), seed...))[:DefaultSaltLength],
+		IterationCount: iterationCount,
+		StoredKey:      scram.HMAC(h, key, append([]byte("stored\x00"), seed...)),
+		ServerKey:      scram.HMAC(h, key, append([]byte("server\x00"), seed...)),
+	}
+}
+
+// VerifyProof returns true if the client proof matches the stored key for the specified auth message.
+func (cred *Credential) VerifyProof(h HashFunc, authMsg string, clientProof []byte) bool {
+	// ClientSignature := HMAC(StoredKey, AuthMessage)
+	clientSignature := scram.HMAC(h, cred.StoredKey, []byte(authMsg))
+	if len(clientProof) != len(clientSignature) {
+		return false
+	}
+	// ClientKey := ClientProof XOR ClientSignature
+	clientKey := scram.XOR(clientProof, clientSignature)
+	// StoredKey := H(ClientKey)
+	return hmac.Equal(cred.StoredKey, scram.H(h, clientKey))
+}
+
+// ServerSignature returns the server signature for the specified auth message.
+func (cred *Credential) ServerSignature(h HashFunc, authMsg string) []byte {
+	// ServerSignature := HMAC(ServerKey, AuthMessage)
+	return scram.HMAC(h, cred.ServerKey, []byte(authMsg))
+}
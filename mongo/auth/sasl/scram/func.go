@@ -19,6 +19,12 @@ import (
 	"github.com/cybergarage/go-sasl/sasl/scram"
 )
 
+// UsernameID is the context key of the authenticated username.
+const UsernameID = scram.UsernameID
+
+// DatabaseID is the context key of the database which the user is defined in.
+const DatabaseID = "db"
+
 // Message represents a SCRAM message.
 type Message = scram.Message
 
@@ -34,3 +40,12 @@ func NewMessageWithError(err error) *Message {
 func IsStandardError(err error) bool {
 	return scram.IsStandardError(err)
 }
+
+// UsernameFrom returns the username of the specified client first message.
+func UsernameFrom(payload []byte) (string, bool) {
+	msg, err := scram.NewMessageFromStringWithHeader(string(payload))
+	if err != nil {
+		return "", false
+	}
+	return msg.Username()
+}
@@ -0,0 +1,232 @@
+// Copyright (C) 2024 The go-mongo Authors. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package scram
+
+import (
+	"fmt"
+
+	"github.com/cybergarage/go-sasl/sasl/mech"
+	"github.com/cybergarage/go-sasl/sasl/scram"
+	"github.com/cybergarage/go-sasl/sasl/util/rand"
+)
+
+const (
+	randomSequenceLength = 24
+)
+
+// fakeCredentialKey is the secret to derive the credentials of unknown users, which is shared by the servers in the process.
+var fakeCredentialKey = newFakeCredentialKey()
+
+func newFakeCredentialKey() []byte {
+	key, err := rand.NewSalt(randomSequenceLength)
+	if err != nil {
+		panic(err)
+	}
+	return key
+}
+
+// Server represents a SCRAM server mechanism which verifies clients with stored SCRAM credentials.
+type Server struct {
+	name     string
+	hashFunc HashFunc
+	store    CredentialStore
+}
+
+// NewServer returns a new SCRAM server mechanism of the specified mechanism name with the credential store.
+func NewServer(name string, store CredentialStore) (*Server, error) {
+	h, ok := HashFuncFor(name)
+	if !ok {
+		return nil, fmt.Errorf("unknown SCRAM mechanism : %s", name)
+	}
+	return &Server{
+		name:     name,
+		hashFunc: h,
+		store:    store,
+	}, nil
+}
+
+// Name returns the mechanism name.
+func (server *Server) Name() string {
+	return server.name
+}
+
+// Type returns the mechanism type.
+func (server *Server) Type() mech.Type {
+	return mech.Server
+}
+
+// SetOptions sets the mechanism options before starting.
+func (server *Server) SetOptions(...mech.Option) error {
+	return nil
+}
+
+// Start returns the initial context. The IterationCount option sets the iteration count of the credentials of unknown users.
+func (server *Server) Start(opts ...mech.Option) (mech.Context, error) {
+	ctx := &serverContext{
+		Store:          mech.NewStore(),
+		server:         server,
+		step:           0,
+		iterationCount: DefaultIterationCount,
+	}
+	for _, opt := range opts {
+		if v, ok := opt.(mech.IterationCount); ok {
+			ctx.iterationCount = int(v)
+		}
+	}
+	return ctx, nil
+}
+
+type serverContext struct {
+	mech.Store
+	server         *Server
+	step           int
+	iterationCount int
+	cred           *Credential
+	clientFirstMsg *scram.Message
+	serverFirstMsg *scram.Message
+}
+
+// Mechanism returns the mechanism.
+func (ctx *serverContext) Mechanism() mech.Mechanism {
+	return ctx.server
+}
+
+// Step returns the current step number.
+func (ctx *serverContext) Step() int {
+	return ctx.step
+}
+
+// Done returns true if the context is completed.
+func (ctx *serverContext) Done() bool {
+	return ctx.step == 2
+}
+
+// Dispose disposes the context.
+func (ctx *serverContext) Dispose() error {
+	ctx.cred = nil
+	return nil
+}
+
+// Next returns the next response.
+func (ctx *serverContext) Next(params ...mech.Parameter) (mech.Response, error) {
+	if len(params) == 0 {
+		return nil, fmt.Errorf("no message")
+	}
+	switch ctx.step {
+	case 0:
+		msg, err := scram.NewMessageFromWithHeader(params[0])
+		if err != nil {
+			return nil, err
+		}
+		res, err := ctx.firstMessageFrom(msg)
+		if err != nil {
+			return nil, err
+		}
+		ctx.step++
+		return res, nil
+	case 1:
+		msg, err := scram.NewMessageFrom(params[0])
+		if err != nil {
+			return nil, err
+		}
+		res, err := ctx.finalMessageFrom(msg)
+		if err != nil {
+			return nil, err
+		}
+		ctx.step++
+		return res, nil
+	}
+	return nil, fmt.Errorf("invalid step : %d", ctx.step)
+}
+
+func (ctx *serverContext) firstMessageFrom(clientMsg *scram.Message) (*scram.Message, error) {
+	if clientMsg == nil {
+		return nil, scram.ErrNoResources
+	}
+
+	username, ok := clientMsg.Username()
+	if !ok || len(username) == 0 {
+		return nil, scram.ErrUnknownUser
+	}
+	ctx.SetValue(UsernameID, username)
+
+	db, _ := ctx.Value(DatabaseID)
+	dbName, _ := db.(string)
+	cred, ok, err := ctx.server.store.LookupSCRAMCredential(ctx.server.name, dbName, username)
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		// Unknown users fail in the final step as the invalid proof not to disclose which users exist.
+		cred = newFakeCredential(ctx.server.hashFunc, fakeCredentialKey, ctx.server.name, dbName, username, ctx.iterationCount)
+	}
+	ctx.cred = cred
+
+	cr, ok := clientMsg.RandomSequence()
+	if !ok {
+		return nil, scram.ErrNoResources
+	}
+	sr, err := rand.NewRandomSequence(randomSequenceLength)
+	if err != nil {
+		return nil, err
+	}
+
+	msg := scram.NewMessage()
+	msg.SetRandomSequence(cr + sr.String())
+	msg.SetSaltBytes(cred.Salt)
+	msg.SetIterationCount(cred.IterationCount)
+
+	ctx.clientFirstMsg = clientMsg
+	ctx.serverFirstMsg = msg
+
+	return msg, nil
+}
+
+func (ctx *serverContext) finalMessageFrom(clientMsg *scram.Message) (*scram.Message, error) {
+	if clientMsg == nil || ctx.cred == nil || ctx.clientFirstMsg == nil || ctx.serverFirstMsg == nil {
+		return nil, scram.ErrNoResources
+	}
+
+	// The server MUST verify that the nonce sent by the client in the second message is
+	// the same as the one sent by the server in its first message.
+
+	clientRS, ok := clientMsg.RandomSequence()
+	if !ok {
+		return nil, scram.ErrNoResources
+	}
+	serverRS, _ := ctx.serverFirstMsg.RandomSequence()
+	if clientRS != serverRS {
+		return nil, scram.ErrOtherError
+	}
+
+	clientProof, ok := clientMsg.ClientProof()
+	if !ok {
+		return nil, scram.ErrInvalidProof
+	}
+
+	// AuthMessage := client-first-message-bare + "," +
+	//                server-first-message + "," +
+	//                client-final-message-without-proof
+
+	authMsg := scram.AuthMessage(ctx.clientFirstMsg.StringWithoutHeader(), ctx.serverFirstMsg.String(), clientMsg.StringWithoutProof())
+	if !ctx.cred.VerifyProof(ctx.server.hashFunc, authMsg, clientProof) {
+		return nil, scram.ErrInvalidProof
+	}
+
+	msg := scram.NewMessage()
+	msg.SetServerSignature(ctx.cred.ServerSignature(ctx.server.hashFunc, authMsg))
+
+	return msg, nil
+}
@@ -0,0 +1,41 @@
+// Copyright (C) 2019 The go-mongo Authors. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package auth
+
+import (
+	"io"
+
+	"github.com/cybergarage/go-mongo/mongo/auth/sasl/scram"
+)
+
+// DefaultSCRAMIterationCount is the default iteration count of SCRAM credentials.
+const DefaultSCRAMIterationCount = scram.DefaultIterationCount
+
+// SCRAMCredentialStore represents a credential store which keeps salted SCRAM-SHA-1 and SCRAM-SHA-256 credentials instead of cleartext passwords.
+type SCRAMCredentialStore interface {
+	CredentialStore
+	CredentialUpdater
+	scram.CredentialStore
+	// UpdateSCRAMCredential sets the credentials of the specified user with the password and iteration count.
+	UpdateSCRAMCredential(name UserName, password string, iterationCount int) error
+	// Load loads the credentials from the specified reader.
+	Load(r io.Reader) error
+	// Save saves the credentials to the specified writer.
+	Save(w io.Writer) error
+	// LoadFile loads the credentials from the specified file.
+	LoadFile(name string) error
+	// SaveFile saves the credentials to the specified file.
+	SaveFile(name string) error
+}
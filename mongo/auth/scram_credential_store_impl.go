@@ -0,0 +1,152 @@
+// Copyright (C) 2019 The go-mongo Authors. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package auth
+
+import (
+	"encoding/json"
+	"io"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"github.com/cybergarage/go-mongo/mongo/auth/sasl"
+	"github.com/cybergarage/go-mongo/mongo/auth/sasl/scram"
+	"github.com/xdg-go/stringprep"
+)
+
+// scramCredentials represents the SCRAM credentials by mechanism of users which are keyed by the user identifiers.
+type scramCredentials map[string]map[string]*scram.Credential
+
+type scramCredentialStore struct {
+	creds scramCredentials
+	mutex *sync.RWMutex
+}
+
+// NewSCRAMCredentialStore returns a new SCRAMCredentialStore.
+func NewSCRAMCredentialStore() SCRAMCredentialStore {
+	return &scramCredentialStore{
+		creds: scramCredentials{},
+		mutex: &sync.RWMutex{},
+	}
+}
+
+// LookupCredential looks up a credential by the query. The store never has cleartext passwords, so it returns no credential.
+func (store *scramCredentialStore) LookupCredential(q sasl.SASLQuery) (sasl.SASLCredential, bool, error) {
+	return nil, false, nil
+}
+
+// LookupSCRAMCredential looks up the SCRAM credential of the specified mechanism and user in the database.
+func (store *scramCredentialStore) LookupSCRAMCredential(mechanism string, db string, username string) (*scram.Credential, bool, error) {
+	store.mutex.RLock()
+	defer store.mutex.RUnlock()
+	cred, ok := store.creds[NewUserName(username, db).ID()][mechanism]
+	return cred, ok, nil
+}
+
+// UpdateCredential sets the credentials of the specified user with the password and the default iteration count.
+// The server updates the credentials with the configured iteration count by UpdateSCRAMCredential.
+func (store *scramCredentialStore) UpdateCredential(name UserName, password string) error {
+	return store.UpdateSCRAMCredential(name, password, DefaultSCRAMIterationCount)
+}
+
+// UpdateSCRAMCredential sets the credentials of the specified user with the password and iteration count.
+func (store *scramCredentialStore) UpdateSCRAMCredential(name UserName, password string, iterationCount int) error {
+	// SCRAM-SHA-1 uses the MongoDB hashed variant of the password.
+	digest, err := sasl.MongoPasswordDigest(name.User, password)
+	if err != nil {
+		return err
+	}
+	// SCRAM-SHA-256 uses the password prepared with SASLprep as RFC 7677 requires.
+	prepared, err := stringprep.SASLprep.Prepare(password)
+	if err != nil {
+		return err
+	}
+	passwords := map[string]string{
+		scram.SHA1:   digest,
+		scram.SHA256: prepared,
+	}
+	creds := map[string]*scram.Credential{}
+	for mechanism, password := range passwords {
+		h, _ := scram.HashFuncFor(mechanism)
+		cred, err := scram.NewCredential(h, password, iterationCount)
+		if err != nil {
+			return err
+		}
+		creds[mechanism] = cred
+	}
+	store.mutex.Lock()
+	defer store.mutex.Unlock()
+	store.creds[name.ID()] = creds
+	return nil
+}
+
+// RemoveCredential removes the credentials of the specified user.
+func (store *scramCredentialStore) RemoveCredential(name UserName) error {
+	store.mutex.Lock()
+	defer store.mutex.Unlock()
+	delete(store.creds, name.ID())
+	return nil
+}
+
+// Load loads the credentials from the specified reader.
+func (store *scramCredentialStore) Load(r io.Reader) error {
+	creds := scramCredentials{}
+	err := json.NewDecoder(r).Decode(&creds)
+	if err != nil {
+		return err
+	}
+	store.mutex.Lock()
+	defer store.mutex.Unlock()
+	store.creds = creds
+	return nil
+}
+
+// Save saves the credentials to the specified writer.
+func (store *scramCredentialStore) Save(w io.Writer) error {
+	store.mutex.RLock()
+	defer store.mutex.RUnlock()
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(store.creds)
+}
+
+// LoadFile loads the credentials from the specified file.
+func (store *scramCredentialStore) LoadFile(name string) error {
+	f, err := os.Open(name)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	return store.Load(f)
+}
+
+// SaveFile saves the credentials to the specified file.
+func (store *scramCredentialStore) SaveFile(name string) error {
+	f, err := os.CreateTemp(filepath.Dir(name), filepath.Base(name)+".*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(f.Name())
+	err = store.Save(f)
+	if err != nil {
+		f.Close()
+		return err
+	}
+	err = f.Close()
+	if err != nil {
+		return err
+	}
+	return os.Rename(f.Name(), name)
+}
@@ -0,0 +1,234 @@
+// Copyright (C) 2019 The go-mongo Authors. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tls
+
+import (
+	"bytes"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"math/big"
+	"os"
+	"sync"
+	"time"
+)
+
+const (
+	// DefaultCRLCheckInterval is the default interval to check whether the CRL files have changed.
+	DefaultCRLCheckInterval = time.Second
+	pemTypeCRL              = "X509 CRL"
+)
+
+// ErrCertificateRevoked is returned when the peer certificate is revoked.
+var ErrCertificateRevoked = errors.New("certificate revoked")
+
+// ErrInvalidCRL is returned when the CRL file is malformed.
+var ErrInvalidCRL = errors.New("invalid CRL")
+
+func newErrCertificateRevoked(cert *x509.Certificate) error {
+	return fmt.Errorf("%w : %s (serial %s)", ErrCertificateRevoked, cert.Subject, cert.SerialNumber)
+}
+
+func newErrInvalidCRL(file string, err error) error {
+	return fmt.Errorf("%w : %s (%w)", ErrInvalidCRL, file, err)
+}
+
+// crlFile represents a loaded CRL file.
+type crlFile struct {
+	name    string
+	modTime time.Time
+	size    int64
+	crls    []*x509.RevocationList
+}
+
+// CRLStore represents certificate revocation lists loaded from PEM or DER files,
+// which are reloaded when the files change.
+type CRLStore struct {
+	files         []*crlFile
+	checkInterval time.Duration
+	lastChecked   time.Time
+	mutex         *sync.Mutex
+}
+
+// NewCRLStore returns a new empty CRL store.
+func NewCRLStore() *CRLStore {
+	return &CRLStore{
+		files:         []*crlFile{},
+		checkInterval: DefaultCRLCheckInterval,
+		lastChecked:   time.Time{},
+		mutex:         &sync.Mutex{},
+	}
+}
+
+// SetCheckInterval sets the interval to check whether the CRL files have changed.
+func (store *CRLStore) SetCheckInterval(d time.Duration) {
+	store.mutex.Lock()
+	defer store.mutex.Unlock()
+	store.checkInterval = d
+}
+
+// LoadFiles loads the specified CRL files, and replaces the loaded CRLs.
+func (store *CRLStore) LoadFiles(names ...string) error {
+	files := make([]*crlFile, len(names))
+	for n, name := range names {
+		file, err := loadCRLFile(name)
+		if err != nil {
+			return err
+		}
+		files[n] = file
+	}
+	store.mutex.Lock()
+	defer store.mutex.Unlock()
+	store.files = files
+	store.lastChecked = time.Now()
+	return nil
+}
+
+// Files returns the names of the loaded CRL files.
+func (store *CRLStore) Files() []string {
+	store.mutex.Lock()
+	defer store.mutex.Unlock()
+	names := make([]string, len(store.files))
+	for n, file := range store.files {
+		names[n] = file.name
+	}
+	return names
+}
+
+// Reload reloads the CRL files which have changed since they were loaded.
+// A file which fails to load keeps the previously loaded CRLs.
+func (store *CRLStore) Reload() error {
+	store.mutex.Lock()
+	defer store.mutex.Unlock()
+	return store.reload()
+}
+
+func (store *CRLStore) reload() error {
+	var errs error
+	for n, file := range store.files {
+		fi, err := os.Stat(file.name)
+		if err != nil {
+			errs = errors.Join(errs, err)
+			continue
+		}
+		if fi.ModTime().Equal(file.modTime) && fi.Size() == file.size {
+			continue
+		}
+		newFile, err := loadCRLFile(file.name)
+		if err != nil {
+			errs = errors.Join(errs, err)
+			continue
+		}
+		store.files[n] = newFile
+	}
+	store.lastChecked = time.Now()
+	return errs
+}
+
+// IsRevoked returns true if the certificate is revoked by a CRL signed by the issuer.
+func (store *CRLStore) IsRevoked(cert *x509.Certificate, issuer *x509.Certificate) bool {
+	store.mutex.Lock()
+	defer store.mutex.Unlock()
+	if store.checkInterval <= time.Since(store.lastChecked) {
+		_ = store.reload()
+	}
+	for _, file := range store.files {
+		for _, crl := range file.crls {
+			if !bytes.Equal(crl.RawIssuer, cert.RawIssuer) {
+				continue
+			}
+			if crl.CheckSignatureFrom(issuer) != nil {
+				continue
+			}
+			if isSerialRevoked(crl, cert.SerialNumber) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// VerifyPeerCertificate returns an error if a certificate of the verified chains is revoked.
+// It can be set to tls.Config.VerifyPeerCertificate to reject revoked certificates during the handshake.
+func (store *CRLStore) VerifyPeerCertificate(rawCerts [][]byte, verifiedChains [][]*x509.Certificate) error {
+	for _, chain := range verifiedChains {
+		for n := 0; n < len(chain)-1; n++ {
+			if store.IsRevoked(chain[n], chain[n+1]) {
+				return newErrCertificateRevoked(chain[n])
+			}
+		}
+	}
+	return nil
+}
+
+func isSerialRevoked(crl *x509.RevocationList, serial *big.Int) bool {
+	for _, entry := range crl.RevokedCertificateEntries {
+		if entry.SerialNumber.Cmp(serial) == 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// loadCRLFile loads CRLs from the specified file, which has PEM encoded CRLs or a DER encoded CRL.
+func loadCRLFile(name string) (*crlFile, error) {
+	fi, err := os.Stat(name)
+	if err != nil {
+		return nil, err
+	}
+	data, err := os.ReadFile(name)
+	if err != nil {
+		return nil, err
+	}
+	crls, err := parseCRLs(data)
+	if err != nil {
+		return nil, newErrInvalidCRL(name, err)
+	}
+	return &crlFile{
+		name:    name,
+		modTime: fi.ModTime(),
+		size:    fi.Size(),
+		crls:    crls,
+	}, nil
+}
+
+func parseCRLs(data []byte) ([]*x509.RevocationList, error) {
+	crls := []*x509.RevocationList{}
+	rest := data
+	for {
+		var block *pem.Block
+		block, rest = pem.Decode(rest)
+		if block == nil {
+			break
+		}
+		if block.Type != pemTypeCRL {
+			continue
+		}
+		crl, err := x509.ParseRevocationList(block.Bytes)
+		if err != nil {
+			return nil, err
+		}
+		crls = append(crls, crl)
+	}
+	if 0 < len(crls) {
+		return crls, nil
+	}
+	crl, err := x509.ParseRevocationList(data)
+	if err != nil {
+		return nil, err
+	}
+	return append(crls, crl), nil
+}
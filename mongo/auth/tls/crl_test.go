@@ -0,0 +1,262 @@
+// Copyright (C) 2022 The go-mongo Authors All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tls
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"errors"
+	"math/big"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+type testCA struct {
+	cert *x509.Certificate
+	key  *ecdsa.PrivateKey
+}
+
+func newTestCA(t *testing.T, name string) *testCA {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tmpl := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: name},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		KeyUsage:              x509.KeyUsageCertSign | x509.KeyUsageCRLSign,
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &testCA{cert: cert, key: key}
+}
+
+func (ca *testCA) issue(t *testing.T, serial int64) *x509.Certificate {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tmpl := &x509.Certificate{
+		SerialNumber: big.NewInt(serial),
+		Subject:      pkix.Name{CommonName: "client"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, ca.cert, &key.PublicKey, ca.key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return cert
+}
+
+func (ca *testCA) writeCRL(t *testing.T, name string, isPEM bool, serials ...int64) {
+	t.Helper()
+	entries := []x509.RevocationListEntry{}
+	for _, serial := range serials {
+		entries = append(entries, x509.RevocationListEntry{SerialNumber: big.NewInt(serial), RevocationTime: time.Now()})
+	}
+	data, err := x509.CreateRevocationList(rand.Reader, &x509.RevocationList{
+		Number:                    big.NewInt(time.Now().UnixNano()),
+		ThisUpdate:                time.Now().Add(-time.Minute),
+		NextUpdate:                time.Now().Add(time.Hour),
+		RevokedCertificateEntries: entries,
+	}, ca.cert, ca.key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if isPEM {
+		data = pem.EncodeToMemory(&pem.Block{Type: pemTypeCRL, Bytes: data})
+	}
+	writeTestFile(t, name, data)
+}
+
+func writeTestFile(t *testing.T, name string, data []byte) {
+	t.Helper()
+	if err := os.WriteFile(name, data, 0o600); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestCRLStoreLoadFiles(t *testing.T) {
+	ca := newTestCA(t, "CA")
+	dir := t.TempDir()
+
+	for _, isPEM := range []bool{true, false} {
+		name := filepath.Join(dir, "crl")
+		ca.writeCRL(t, name, isPEM, 3)
+
+		store := NewCRLStore()
+		if err := store.LoadFiles(name); err != nil {
+			t.Errorf("PEM(%t) : %s", isPEM, err)
+			continue
+		}
+		if files := store.Files(); len(files) != 1 || files[0] != name {
+			t.Errorf("%v != [%s]", files, name)
+		}
+		if !store.IsRevoked(ca.issue(t, 3), ca.cert) {
+			t.Errorf("PEM(%t) : revoked certificate is not revoked", isPEM)
+		}
+		if store.IsRevoked(ca.issue(t, 4), ca.cert) {
+			t.Errorf("PEM(%t) : valid certificate is revoked", isPEM)
+		}
+	}
+
+	// Invalid files are rejected without replacing the loaded CRLs
+
+	invalidFile := filepath.Join(dir, "invalid.crl")
+	writeTestFile(t, invalidFile, []byte("not a CRL"))
+
+	store := NewCRLStore()
+	if err := store.LoadFiles(filepath.Join(dir, "crl")); err != nil {
+		t.Error(err)
+		return
+	}
+	if err := store.LoadFiles(invalidFile); !errors.Is(err, ErrInvalidCRL) {
+		t.Errorf("%v != %s", err, ErrInvalidCRL)
+	}
+	if err := store.LoadFiles(filepath.Join(dir, "none.crl")); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("%v != %s", err, os.ErrNotExist)
+	}
+	if files := store.Files(); len(files) != 1 {
+		t.Errorf("loaded CRLs are replaced : %v", files)
+	}
+}
+
+func TestCRLStoreIssuer(t *testing.T) {
+	ca := newTestCA(t, "CA")
+	otherCA := newTestCA(t, "Other CA")
+	name := filepath.Join(t.TempDir(), "crl.pem")
+	otherCA.writeCRL(t, name, true, 3)
+
+	store := NewCRLStore()
+	if err := store.LoadFiles(name); err != nil {
+		t.Error(err)
+		return
+	}
+
+	// CRLs of other issuers are ignored
+
+	if store.IsRevoked(ca.issue(t, 3), ca.cert) {
+		t.Errorf("certificate is revoked by the CRL of the other issuer")
+	}
+	if !store.IsRevoked(otherCA.issue(t, 3), otherCA.cert) {
+		t.Errorf("revoked certificate is not revoked")
+	}
+
+	// CRLs which are not signed by the issuer are ignored
+
+	if store.IsRevoked(otherCA.issue(t, 3), ca.cert) {
+		t.Errorf("certificate is revoked by the CRL which is not signed by the issuer")
+	}
+}
+
+func TestCRLStoreReload(t *testing.T) {
+	ca := newTestCA(t, "CA")
+	name := filepath.Join(t.TempDir(), "crl.pem")
+	ca.writeCRL(t, name, true, 3)
+
+	store := NewCRLStore()
+	store.SetCheckInterval(time.Hour)
+	if err := store.LoadFiles(name); err != nil {
+		t.Error(err)
+		return
+	}
+	cert := ca.issue(t, 4)
+
+	// The changed file is not reloaded until the check interval passes
+
+	ca.writeCRL(t, name, true, 3, 4)
+	if store.IsRevoked(cert, ca.cert) {
+		t.Errorf("CRL is reloaded before the check interval")
+	}
+
+	// The changed file is reloaded explicitly
+
+	if err := store.Reload(); err != nil {
+		t.Error(err)
+		return
+	}
+	if !store.IsRevoked(cert, ca.cert) {
+		t.Errorf("CRL is not reloaded")
+	}
+
+	// A broken file keeps the previously loaded CRLs
+
+	writeTestFile(t, name, []byte("not a CRL"))
+	if err := store.Reload(); !errors.Is(err, ErrInvalidCRL) {
+		t.Errorf("%v != %s", err, ErrInvalidCRL)
+	}
+	if !store.IsRevoked(cert, ca.cert) {
+		t.Errorf("previously loaded CRL is dropped")
+	}
+
+	// The changed file is reloaded automatically after the check interval
+
+	store.SetCheckInterval(0)
+	ca.writeCRL(t, name, true, 3)
+	if store.IsRevoked(cert, ca.cert) {
+		t.Errorf("CRL is not reloaded after the check interval")
+	}
+}
+
+func TestCRLStoreVerifyPeerCertificate(t *testing.T) {
+	ca := newTestCA(t, "CA")
+	name := filepath.Join(t.TempDir(), "crl.pem")
+	ca.writeCRL(t, name, true, 3)
+
+	store := NewCRLStore()
+	if err := store.LoadFiles(name); err != nil {
+		t.Error(err)
+		return
+	}
+
+	revokedChains := [][]*x509.Certificate{{ca.issue(t, 3), ca.cert}}
+	if err := store.VerifyPeerCertificate(nil, revokedChains); !errors.Is(err, ErrCertificateRevoked) {
+		t.Errorf("%v != %s", err, ErrCertificateRevoked)
+	}
+
+	validChains := [][]*x509.Certificate{{ca.issue(t, 4), ca.cert}}
+	if err := store.VerifyPeerCertificate(nil, validChains); err != nil {
+		t.Error(err)
+	}
+
+	if err := store.VerifyPeerCertificate(nil, nil); err != nil {
+		t.Error(err)
+	}
+}
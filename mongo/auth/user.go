@@ -0,0 +1,80 @@
+// Copyright (C) 2019 The go-mongo Authors. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package auth
+
+import (
+	"fmt"
+	"slices"
+)
+
+// UserName represents a user name which is defined in a database.
+type UserName struct {
+	User string
+	DB   string
+}
+
+// NewUserName returns a new user name of the specified user and database.
+func NewUserName(user string, db string) UserName {
+	return UserName{
+		User: user,
+		DB:   db,
+	}
+}
+
+// ID returns the user identifier as "<db>.<user>" as MongoDB does.
+func (name UserName) ID() string {
+	return fmt.Sprintf("%s.%s", name.DB, name.User)
+}
+
+// String returns the string description of the user name.
+func (name UserName) String() string {
+	return fmt.Sprintf("%s@%s", name.User, name.DB)
+}
+
+// User represents a user which has roles.
+type User struct {
+	UserName
+	Roles []RoleName
+}
+
+// NewUser returns a new user with the specified roles.
+func NewUser(name UserName, roles ...RoleName) *User {
+	return &User{
+		UserName: name,
+		Roles:    roles,
+	}
+}
+
+// HasRole returns true if the user has the specified role directly.
+func (user *User) HasRole(name RoleName) bool {
+	return slices.Contains(user.Roles, name)
+}
+
+// GrantRoles adds the specified roles which the user does not have.
+func (user *User) GrantRoles(names ...RoleName) {
+	for _, name := range names {
+		if user.HasRole(name) {
+			continue
+		}
+		user.Roles = append(user.Roles, name)
+	}
+}
+
+// RevokeRoles removes the specified roles.
+func (user *User) RevokeRoles(names ...RoleName) {
+	user.Roles = slices.DeleteFunc(user.Roles, func(name RoleName) bool {
+		return slices.Contains(names, name)
+	})
+}
@@ -19,6 +19,18 @@ import (
 
 	"github.com/cybergarage/go-mongo/mongo/bson"
 	"github.com/cybergarage/go-mongo/mongo/message"
+	"go.mongodb.org/mongo-driver/x/bsonx/bsoncore"
+)
+
+// See : listCommands - MongoDB Manual
+// https://www.mongodb.com/docs/manual/reference/command/listCommands/
+
+const (
+	commandsElement     = "commands"
+	helpElement         = "help"
+	adminOnlyElement    = "adminOnly"
+	requiresAuthElement = "requiresAuth"
+	secondaryOkElement  = "secondaryOk"
 )
 
 // BaseCommandExecutor is a complete hander for CommandExecutor.
@@ -26,6 +38,8 @@ type BaseCommandExecutor struct {
 	UserCommandExecutor
 	DatabaseCommandExecutor
 	AuthCommandExecutor
+	UserManagementCommandExecutor
+	*CommandRegistry
 }
 
 func baseCommandExecutorNotImplementedError(q *Query) error {
@@ -35,16 +49,160 @@ func baseCommandExecutorNotImplementedError(q *Query) error {
 // NewBaseCommandExecutor returns a complete null executor for CommandExecutor.
 func NewBaseCommandExecutor() *BaseCommandExecutor {
 	executor := &BaseCommandExecutor{
-		UserCommandExecutor:     nil,
-		DatabaseCommandExecutor: nil,
-		AuthCommandExecutor:     nil,
+		UserCommandExecutor:           nil,
+		DatabaseCommandExecutor:       nil,
+		AuthCommandExecutor:           nil,
+		UserManagementCommandExecutor: nil,
+		CommandRegistry:               NewCommandRegistry(),
 	}
 	executor.UserCommandExecutor = executor
 	executor.DatabaseCommandExecutor = executor
 	executor.AuthCommandExecutor = executor
+	executor.UserManagementCommandExecutor = executor
+	executor.registerBaseCommands()
 	return executor
 }
 
+// registerBaseCommands registers the built-in commands which are dispatched to the current executors.
+func (executor *BaseCommandExecutor) registerBaseCommands() {
+	specs := []*CommandSpec{
+		NewCommandSpec("hello",
+			func(conn *Conn, cmd *Command) (bson.Document, error) {
+				return executor.DatabaseCommandExecutor.Hello(conn, cmd)
+			},
+			WithCommandHelp("returns information about the role of this instance"),
+			WithCommandAuthRequired(false),
+			WithCommandSecondaryAllowed(true)),
+		NewCommandSpec("isMaster",
+			func(conn *Conn, cmd *Command) (bson.Document, error) {
+				return executor.DatabaseCommandExecutor.Hello(conn, cmd)
+			},
+			WithCommandHelp("returns information about the role of this instance"),
+			WithCommandAuthRequired(false),
+			WithCommandSecondaryAllowed(true)),
+		NewCommandSpec("buildInfo",
+			func(conn *Conn, cmd *Command) (bson.Document, error) {
+				return executor.DatabaseCommandExecutor.BuildInfo(conn, cmd)
+			},
+			WithCommandHelp("returns the build information of this instance"),
+			WithCommandAuthRequired(false),
+			WithCommandSecondaryAllowed(true)),
+		NewCommandSpec("getLastError",
+			func(conn *Conn, cmd *Command) (bson.Document, error) {
+				return executor.DatabaseCommandExecutor.GetLastError(conn, cmd)
+			},
+			WithCommandHelp("returns the error status of the preceding write operation"),
+			WithCommandSecondaryAllowed(true)),
+		NewCommandSpec("ping",
+			executor.okCommand,
+			WithCommandHelp("tests whether this instance is responding"),
+			WithCommandAuthRequired(false),
+			WithCommandSecondaryAllowed(true)),
+		NewCommandSpec("endSessions",
+			executor.okCommand,
+			WithCommandHelp("ends the specified sessions"),
+			WithCommandSecondaryAllowed(true)),
+		NewCommandSpec("listCommands",
+			executor.ListCommands,
+			WithCommandHelp("returns the registered commands"),
+			WithCommandAuthRequired(false),
+			WithCommandSecondaryAllowed(true)),
+		NewCommandSpec("killOp",
+			func(conn *Conn, cmd *Command) (bson.Document, error) {
+				return executor.DatabaseCommandExecutor.KillOp(conn, cmd)
+			},
+			WithCommandHelp("kills the specified operation"),
+			WithCommandAdminOnly(true),
+			WithCommandSecondaryAllowed(true)),
+		NewCommandSpec("currentOp",
+			func(conn *Conn, cmd *Command) (bson.Document, error) {
+				return executor.DatabaseCommandExecutor.CurrentOp(conn, cmd)
+			},
+			WithCommandHelp("returns the in-progress operations"),
+			WithCommandAdminOnly(true),
+			WithCommandSecondaryAllowed(true)),
+		NewCommandSpec("saslStart",
+			func(conn *Conn, cmd *Command) (bson.Document, error) {
+				return executor.AuthCommandExecutor.SASLStart(conn, cmd)
+			},
+			WithCommandHelp("starts a SASL authentication conversation"),
+			WithCommandAuthRequired(false),
+			WithCommandSecondaryAllowed(true)),
+		NewCommandSpec("saslContinue",
+			func(conn *Conn, cmd *Command) (bson.Document, error) {
+				return executor.AuthCommandExecutor.SASLContinue(conn, cmd)
+			},
+			WithCommandHelp("continues a SASL authentication conversation"),
+			WithCommandAuthRequired(false),
+			WithCommandSecondaryAllowed(true)),
+		NewCommandSpec("authenticate",
+			func(conn *Conn, cmd *Command) (bson.Document, error) {
+				return executor.AuthCommandExecutor.Authenticate(conn, cmd)
+			},
+			WithCommandHelp("authenticates the connection"),
+			WithCommandAuthRequired(false),
+			WithCommandSecondaryAllowed(true)),
+		NewCommandSpec("logout",
+			func(conn *Conn, cmd *Command) (bson.Document, error) {
+				return executor.AuthCommandExecutor.Logout(conn, cmd)
+			},
+			WithCommandHelp("logs out the authenticated user of the connection"),
+			WithCommandAuthRequired(false),
+			WithCommandSecondaryAllowed(true)),
+		NewCommandSpec("createUser",
+			func(conn *Conn, cmd *Command) (bson.Document, error) {
+				return executor.UserManagementCommandExecutor.CreateUser(conn, cmd)
+			},
+			WithCommandHelp("creates a new user"),
+			WithCommandWrite(true)),
+		NewCommandSpec("updateUser",
+			func(conn *Conn, cmd *Command) (bson.Document, error) {
+				return executor.UserManagementCommandExecutor.UpdateUser(conn, cmd)
+			},
+			WithCommandHelp("updates the specified user"),
+			WithCommandWrite(true)),
+		NewCommandSpec("dropUser",
+			func(conn *Conn, cmd *Command) (bson.Document, error) {
+				return executor.UserManagementCommandExecutor.DropUser(conn, cmd)
+			},
+			WithCommandHelp("removes the specified user"),
+			WithCommandWrite(true)),
+		NewCommandSpec("usersInfo",
+			func(conn *Conn, cmd *Command) (bson.Document, error) {
+				return executor.UserManagementCommandExecutor.UsersInfo(conn, cmd)
+			},
+			WithCommandHelp("returns information about the users"),
+			WithCommandSecondaryAllowed(true)),
+		NewCommandSpec("createRole",
+			func(conn *Conn, cmd *Command) (bson.Document, error) {
+				return executor.UserManagementCommandExecutor.CreateRole(conn, cmd)
+			},
+			WithCommandHelp("creates a new role"),
+			WithCommandWrite(true)),
+		NewCommandSpec("grantRolesToUser",
+			func(conn *Conn, cmd *Command) (bson.Document, error) {
+				return executor.UserManagementCommandExecutor.GrantRolesToUser(conn, cmd)
+			},
+			WithCommandHelp("grants the roles to the specified user"),
+			WithCommandWrite(true)),
+		NewCommandSpec("revokeRolesFromUser",
+			func(conn *Conn, cmd *Command) (bson.Document, error) {
+				return executor.UserManagementCommandExecutor.RevokeRolesFromUser(conn, cmd)
+			},
+			WithCommandHelp("revokes the roles from the specified user"),
+			WithCommandWrite(true)),
+		NewCommandSpec("rolesInfo",
+			func(conn *Conn, cmd *Command) (bson.Document, error) {
+				return executor.UserManagementCommandExecutor.RolesInfo(conn, cmd)
+			},
+			WithCommandHelp("returns information about the roles"),
+			WithCommandSecondaryAllowed(true)),
+	}
+	for _, spec := range specs {
+		executor.RegisterCommand(spec)
+	}
+}
+
 // SetUserCommandExecutor sets a command exector for database operation commands.
 func (executor *BaseCommandExecutor) SetUserCommandExecutor(fn UserCommandExecutor) {
 	executor.UserCommandExecutor = fn
@@ -60,40 +218,51 @@ func (executor *BaseCommandExecutor) SetAuthCommandExecutor(fn AuthCommandExecut
 	executor.AuthCommandExecutor = fn
 }
 
+// SetUserManagementCommandExecutor sets a command exector for user and role management commands.
+func (executor *BaseCommandExecutor) SetUserManagementCommandExecutor(fn UserManagementCommandExecutor) {
+	executor.UserManagementCommandExecutor = fn
+}
+
 //////////////////////////////////////////////////
 // CommandExecutor
 //////////////////////////////////////////////////
 
-// ExecuteCommand handles query commands other than those explicitly specified above.
+// ExecuteCommand runs the registered command handler of the command name, and returns a command not found error for unregistered commands.
 func (executor *BaseCommandExecutor) ExecuteCommand(conn *Conn, cmd *Command) (bson.Document, error) {
-	if executor.DatabaseCommandExecutor == nil {
-		// Returns only a 'ok' response as default
-		resDoc, err := message.NewOkResponse().BSONBytes()
-		if err != nil {
-			return nil, err
-		}
-		return resDoc, nil
-	}
-
-	switch cmd.Type() {
-	case message.IsMaster, message.Hello:
-		return executor.DatabaseCommandExecutor.Hello(conn, cmd)
-	case message.BuildInfo:
-		return executor.DatabaseCommandExecutor.BuildInfo(conn, cmd)
-	case message.GetLastError:
-		return executor.DatabaseCommandExecutor.GetLastError(conn, cmd)
-	case message.SASLStart:
-		return executor.AuthCommandExecutor.SASLStart(conn, cmd)
-	case message.SASLContinue:
-		return executor.AuthCommandExecutor.SASLContinue(conn, cmd)
-	}
-
-	// Returns only a 'ok' response as default
-	resDoc, err := message.NewOkResponse().BSONBytes()
+	spec, ok := executor.LookupCommand(cmd.Type())
+	if !ok {
+		return nil, NewCommandNotFoundError(cmd.Type())
+	}
+	if spec.IsAdminOnly() && !cmd.IsAdminCommand() {
+		return nil, NewErrorf(ErrorCodeUnauthorized, "%s may only be run against the admin database.", spec.Name())
+	}
+	return spec.Execute(conn, cmd)
+}
+
+// okCommand returns only a 'ok' response.
+func (executor *BaseCommandExecutor) okCommand(conn *Conn, cmd *Command) (bson.Document, error) {
+	return message.NewOkResponse().BSONBytes()
+}
+
+// ListCommands returns the registered commands with the metadata.
+func (executor *BaseCommandExecutor) ListCommands(conn *Conn, cmd *Command) (bson.Document, error) {
+	cmdsBuilder := bsoncore.NewDocumentBuilder()
+	for _, spec := range executor.Commands() {
+		specDoc := bsoncore.NewDocumentBuilder().
+			AppendString(helpElement, spec.Help()).
+			AppendBoolean(adminOnlyElement, spec.IsAdminOnly()).
+			AppendBoolean(requiresAuthElement, spec.IsAuthRequired()).
+			AppendBoolean(secondaryOkElement, spec.IsSecondaryAllowed()).
+			Build()
+		cmdsBuilder.AppendDocument(spec.Name(), specDoc)
+	}
+	reply := message.NewOkResponse()
+	reply.SetDocumentElement(commandsElement, cmdsBuilder.Build())
+	replyDoc, err := reply.BSONBytes()
 	if err != nil {
 		return nil, err
 	}
-	return resDoc, nil
+	return replyDoc, nil
 }
 
 //////////////////////////////////////////////////
@@ -126,9 +295,9 @@ func (executor *BaseCommandExecutor) BuildInfo(conn *Conn, cmd *Command) (bson.D
 	return replyDoc, nil
 }
 
-// GetLastError returns statistics about the MongoDB build.
+// GetLastError returns the error status of the preceding write operation on the current connection.
 func (executor *BaseCommandExecutor) GetLastError(conn *Conn, cmd *Command) (bson.Document, error) {
-	reply, err := message.NewDefaultLastErrorResponse()
+	reply, err := message.NewLastErrorResponseWithError(conn.LastError())
 	if err != nil {
 		return nil, err
 	}
@@ -139,6 +308,16 @@ func (executor *BaseCommandExecutor) GetLastError(conn *Conn, cmd *Command) (bso
 	return replyDoc, nil
 }
 
+// KillOp kills the specified in-flight operation.
+func (executor *BaseCommandExecutor) KillOp(conn *Conn, cmd *Command) (bson.Document, error) {
+	return nil, NewCommandNotSupported(cmd)
+}
+
+// CurrentOp returns the in-flight operations.
+func (executor *BaseCommandExecutor) CurrentOp(conn *Conn, cmd *Command) (bson.Document, error) {
+	return nil, NewCommandNotSupported(cmd)
+}
+
 //////////////////////////////////////////////////
 // UserCommandExecutor
 //////////////////////////////////////////////////
@@ -188,3 +367,57 @@ func (executor *BaseCommandExecutor) SASLStart(conn *Conn, cmd *Command) (bson.D
 func (executor *BaseCommandExecutor) SASLContinue(*Conn, *Command) (bson.Document, error) {
 	return nil, nil
 }
+
+// Authenticate handles 'authenticate' command.
+func (executor *BaseCommandExecutor) Authenticate(conn *Conn, cmd *Command) (bson.Document, error) {
+	return nil, NewCommandNotSupported(cmd)
+}
+
+// Logout handles 'logout' command.
+func (executor *BaseCommandExecutor) Logout(conn *Conn, cmd *Command) (bson.Document, error) {
+	return nil, NewCommandNotSupported(cmd)
+}
+
+//////////////////////////////////////////////////
+// UserManagementCommandExecutor
+//////////////////////////////////////////////////
+
+// CreateUser handles 'createUser' command.
+func (executor *BaseCommandExecutor) CreateUser(conn *Conn, cmd *Command) (bson.Document, error) {
+	return nil, NewCommandNotSupported(cmd)
+}
+
+// UpdateUser handles 'updateUser' command.
+func (executor *BaseCommandExecutor) UpdateUser(conn *Conn, cmd *Command) (bson.Document, error) {
+	return nil, NewCommandNotSupported(cmd)
+}
+
+// DropUser handles 'dropUser' command.
+func (executor *BaseCommandExecutor) DropUser(conn *Conn, cmd *Command) (bson.Document, error) {
+	return nil, NewCommandNotSupported(cmd)
+}
+
+// UsersInfo handles 'usersInfo' command.
+func (executor *BaseCommandExecutor) UsersInfo(conn *Conn, cmd *Command) (bson.Document, error) {
+	return nil, NewCommandNotSupported(cmd)
+}
+
+// CreateRole handles 'createRole' command.
+func (executor *BaseCommandExecutor) CreateRole(conn *Conn, cmd *Command) (bson.Document, error) {
+	return nil, NewCommandNotSupported(cmd)
+}
+
+// GrantRolesToUser handles 'grantRolesToUser' command.
+func (executor *BaseCommandExecutor) GrantRolesToUser(conn *Conn, cmd *Command) (bson.Document, error) {
+	return nil, NewCommandNotSupported(cmd)
+}
+
+// RevokeRolesFromUser handles 'revokeRolesFromUser' command.
+func (executor *BaseCommandExecutor) RevokeRolesFromUser(conn *Conn, cmd *Command) (bson.Document, error) {
+	return nil, NewCommandNotSupported(cmd)
+}
+
+// RolesInfo handles 'rolesInfo' command.
+func (executor *BaseCommandExecutor) RolesInfo(conn *Conn, cmd *Command) (bson.Document, error) {
+	return nil, NewCommandNotSupported(cmd)
+}
@@ -0,0 +1,71 @@
+// Copyright (C) 2022 The go-mongo Authors All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package mongo
+
+import (
+	"slices"
+	"strings"
+	"sync"
+)
+
+// CommandRegistry represents a command map of the lower-cased command names.
+type CommandRegistry struct {
+	m     map[string]*CommandSpec
+	mutex *sync.RWMutex
+}
+
+// NewCommandRegistry returns a new empty command registry.
+func NewCommandRegistry() *CommandRegistry {
+	return &CommandRegistry{
+		m:     map[string]*CommandSpec{},
+		mutex: &sync.RWMutex{},
+	}
+}
+
+// RegisterCommand registers the specified command, and replaces the command of the same name.
+func (registry *CommandRegistry) RegisterCommand(spec *CommandSpec) {
+	registry.mutex.Lock()
+	defer registry.mutex.Unlock()
+	registry.m[strings.ToLower(spec.Name())] = spec
+}
+
+// UnregisterCommand removes the command of the specified name.
+func (registry *CommandRegistry) UnregisterCommand(name string) {
+	registry.mutex.Lock()
+	defer registry.mutex.Unlock()
+	delete(registry.m, strings.ToLower(name))
+}
+
+// LookupCommand returns the command of the specified name case-insensitively.
+func (registry *CommandRegistry) LookupCommand(name string) (*CommandSpec, bool) {
+	registry.mutex.RLock()
+	defer registry.mutex.RUnlock()
+	spec, ok := registry.m[strings.ToLower(name)]
+	return spec, ok
+}
+
+// Commands returns the registered commands in the order of the names.
+func (registry *CommandRegistry) Commands() []*CommandSpec {
+	registry.mutex.RLock()
+	defer registry.mutex.RUnlock()
+	specs := make([]*CommandSpec, 0, len(registry.m))
+	for _, spec := range registry.m {
+		specs = append(specs, spec)
+	}
+	slices.SortFunc(specs, func(a, b *CommandSpec) int {
+		return strings.Compare(a.Name(), b.Name())
+	})
+	return specs
+}
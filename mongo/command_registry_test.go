@@ -0,0 +1,104 @@
+// Copyright (C) 2022 The go-mongo Authors All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package mongo
+
+import (
+	"testing"
+
+	"github.com/cybergarage/go-mongo/mongo/bson"
+)
+
+func TestCommandSpec(t *testing.T) {
+	spec := NewCommandSpec("test", func(*Conn, *Command) (bson.Document, error) {
+		return nil, nil
+	})
+	if spec.Name() != "test" || spec.Help() != "" {
+		t.Errorf("%s (%s)", spec.Name(), spec.Help())
+	}
+	if spec.IsAdminOnly() || !spec.IsAuthRequired() || spec.IsWrite() || spec.IsSecondaryAllowed() {
+		t.Errorf("invalid default options : %v", spec)
+	}
+
+	spec = NewCommandSpec("test", nil,
+		WithCommandHelp("help"),
+		WithCommandAdminOnly(true),
+		WithCommandAuthRequired(false),
+		WithCommandWrite(true),
+		WithCommandSecondaryAllowed(true))
+	if spec.Help() != "help" {
+		t.Errorf("%s != %s", spec.Help(), "help")
+	}
+	if !spec.IsAdminOnly() || spec.IsAuthRequired() || !spec.IsWrite() || !spec.IsSecondaryAllowed() {
+		t.Errorf("invalid options : %v", spec)
+	}
+}
+
+func TestCommandRegistry(t *testing.T) {
+	registry := NewCommandRegistry()
+	if n := len(registry.Commands()); n != 0 {
+		t.Errorf("%d != %d", n, 0)
+	}
+
+	for _, name := range []string{"ping", "buildInfo", "isMaster"} {
+		registry.RegisterCommand(NewCommandSpec(name, nil))
+	}
+
+	// Commands are looked up case-insensitively
+
+	for _, name := range []string{"buildInfo", "buildinfo", "BUILDINFO"} {
+		spec, ok := registry.LookupCommand(name)
+		if !ok || spec.Name() != "buildInfo" {
+			t.Errorf("%s is not found", name)
+		}
+	}
+	if _, ok := registry.LookupCommand("unknown"); ok {
+		t.Errorf("unknown command is found")
+	}
+
+	// Commands are sorted by the names
+
+	expected := []string{"buildInfo", "isMaster", "ping"}
+	specs := registry.Commands()
+	if len(specs) != len(expected) {
+		t.Errorf("%d != %d", len(specs), len(expected))
+		return
+	}
+	for n, spec := range specs {
+		if spec.Name() != expected[n] {
+			t.Errorf("%s != %s", spec.Name(), expected[n])
+		}
+	}
+
+	// A command of the same name is replaced
+
+	registry.RegisterCommand(NewCommandSpec("PING", nil, WithCommandHelp("replaced")))
+	spec, ok := registry.LookupCommand("ping")
+	if !ok || spec.Help() != "replaced" {
+		t.Errorf("ping is not replaced")
+	}
+	if n := len(registry.Commands()); n != len(expected) {
+		t.Errorf("%d != %d", n, len(expected))
+	}
+
+	// Commands are unregistered case-insensitively
+
+	registry.UnregisterCommand("Ping")
+	if _, ok := registry.LookupCommand("ping"); ok {
+		t.Errorf("ping is not unregistered")
+	}
+	if n := len(registry.Commands()); n != len(expected)-1 {
+		t.Errorf("%d != %d", n, len(expected)-1)
+	}
+}
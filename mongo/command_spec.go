@@ -0,0 +1,152 @@
+// Copyright (C) 2022 The go-mongo Authors All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package mongo
+
+import (
+	"github.com/cybergarage/go-mongo/mongo/bson"
+)
+
+// CommandFunc represents a handler function of a database command.
+type CommandFunc func(*Conn, *Command) (bson.Document, error)
+
+// QueryFunc represents a handler function of a user database command which is parsed as a query, such as find and insert.
+type QueryFunc func(*Conn, *Query) (bson.Document, error)
+
+// CommandSpec represents a database command handler with the metadata.
+type CommandSpec struct {
+	name               string
+	fn                 CommandFunc
+	queryFn            QueryFunc
+	help               string
+	isAdminOnly        bool
+	isAuthRequired     bool
+	isWrite            bool
+	isSecondaryAllowed bool
+}
+
+// CommandOption represents an option of a command specification.
+type CommandOption func(*CommandSpec)
+
+// WithCommandHelp sets the help text of the command.
+func WithCommandHelp(help string) CommandOption {
+	return func(spec *CommandSpec) {
+		spec.help = help
+	}
+}
+
+// WithCommandAdminOnly sets the flag to allow the command only on the admin database.
+func WithCommandAdminOnly(v bool) CommandOption {
+	return func(spec *CommandSpec) {
+		spec.isAdminOnly = v
+	}
+}
+
+// WithCommandAuthRequired sets the flag to require the authentication to run the command.
+func WithCommandAuthRequired(v bool) CommandOption {
+	return func(spec *CommandSpec) {
+		spec.isAuthRequired = v
+	}
+}
+
+// WithCommandWrite sets the flag that the command writes data.
+func WithCommandWrite(v bool) CommandOption {
+	return func(spec *CommandSpec) {
+		spec.isWrite = v
+	}
+}
+
+// WithCommandSecondaryAllowed sets the flag to allow the command on secondaries.
+func WithCommandSecondaryAllowed(v bool) CommandOption {
+	return func(spec *CommandSpec) {
+		spec.isSecondaryAllowed = v
+	}
+}
+
+// NewCommandSpec returns a new command specification of the specified name and handler.
+// The command requires the authentication and is not allowed on secondaries by default.
+func NewCommandSpec(name string, fn CommandFunc, opts ...CommandOption) *CommandSpec {
+	spec := &CommandSpec{
+		name:               name,
+		fn:                 fn,
+		queryFn:            nil,
+		help:               "",
+		isAdminOnly:        false,
+		isAuthRequired:     true,
+		isWrite:            false,
+		isSecondaryAllowed: false,
+	}
+	for _, opt := range opts {
+		opt(spec)
+	}
+	return spec
+}
+
+// NewQueryCommandSpec returns a new command specification of the specified name and query handler.
+func NewQueryCommandSpec(name string, fn QueryFunc, opts ...CommandOption) *CommandSpec {
+	spec := NewCommandSpec(name, nil, opts...)
+	spec.queryFn = fn
+	return spec
+}
+
+// Name returns the command name.
+func (spec *CommandSpec) Name() string {
+	return spec.name
+}
+
+// Help returns the help text of the command.
+func (spec *CommandSpec) Help() string {
+	return spec.help
+}
+
+// IsAdminOnly returns true if the command is allowed only on the admin database.
+func (spec *CommandSpec) IsAdminOnly() bool {
+	return spec.isAdminOnly
+}
+
+// IsAuthRequired returns true if the command requires the authentication.
+func (spec *CommandSpec) IsAuthRequired() bool {
+	return spec.isAuthRequired
+}
+
+// IsWrite returns true if the command writes data.
+func (spec *CommandSpec) IsWrite() bool {
+	return spec.isWrite
+}
+
+// IsSecondaryAllowed returns true if the command is allowed on secondaries.
+func (spec *CommandSpec) IsSecondaryAllowed() bool {
+	return spec.isSecondaryAllowed
+}
+
+// IsQuery returns true if the command is handled as a query.
+func (spec *CommandSpec) IsQuery() bool {
+	return spec.queryFn != nil
+}
+
+// Execute runs the command handler, and returns a command not supported error for the query commands.
+func (spec *CommandSpec) Execute(conn *Conn, cmd *Command) (bson.Document, error) {
+	if spec.fn == nil {
+		return nil, NewErrorf(ErrorCodeCommandNotSupported, "%s is not supported as a command", spec.name)
+	}
+	return spec.fn(conn, cmd)
+}
+
+// ExecuteQuery runs the query handler, and returns a command not supported error for the other commands.
+func (spec *CommandSpec) ExecuteQuery(conn *Conn, q *Query) (bson.Document, error) {
+	if spec.queryFn == nil {
+		return nil, NewErrorf(ErrorCodeCommandNotSupported, "%s is not supported as a query", spec.name)
+	}
+	return spec.queryFn(conn, q)
+}
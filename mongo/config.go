@@ -16,7 +16,9 @@ package mongo
 
 import (
 	"crypto/tls"
+	"time"
 
+	authtls "github.com/cybergarage/go-mongo/mongo/auth/tls"
 	"github.com/cybergarage/go-mongo/mongo/message"
 )
 
@@ -36,6 +38,10 @@ type CertConfig interface {
 	SetServerCert(cert []byte)
 	// SetRootCerts sets a SSL root certificates.
 	SetRootCerts(certs ...[]byte)
+	// SetCRLFiles loads PEM or DER CRL files, and revoked client certificates are rejected during the handshake.
+	SetCRLFiles(files ...string) error
+	// CRLStore returns the CRL store, which reloads the CRL files when they change.
+	CRLStore() *authtls.CRLStore
 	// SetTLSConfig sets a TLS configuration.
 	SetTLSConfig(tlsConfig *tls.Config)
 	// TLSConfig returns a TLS configuration from the configuration.
@@ -50,6 +56,14 @@ type TLSConfig interface {
 	SetTLSEnabled(enabled bool)
 	// IsEnabled returns true if the TLS is enabled.
 	IsTLSEnabled() bool
+	// SetTLSHandshakeTimeout sets the timeout of TLS handshakes, and zero disables the timeout.
+	SetTLSHandshakeTimeout(d time.Duration)
+	// TLSHandshakeTimeout returns the timeout of TLS handshakes.
+	TLSHandshakeTimeout() time.Duration
+	// SetTLSReloadInterval sets the interval to watch the key, certificate and root certificate files, and zero disables watching.
+	SetTLSReloadInterval(d time.Duration)
+	// TLSReloadInterval returns the interval to watch the key, certificate and root certificate files.
+	TLSReloadInterval() time.Duration
 }
 
 // Config stores server configuration parammeters.
@@ -58,10 +72,25 @@ type Config interface {
 
 	message.Config
 
+	// SetCompressions sets the supported compressor names in the preferred order.
+	SetCompressions(compressions ...string)
+	// SetChecksumEnabled sets the flag to add CRC-32C checksums to OP_MSG replies.
+	SetChecksumEnabled(enabled bool)
+	// IsChecksumEnabled returns true when CRC-32C checksums are added to OP_MSG replies.
+	IsChecksumEnabled() bool
+
 	// SetAuthrizationEnabled sets the authorization flag.
 	SetAuthrizationEnabled(authorized bool)
 	// IsAuthrizationEnabled returns true when the authorization is enabled.
 	IsAuthrizationEnabled() bool
+	// SetSCRAMIterationCount sets the iteration count of new SCRAM credentials and unknown users, and returns an error if it is less than 4096.
+	SetSCRAMIterationCount(n int) error
+	// SCRAMIterationCount returns the iteration count of new SCRAM credentials.
+	SCRAMIterationCount() int
+	// SetPlainWithoutTLSAllowed sets the flag to allow PLAIN authentication on non-TLS connections.
+	SetPlainWithoutTLSAllowed(allowed bool)
+	// IsPlainWithoutTLSAllowed returns true when PLAIN authentication is allowed on non-TLS connections.
+	IsPlainWithoutTLSAllowed() bool
 
 	// SetAddress sets a listen address.
 	SetAddress(addr string)
@@ -72,4 +101,30 @@ type Config interface {
 	SetPort(port int)
 	// Port returns a listent port.
 	Port() int
+
+	// SetMaxIncomingConnections sets the max number of simultaneous connections, and zero means no limit.
+	SetMaxIncomingConnections(n int)
+	// MaxIncomingConnections returns the max number of simultaneous connections.
+	MaxIncomingConnections() int
+	// SetIdleTimeout sets the timeout to close connections which have no requests, and zero disables the timeout.
+	SetIdleTimeout(d time.Duration)
+	// IdleTimeout returns the timeout to close connections which have no requests.
+	IdleTimeout() time.Duration
+	// SetReadTimeout sets the timeout to read a whole request message after it begins to arrive, and zero disables the timeout.
+	SetReadTimeout(d time.Duration)
+	// ReadTimeout returns the timeout to read a whole request message.
+	ReadTimeout() time.Duration
+	// SetCursorTimeout sets the timeout to remove cursors which are not accessed, and zero disables the timeout.
+	SetCursorTimeout(d time.Duration)
+	// CursorTimeout returns the timeout to remove cursors which are not accessed.
+	CursorTimeout() time.Duration
+	// SetWriteTimeout sets the timeout to write a reply message, and zero disables the timeout.
+	SetWriteTimeout(d time.Duration)
+	// WriteTimeout returns the timeout to write a reply message.
+	WriteTimeout() time.Duration
+
+	// AddEndpoint adds a listening endpoint. If no endpoint is added, the server listens on the address and port.
+	AddEndpoint(ep *Endpoint)
+	// Endpoints returns the listening endpoints.
+	Endpoints() []*Endpoint
 }
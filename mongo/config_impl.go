@@ -15,13 +15,32 @@
 package mongo
 
 import (
+	gotls "crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"os"
+	"time"
+
 	"github.com/cybergarage/go-authenticator/auth/tls"
+	"github.com/cybergarage/go-mongo/mongo/auth"
+	"github.com/cybergarage/go-mongo/mongo/auth/sasl/scram"
+	authtls "github.com/cybergarage/go-mongo/mongo/auth/tls"
 	"github.com/cybergarage/go-mongo/mongo/message"
 )
 
 // Config stores server configuration parammeters.
 type config struct {
 	tls.CertConfig
+	crlStore            *authtls.CRLStore
+	clientAuthType      gotls.ClientAuthType
+	serverKey           []byte
+	serverCert          []byte
+	rootCerts           [][]byte
+	serverKeyFile       string
+	serverCertFile      string
+	rootCertFiles       []string
+	tlsReloadInterval   time.Duration
+	tlsHandshakeTimeout time.Duration
 
 	addr                         string
 	port                         int
@@ -35,8 +54,17 @@ type config struct {
 	maxWireVersion               int32
 	readOnly                     bool
 	compressions                 []string
+	checksumEnabled              bool
 	version                      string
 	securityAuthorizationEnabled bool
+	scramIterationCount          int
+	plainWithoutTLSAllowed       bool
+	endpoints                    []*Endpoint
+	maxIncomingConnections       int
+	idleTimeout                  time.Duration
+	readTimeout                  time.Duration
+	writeTimeout                 time.Duration
+	cursorTimeout                time.Duration
 }
 
 // NewDefaultConfig returns a default configuration instance.
@@ -48,6 +76,16 @@ func NewDefaultConfig() Config {
 func newDefaultConfig() *config {
 	config := &config{
 		CertConfig:                   tls.NewCertConfig(),
+		crlStore:                     authtls.NewCRLStore(),
+		clientAuthType:               gotls.RequireAndVerifyClientCert,
+		serverKey:                    []byte{},
+		serverCert:                   []byte{},
+		rootCerts:                    [][]byte{},
+		serverKeyFile:                "",
+		serverCertFile:               "",
+		rootCertFiles:                []string{},
+		tlsReloadInterval:            0,
+		tlsHandshakeTimeout:          DefaultTLSHandshakeTimeout,
 		addr:                         "",
 		port:                         DefaultPort,
 		tlsEnabled:                   false,
@@ -61,7 +99,16 @@ func newDefaultConfig() *config {
 		version:                      message.DefaultCompatibleVersion,
 		isMaster:                     true,
 		compressions:                 nil,
+		checksumEnabled:              false,
 		securityAuthorizationEnabled: false,
+		scramIterationCount:          auth.DefaultSCRAMIterationCount,
+		plainWithoutTLSAllowed:       false,
+		endpoints:                    []*Endpoint{},
+		maxIncomingConnections:       0,
+		idleTimeout:                  0,
+		readTimeout:                  0,
+		writeTimeout:                 0,
+		cursorTimeout:                DefaultCursorTimeout,
 	}
 	return config
 }
@@ -136,11 +183,26 @@ func (config *config) IsReadOnly() bool {
 	return config.readOnly
 }
 
+// SetCompressions sets the supported compressor names in the preferred order.
+func (config *config) SetCompressions(compressions ...string) {
+	config.compressions = compressions
+}
+
 // Compressions should return supported compress strings.
 func (config *config) Compressions() []string {
 	return config.compressions
 }
 
+// SetChecksumEnabled sets the flag to add CRC-32C checksums to OP_MSG replies.
+func (config *config) SetChecksumEnabled(enabled bool) {
+	config.checksumEnabled = enabled
+}
+
+// IsChecksumEnabled returns true when CRC-32C checksums are added to OP_MSG replies.
+func (config *config) IsChecksumEnabled() bool {
+	return config.checksumEnabled
+}
+
 // Version should return supported MongoDB version string.
 func (config *config) Version() string {
 	return config.version
@@ -155,3 +217,275 @@ func (config *config) SetAuthrizationEnabled(authorized bool) {
 func (config *config) IsAuthrizationEnabled() bool {
 	return config.securityAuthorizationEnabled
 }
+
+// SetSCRAMIterationCount sets the iteration count of new SCRAM credentials and unknown users, and returns an error if it is less than 4096.
+func (config *config) SetSCRAMIterationCount(n int) error {
+	if n < scram.MinIterationCount {
+		return fmt.Errorf("%w : %d < %d", scram.ErrInvalidIterationCount, n, scram.MinIterationCount)
+	}
+	config.scramIterationCount = n
+	return nil
+}
+
+// SCRAMIterationCount returns the iteration count of new SCRAM credentials.
+func (config *config) SCRAMIterationCount() int {
+	return config.scramIterationCount
+}
+
+// SetPlainWithoutTLSAllowed sets the flag to allow PLAIN authentication on non-TLS connections.
+func (config *config) SetPlainWithoutTLSAllowed(allowed bool) {
+	config.plainWithoutTLSAllowed = allowed
+}
+
+// IsPlainWithoutTLSAllowed returns true when PLAIN authentication is allowed on non-TLS connections.
+func (config *config) IsPlainWithoutTLSAllowed() bool {
+	return config.plainWithoutTLSAllowed
+}
+
+// SetClientAuthType sets a client authentication type.
+func (config *config) SetClientAuthType(authType gotls.ClientAuthType) {
+	config.CertConfig.SetClientAuthType(authType)
+	config.clientAuthType = authType
+}
+
+// SetServerKeyFile loads a SSL server key file and sets it, and the file is reloaded by ReloadTLSConfig.
+func (config *config) SetServerKeyFile(file string) error {
+	key, err := os.ReadFile(file)
+	if err != nil {
+		return err
+	}
+	config.CertConfig.SetServerKey(key)
+	config.serverKey = key
+	config.serverKeyFile = file
+	return nil
+}
+
+// SetServerCertFile loads a SSL server certificate file and sets it, and the file is reloaded by ReloadTLSConfig.
+func (config *config) SetServerCertFile(file string) error {
+	cert, err := os.ReadFile(file)
+	if err != nil {
+		return err
+	}
+	config.CertConfig.SetServerCert(cert)
+	config.serverCert = cert
+	config.serverCertFile = file
+	return nil
+}
+
+// SetRootCertFiles loads SSL root certificate files and sets them, and the files are reloaded by ReloadTLSConfig.
+func (config *config) SetRootCertFiles(files ...string) error {
+	certs := make([][]byte, len(files))
+	for n, file := range files {
+		var err error
+		certs[n], err = os.ReadFile(file)
+		if err != nil {
+			return err
+		}
+	}
+	config.CertConfig.SetRootCerts(certs...)
+	config.rootCerts = certs
+	config.rootCertFiles = files
+	return nil
+}
+
+// SetServerKey sets a SSL server key, and the key file is no longer reloaded.
+func (config *config) SetServerKey(key []byte) {
+	config.CertConfig.SetServerKey(key)
+	config.serverKey = key
+	config.serverKeyFile = ""
+}
+
+// SetServerCert sets a SSL server certificate, and the certificate file is no longer reloaded.
+func (config *config) SetServerCert(cert []byte) {
+	config.CertConfig.SetServerCert(cert)
+	config.serverCert = cert
+	config.serverCertFile = ""
+}
+
+// SetRootCerts sets a SSL root certificates, and the root certificate files are no longer reloaded.
+func (config *config) SetRootCerts(certs ...[]byte) {
+	config.CertConfig.SetRootCerts(certs...)
+	config.rootCerts = certs
+	config.rootCertFiles = []string{}
+}
+
+// SetTLSHandshakeTimeout sets the timeout of TLS handshakes.
+func (config *config) SetTLSHandshakeTimeout(d time.Duration) {
+	config.tlsHandshakeTimeout = d
+}
+
+// TLSHandshakeTimeout returns the timeout of TLS handshakes.
+func (config *config) TLSHandshakeTimeout() time.Duration {
+	return config.tlsHandshakeTimeout
+}
+
+// SetTLSReloadInterval sets the interval to watch the key, certificate and root certificate files.
+func (config *config) SetTLSReloadInterval(d time.Duration) {
+	config.tlsReloadInterval = d
+}
+
+// TLSReloadInterval returns the interval to watch the key, certificate and root certificate files.
+func (config *config) TLSReloadInterval() time.Duration {
+	return config.tlsReloadInterval
+}
+
+// certFiles returns the loaded key, certificate and root certificate files.
+func (config *config) certFiles() []string {
+	files := []string{}
+	for _, file := range append([]string{config.serverKeyFile, config.serverCertFile}, config.rootCertFiles...) {
+		if len(file) != 0 {
+			files = append(files, file)
+		}
+	}
+	return files
+}
+
+// reloadCertFiles reloads the key, certificate and root certificate files.
+// The new configuration is built and parsed apart from the current one, and all of the files are applied only if it is valid.
+func (config *config) reloadCertFiles() error {
+	if len(config.certFiles()) == 0 {
+		return nil
+	}
+
+	key := config.serverKey
+	if len(config.serverKeyFile) != 0 {
+		var err error
+		key, err = os.ReadFile(config.serverKeyFile)
+		if err != nil {
+			return err
+		}
+	}
+	cert := config.serverCert
+	if len(config.serverCertFile) != 0 {
+		var err error
+		cert, err = os.ReadFile(config.serverCertFile)
+		if err != nil {
+			return err
+		}
+	}
+	rootCerts := config.rootCerts
+	if 0 < len(config.rootCertFiles) {
+		rootCerts = make([][]byte, len(config.rootCertFiles))
+		for n, file := range config.rootCertFiles {
+			var err error
+			rootCerts[n], err = os.ReadFile(file)
+			if err != nil {
+				return err
+			}
+			if !x509.NewCertPool().AppendCertsFromPEM(rootCerts[n]) {
+				return fmt.Errorf("%w : no PEM certificates in %s", ErrInvalidCertificate, file)
+			}
+		}
+	}
+
+	certConfig := tls.NewCertConfig()
+	certConfig.SetClientAuthType(config.clientAuthType)
+	certConfig.SetServerKey(key)
+	certConfig.SetServerCert(cert)
+	certConfig.SetRootCerts(rootCerts...)
+	if _, err := certConfig.TLSConfig(); err != nil {
+		return err
+	}
+
+	config.CertConfig = certConfig
+	config.serverKey = key
+	config.serverCert = cert
+	config.rootCerts = rootCerts
+	return nil
+}
+
+// SetMaxIncomingConnections sets the max number of simultaneous connections, and zero means no limit.
+func (config *config) SetMaxIncomingConnections(n int) {
+	config.maxIncomingConnections = n
+}
+
+// MaxIncomingConnections returns the max number of simultaneous connections.
+func (config *config) MaxIncomingConnections() int {
+	return config.maxIncomingConnections
+}
+
+// SetIdleTimeout sets the timeout to close connections which have no requests, and zero disables the timeout.
+func (config *config) SetIdleTimeout(d time.Duration) {
+	config.idleTimeout = d
+}
+
+// IdleTimeout returns the timeout to close connections which have no requests.
+func (config *config) IdleTimeout() time.Duration {
+	return config.idleTimeout
+}
+
+// SetCursorTimeout sets the timeout to remove cursors which are not accessed, and zero disables the timeout.
+func (config *config) SetCursorTimeout(d time.Duration) {
+	config.cursorTimeout = d
+}
+
+// CursorTimeout returns the timeout to remove cursors which are not accessed.
+func (config *config) CursorTimeout() time.Duration {
+	return config.cursorTimeout
+}
+
+// SetReadTimeout sets the timeout to read a whole request message after it begins to arrive, and zero disables the timeout.
+func (config *config) SetReadTimeout(d time.Duration) {
+	config.readTimeout = d
+}
+
+// ReadTimeout returns the timeout to read a whole request message.
+func (config *config) ReadTimeout() time.Duration {
+	return config.readTimeout
+}
+
+// SetWriteTimeout sets the timeout to write a reply message, and zero disables the timeout.
+func (config *config) SetWriteTimeout(d time.Duration) {
+	config.writeTimeout = d
+}
+
+// WriteTimeout returns the timeout to write a reply message.
+func (config *config) WriteTimeout() time.Duration {
+	return config.writeTimeout
+}
+
+// AddEndpoint adds a listening endpoint.
+func (config *config) AddEndpoint(ep *Endpoint) {
+	config.endpoints = append(config.endpoints, ep)
+}
+
+// Endpoints returns the listening endpoints. If no endpoint is added, it returns the TCP endpoint of the address and port.
+func (config *config) Endpoints() []*Endpoint {
+	if 0 < len(config.endpoints) {
+		return config.endpoints
+	}
+	return []*Endpoint{NewTCPEndpoint(config.addr, config.port).SetTLSEnabled(config.tlsEnabled)}
+}
+
+// SetCRLFiles loads PEM or DER CRL files, and revoked client certificates are rejected during the handshake.
+func (config *config) SetCRLFiles(files ...string) error {
+	return config.crlStore.LoadFiles(files...)
+}
+
+// CRLStore returns the CRL store.
+func (config *config) CRLStore() *authtls.CRLStore {
+	return config.crlStore
+}
+
+// TLSConfig returns a TLS configuration which checks the CRLs if the CRL files are set.
+// The CRLs are checked after the VerifyPeerCertificate of the configuration if it is set.
+func (config *config) TLSConfig() (*gotls.Config, error) {
+	tlsConfig, err := config.CertConfig.TLSConfig()
+	if err != nil || tlsConfig == nil {
+		return tlsConfig, err
+	}
+	if len(config.crlStore.Files()) == 0 {
+		return tlsConfig, nil
+	}
+	tlsConfig = tlsConfig.Clone()
+	verifyPeerCertificate := tlsConfig.VerifyPeerCertificate
+	tlsConfig.VerifyPeerCertificate = func(rawCerts [][]byte, verifiedChains [][]*x509.Certificate) error {
+		if verifyPeerCertificate != nil {
+			if err := verifyPeerCertificate(rawCerts, verifiedChains); err != nil {
+				return err
+			}
+		}
+		return config.crlStore.VerifyPeerCertificate(rawCerts, verifiedChains)
+	}
+	return tlsConfig, nil
+}
@@ -15,12 +15,14 @@
 package mongo
 
 import (
+	"context"
 	"crypto/tls"
 	"net"
 	"sync"
 	"time"
 
-	"github.com/cybergarage/go-sasl/sasl"
+	"github.com/cybergarage/go-mongo/mongo/auth"
+	"github.com/cybergarage/go-mongo/mongo/protocol"
 	"github.com/cybergarage/go-tracing/tracer"
 	"github.com/google/uuid"
 )
@@ -28,35 +30,65 @@ import (
 // Conn represents a connection of Wire protocol.
 type Conn struct {
 	net.Conn
-	isClosed bool
+	mutex     sync.Mutex
+	isClosed  bool
+	isActive  bool
+	nRequests uint64
+	lastTS    time.Time
+	isReading bool
+	rTimeout  time.Duration
+	wTimeout  time.Duration
+	ctx       context.Context
+	cancel    context.CancelCauseFunc
+	op        *Operation
 	sync.Map
 	ts time.Time
 	tracer.Context
-	authrized   bool
-	tlsState    *tls.ConnectionState
-	saslContext sasl.Context
-	uuid        uuid.UUID
+	authrized      bool
+	userName       *auth.UserName
+	authExpiration time.Time
+	tlsState       *tls.ConnectionState
+	uuid           uuid.UUID
+	compressor     protocol.Compressor
+	lastError      error
 }
 
 func newConnWith(conn net.Conn, tlsState *tls.ConnectionState) *Conn {
+	ctx, cancel := context.WithCancelCause(context.Background())
 	return &Conn{
-		Conn:        conn,
-		isClosed:    false,
-		Map:         sync.Map{},
-		ts:          time.Now(),
-		Context:     nil,
-		authrized:   false,
-		tlsState:    tlsState,
-		saslContext: nil,
-		uuid:        uuid.New(),
+		Conn:           conn,
+		mutex:          sync.Mutex{},
+		isClosed:       false,
+		isActive:       false,
+		nRequests:      0,
+		lastTS:         time.Now(),
+		isReading:      false,
+		rTimeout:       0,
+		wTimeout:       0,
+		ctx:            ctx,
+		cancel:         cancel,
+		op:             nil,
+		Map:            sync.Map{},
+		ts:             time.Now(),
+		Context:        nil,
+		authrized:      false,
+		userName:       nil,
+		authExpiration: time.Time{},
+		tlsState:       tlsState,
+		uuid:           uuid.New(),
+		compressor:     protocol.CompressorNoop,
+		lastError:      nil,
 	}
 }
 
 // Close closes the connection.
 func (conn *Conn) Close() error {
+	conn.mutex.Lock()
+	defer conn.mutex.Unlock()
 	if conn.isClosed {
 		return nil
 	}
+	conn.closeContext(NewClientDisconnectError())
 	if err := conn.Conn.Close(); err != nil {
 		return err
 	}
@@ -64,6 +96,116 @@ func (conn *Conn) Close() error {
 	return nil
 }
 
+// context returns the context of the connection which is cancelled when the connection is closed.
+func (conn *Conn) context() context.Context {
+	return conn.ctx
+}
+
+// closeContext cancels the context of the connection and the in-flight operation with the specified cause.
+func (conn *Conn) closeContext(cause error) {
+	conn.cancel(cause)
+}
+
+// setOperation sets the in-flight operation of the connection.
+func (conn *Conn) setOperation(op *Operation) {
+	conn.mutex.Lock()
+	defer conn.mutex.Unlock()
+	conn.op = op
+}
+
+// Operation returns the in-flight operation of the connection.
+func (conn *Conn) Operation() (*Operation, bool) {
+	conn.mutex.Lock()
+	defer conn.mutex.Unlock()
+	return conn.op, conn.op != nil
+}
+
+// RequestContext returns the context of the in-flight request, which is cancelled when the connection is closed,
+// the 'maxTimeMS' of the request expires or the request is killed by 'killOp'.
+// A half-closed connection is regarded as closed since the server detects disconnects by reading ahead.
+// It returns the context of the connection if no request is in flight.
+func (conn *Conn) RequestContext() context.Context {
+	if op, ok := conn.Operation(); ok {
+		return op.Context()
+	}
+	return conn.ctx
+}
+
+// requestError returns the cause of the cancelled request if the specified error is the context error of the request.
+func (conn *Conn) requestError(err error) error {
+	if op, ok := conn.Operation(); ok {
+		return op.causeOf(err)
+	}
+	return err
+}
+
+// beginRequest marks the connection as handling a request.
+func (conn *Conn) beginRequest() {
+	conn.mutex.Lock()
+	defer conn.mutex.Unlock()
+	conn.isActive = true
+}
+
+// endRequest marks the connection as idle after the reply of the request has been sent.
+func (conn *Conn) endRequest() {
+	conn.mutex.Lock()
+	defer conn.mutex.Unlock()
+	conn.isActive = false
+	conn.nRequests++
+	conn.lastTS = time.Now()
+}
+
+// requestState returns whether the connection is handling a request, and the number of finished requests.
+func (conn *Conn) requestState() (bool, uint64) {
+	conn.mutex.Lock()
+	defer conn.mutex.Unlock()
+	return conn.isActive, conn.nRequests
+}
+
+// isIdle returns true if the connection has not handled any requests for the specified duration.
+func (conn *Conn) isIdle(d time.Duration) bool {
+	conn.mutex.Lock()
+	defer conn.mutex.Unlock()
+	return !conn.isActive && d <= time.Since(conn.lastTS)
+}
+
+// setTimeouts sets the timeouts to read a whole request message and to write a reply message.
+func (conn *Conn) setTimeouts(read time.Duration, write time.Duration) {
+	conn.rTimeout = read
+	conn.wTimeout = write
+}
+
+// Read reads data from the connection, and sets the read deadline when a new request message begins to arrive.
+func (conn *Conn) Read(b []byte) (int, error) {
+	n, err := conn.Conn.Read(b)
+	if 0 < n && !conn.isReading && 0 < conn.rTimeout {
+		conn.isReading = true
+		if err := conn.Conn.SetReadDeadline(time.Now().Add(conn.rTimeout)); err != nil {
+			return n, err
+		}
+	}
+	return n, err
+}
+
+// finishReading clears the read deadline after a whole request message has been read.
+func (conn *Conn) finishReading() error {
+	if !conn.isReading {
+		return nil
+	}
+	conn.isReading = false
+	return conn.Conn.SetReadDeadline(time.Time{})
+}
+
+// Write writes data to the connection within the write timeout.
+func (conn *Conn) Write(b []byte) (int, error) {
+	if 0 < conn.wTimeout {
+		if err := conn.Conn.SetWriteDeadline(time.Now().Add(conn.wTimeout)); err != nil {
+			return 0, err
+		}
+	}
+	return conn.Conn.Write(b)
+}
+
 // SetSpanContext sets the span context to the connection.
 func (conn *Conn) SetSpanContext(span tracer.Context) {
 	conn.Context = span
@@ -89,6 +231,52 @@ func (conn *Conn) IsAuthrized() bool {
 	return conn.authrized
 }
 
+// SetUserName sets the authenticated user name to the connection.
+func (conn *Conn) SetUserName(name auth.UserName) {
+	conn.userName = &name
+}
+
+// UserName returns the authenticated user name of the connection.
+func (conn *Conn) UserName() (auth.UserName, bool) {
+	if conn.userName == nil {
+		return auth.UserName{}, false
+	}
+	return *conn.userName, true
+}
+
+// resetAuthentication discards the authenticated state of the connection.
+func (conn *Conn) resetAuthentication() {
+	conn.authrized = false
+	conn.userName = nil
+	conn.authExpiration = time.Time{}
+}
+
+// SetAuthenticationExpiration sets the expiration time of the authentication, and the zero time means no expiration.
+func (conn *Conn) SetAuthenticationExpiration(t time.Time) {
+	conn.authExpiration = t
+}
+
+// AuthenticationExpiration returns the expiration time of the authentication if the authentication expires.
+func (conn *Conn) AuthenticationExpiration() (time.Time, bool) {
+	if conn.authExpiration.IsZero() {
+		return time.Time{}, false
+	}
+	return conn.authExpiration, true
+}
+
+// IsAuthenticationExpired returns true if the authentication has expired and the client should reauthenticate.
+func (conn *Conn) IsAuthenticationExpired() bool {
+	if conn.authExpiration.IsZero() {
+		return false
+	}
+	return !time.Now().Before(conn.authExpiration)
+}
+
+// setTLSConnectionState sets the TLS connection state after the handshake.
+func (conn *Conn) setTLSConnectionState(state *tls.ConnectionState) {
+	conn.tlsState = state
+}
+
 // IsTLSConnection return true if the connection is enabled TLS.
 func (conn *Conn) IsTLSConnection() bool {
 	return conn.tlsState != nil
@@ -104,12 +292,22 @@ func (conn *Conn) UUID() uuid.UUID {
 	return conn.uuid
 }
 
-// SetSaslContext sets the SASL context to the connection.
-func (conn *Conn) SetSASLContext(saslContext sasl.Context) {
-	conn.saslContext = saslContext
+// SetCompressor sets the negotiated compressor to the connection.
+func (conn *Conn) SetCompressor(c protocol.Compressor) {
+	conn.compressor = c
+}
+
+// Compressor returns the negotiated compressor of the connection.
+func (conn *Conn) Compressor() protocol.Compressor {
+	return conn.compressor
+}
+
+// SetLastError sets the error of the last operation to the connection.
+func (conn *Conn) SetLastError(err error) {
+	conn.lastError = err
 }
 
-// SaslContext returns the SASL context of the connection.
-func (conn *Conn) SASLContext() sasl.Context {
-	return conn.saslContext
+// LastError returns the error of the last operation on the connection.
+func (conn *Conn) LastError() error {
+	return conn.lastError
 }
@@ -17,21 +17,29 @@ package mongo
 import (
 	"errors"
 	"sync"
+	"time"
 
+	"github.com/cybergarage/go-logger/log"
 	"github.com/google/uuid"
 )
 
 // ConnManager represents a connection map.
 type ConnManager struct {
-	m     map[uuid.UUID]*Conn
-	mutex *sync.RWMutex
+	m           map[uuid.UUID]*Conn
+	mutex       *sync.RWMutex
+	peak        int
+	idleTimeout time.Duration
+	reaperDone  chan struct{}
 }
 
 // NewConnManager returns a connection map.
 func NewConnManager() *ConnManager {
 	return &ConnManager{
-		m:     map[uuid.UUID]*Conn{},
-		mutex: &sync.RWMutex{},
+		m:           map[uuid.UUID]*Conn{},
+		mutex:       &sync.RWMutex{},
+		peak:        0,
+		idleTimeout: 0,
+		reaperDone:  nil,
 	}
 }
 
@@ -39,8 +47,47 @@ func NewConnManager() *ConnManager {
 func (mgr *ConnManager) AddConn(c *Conn) {
 	mgr.mutex.Lock()
 	defer mgr.mutex.Unlock()
+	mgr.addConn(c)
+}
+
+// addConnWithLimit adds the specified connection if the number of connections is less than the limit, and zero means no limit.
+func (mgr *ConnManager) addConnWithLimit(c *Conn, limit int) bool {
+	mgr.mutex.Lock()
+	defer mgr.mutex.Unlock()
+	if 0 < limit && limit <= len(mgr.m) {
+		return false
+	}
+	mgr.addConn(c)
+	return true
+}
+
+// addConn adds the specified connection, and updates the peak number of connections.
+func (mgr *ConnManager) addConn(c *Conn) {
 	uuid := c.UUID()
 	mgr.m[uuid] = c
+	mgr.peak = max(mgr.peak, len(mgr.m))
+}
+
+// NumConns returns the current number of connections.
+func (mgr *ConnManager) NumConns() int {
+	mgr.mutex.RLock()
+	defer mgr.mutex.RUnlock()
+	return len(mgr.m)
+}
+
+// PeakNumConns returns the peak number of connections.
+func (mgr *ConnManager) PeakNumConns() int {
+	mgr.mutex.RLock()
+	defer mgr.mutex.RUnlock()
+	return mgr.peak
+}
+
+// setIdleTimeout sets the timeout to close connections which have no requests, and zero disables the timeout.
+// The timeout is applied when the connection manager starts.
+func (mgr *ConnManager) setIdleTimeout(d time.Duration) {
+	mgr.mutex.Lock()
+	defer mgr.mutex.Unlock()
+	mgr.idleTimeout = d
 }
 
 // Conns returns the included connections.
@@ -70,11 +117,38 @@ func (mgr *ConnManager) RemoveConn(conn *Conn) error {
 	return nil
 }
 
-// Start starts the connection manager.
+// Start starts the connection manager, and starts to close idle connections if the idle timeout is set.
 func (mgr *ConnManager) Start() error {
+	mgr.mutex.Lock()
+	defer mgr.mutex.Unlock()
+	if mgr.reaperDone != nil || mgr.idleTimeout <= 0 {
+		return nil
+	}
+	mgr.reaperDone = make(chan struct{})
+	go mgr.reapIdleConns(mgr.idleTimeout, mgr.reaperDone)
 	return nil
 }
 
+// reapIdleConns closes the connections which have no requests for the idle timeout until done is closed.
+func (mgr *ConnManager) reapIdleConns(timeout time.Duration, done chan struct{}) {
+	ticker := time.NewTicker(max(timeout/2, time.Millisecond))
+	defer ticker.Stop()
+	for {
+		select {
+		case <-done:
+			return
+		case <-ticker.C:
+			for _, conn := range mgr.Conns() {
+				if !conn.isIdle(timeout) {
+					continue
+				}
+				log.Debugf("%s/%s (%s) idle timeout", PackageName, Version, conn.RemoteAddr().String())
+				conn.Close()
+			}
+		}
+	}
+}
+
 // Close closes the connection manager.
 func (mgr *ConnManager) Close() error {
 	var errs error
@@ -92,8 +166,14 @@ func (mgr *ConnManager) Close() error {
 	return errs
 }
 
-// Stop closes all connections.
+// Stop stops closing idle connections, and closes all connections.
 func (mgr *ConnManager) Stop() error {
+	mgr.mutex.Lock()
+	if mgr.reaperDone != nil {
+		close(mgr.reaperDone)
+		mgr.reaperDone = nil
+	}
+	mgr.mutex.Unlock()
 	if err := mgr.Close(); err != nil {
 		return err
 	}
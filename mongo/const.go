@@ -14,6 +14,10 @@
 
 package mongo
 
+import (
+	"time"
+)
+
 const (
 	// PackageName is the package name.
 	PackageName = "go-mongo"
@@ -23,4 +27,8 @@ const (
 	DefaultPort int = 27017
 	// DefaultTimeoutSecond is the default request timeout for MongoDB servers.
 	DefaultTimeoutSecond = 5
+	// DefaultTLSHandshakeTimeout is the default timeout of TLS handshakes.
+	DefaultTLSHandshakeTimeout = 10 * time.Second
+	// DefaultCursorTimeout is the default timeout of idle cursors as 'cursorTimeoutMillis' of MongoDB.
+	DefaultCursorTimeout = 10 * time.Minute
 )
@@ -0,0 +1,91 @@
+// Copyright (C) 2019 The go-mongo Authors. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package mongo
+
+import (
+	"sync"
+	"time"
+
+	"github.com/cybergarage/go-mongo/mongo/bson"
+	"github.com/google/uuid"
+)
+
+// Cursor represents a server-side cursor which holds the remaining documents of a query.
+type Cursor struct {
+	id        int64
+	connUUID  uuid.UUID
+	namespace string
+	docs      []bson.Document
+	ts        time.Time
+	mutex     *sync.Mutex
+}
+
+func newCursorWith(id int64, connUUID uuid.UUID, namespace string, docs []bson.Document) *Cursor {
+	return &Cursor{
+		id:        id,
+		connUUID:  connUUID,
+		namespace: namespace,
+		docs:      docs,
+		ts:        time.Now(),
+		mutex:     &sync.Mutex{},
+	}
+}
+
+// ID returns the cursor identifier.
+func (cursor *Cursor) ID() int64 {
+	return cursor.id
+}
+
+// ConnUUID returns the UUID of the connection which owns the cursor.
+func (cursor *Cursor) ConnUUID() uuid.UUID {
+	return cursor.connUUID
+}
+
+// Namespace returns the full collection name of the cursor.
+func (cursor *Cursor) Namespace() string {
+	return cursor.namespace
+}
+
+// Timestamp returns the last access time of the cursor.
+func (cursor *Cursor) Timestamp() time.Time {
+	cursor.mutex.Lock()
+	defer cursor.mutex.Unlock()
+	return cursor.ts
+}
+
+// Next returns the next batch of the specified size, and returns all remaining documents if the size is not positive.
+func (cursor *Cursor) Next(batchSize int) []bson.Document {
+	cursor.mutex.Lock()
+	defer cursor.mutex.Unlock()
+	cursor.ts = time.Now()
+	if batchSize <= 0 || len(cursor.docs) < batchSize {
+		batchSize = len(cursor.docs)
+	}
+	batch := cursor.docs[:batchSize]
+	cursor.docs = cursor.docs[batchSize:]
+	return batch
+}
+
+// isIdle returns true if the cursor has not been accessed for the specified duration.
+func (cursor *Cursor) isIdle(d time.Duration) bool {
+	return d < time.Since(cursor.Timestamp())
+}
+
+// IsExhausted returns true if the cursor has no more documents.
+func (cursor *Cursor) IsExhausted() bool {
+	cursor.mutex.Lock()
+	defer cursor.mutex.Unlock()
+	return len(cursor.docs) == 0
+}
@@ -0,0 +1,209 @@
+// Copyright (C) 2019 The go-mongo Authors. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package mongo
+
+import (
+	"crypto/rand"
+	"encoding/binary"
+	"math"
+	"sync"
+	"time"
+
+	"github.com/cybergarage/go-logger/log"
+	"github.com/cybergarage/go-mongo/mongo/bson"
+)
+
+// CursorManager represents a cursor map.
+type CursorManager struct {
+	m           map[int64]*Cursor
+	mutex       *sync.RWMutex
+	idleTimeout time.Duration
+	reaperDone  chan struct{}
+}
+
+// NewCursorManager returns a cursor map.
+func NewCursorManager() *CursorManager {
+	return &CursorManager{
+		m:           map[int64]*Cursor{},
+		mutex:       &sync.RWMutex{},
+		idleTimeout: 0,
+		reaperDone:  nil,
+	}
+}
+
+// OpenCursor opens a new cursor of the connection with the specified remaining documents.
+func (mgr *CursorManager) OpenCursor(conn *Conn, namespace string, docs []bson.Document) (*Cursor, error) {
+	mgr.mutex.Lock()
+	defer mgr.mutex.Unlock()
+	for {
+		id, err := newCursorID()
+		if err != nil {
+			return nil, err
+		}
+		if _, ok := mgr.m[id]; ok {
+			continue
+		}
+		cursor := newCursorWith(id, conn.UUID(), namespace, docs)
+		mgr.m[id] = cursor
+		return cursor, nil
+	}
+}
+
+// Cursors returns the included cursors.
+func (mgr *CursorManager) Cursors() []*Cursor {
+	mgr.mutex.RLock()
+	defer mgr.mutex.RUnlock()
+	cursors := make([]*Cursor, 0, len(mgr.m))
+	for _, cursor := range mgr.m {
+		cursors = append(cursors, cursor)
+	}
+	return cursors
+}
+
+// CursorByID returns the cursor with the specified identifier.
+func (mgr *CursorManager) CursorByID(id int64) (*Cursor, bool) {
+	mgr.mutex.RLock()
+	defer mgr.mutex.RUnlock()
+	c, ok := mgr.m[id]
+	return c, ok
+}
+
+// KillCursor removes the cursor with the specified identifier, and returns false if the cursor is not found.
+func (mgr *CursorManager) KillCursor(id int64) bool {
+	mgr.mutex.Lock()
+	defer mgr.mutex.Unlock()
+	_, ok := mgr.m[id]
+	delete(mgr.m, id)
+	return ok
+}
+
+// ConnCursorByID returns the cursor of the connection with the specified identifier.
+func (mgr *CursorManager) ConnCursorByID(conn *Conn, id int64) (*Cursor, bool) {
+	c, ok := mgr.CursorByID(id)
+	if !ok || c.ConnUUID() != conn.UUID() {
+		return nil, false
+	}
+	return c, true
+}
+
+// KillConnCursor removes the cursor of the connection with the specified identifier and namespace, and returns false if the cursor is not found.
+func (mgr *CursorManager) KillConnCursor(conn *Conn, namespace string, id int64) bool {
+	mgr.mutex.Lock()
+	defer mgr.mutex.Unlock()
+	c, ok := mgr.m[id]
+	if !ok || c.ConnUUID() != conn.UUID() || c.Namespace() != namespace {
+		return false
+	}
+	delete(mgr.m, id)
+	return true
+}
+
+// KillConnCursors removes all cursors of the specified connection, and returns the number of the removed cursors.
+func (mgr *CursorManager) KillConnCursors(conn *Conn) int {
+	return mgr.killCursorsFunc(func(c *Cursor) bool {
+		return c.ConnUUID() == conn.UUID()
+	})
+}
+
+// KillIdleCursors removes the cursors which have not been accessed for the specified duration, and returns the number of the removed cursors.
+func (mgr *CursorManager) KillIdleCursors(d time.Duration) int {
+	return mgr.killCursorsFunc(func(c *Cursor) bool {
+		return c.isIdle(d)
+	})
+}
+
+// killCursorsFunc removes the cursors which the specified function returns true.
+func (mgr *CursorManager) killCursorsFunc(fn func(*Cursor) bool) int {
+	mgr.mutex.Lock()
+	defer mgr.mutex.Unlock()
+	n := 0
+	for id, c := range mgr.m {
+		if !fn(c) {
+			continue
+		}
+		delete(mgr.m, id)
+		n++
+	}
+	return n
+}
+
+// KillAllCursors removes all cursors.
+func (mgr *CursorManager) KillAllCursors() {
+	mgr.mutex.Lock()
+	defer mgr.mutex.Unlock()
+	mgr.m = map[int64]*Cursor{}
+}
+
+// setCursorTimeout sets the timeout to remove cursors which are not accessed, and zero disables the timeout.
+// The timeout is applied when the cursor manager starts.
+func (mgr *CursorManager) setCursorTimeout(d time.Duration) {
+	mgr.mutex.Lock()
+	defer mgr.mutex.Unlock()
+	mgr.idleTimeout = d
+}
+
+// Start starts the cursor manager, and starts to remove idle cursors if the cursor timeout is set.
+func (mgr *CursorManager) Start() error {
+	mgr.mutex.Lock()
+	defer mgr.mutex.Unlock()
+	if mgr.reaperDone != nil || mgr.idleTimeout <= 0 {
+		return nil
+	}
+	mgr.reaperDone = make(chan struct{})
+	go mgr.reapIdleCursors(mgr.idleTimeout, mgr.reaperDone)
+	return nil
+}
+
+// reapIdleCursors removes the cursors which are not accessed for the timeout until done is closed.
+func (mgr *CursorManager) reapIdleCursors(timeout time.Duration, done chan struct{}) {
+	ticker := time.NewTicker(max(timeout/2, time.Millisecond))
+	defer ticker.Stop()
+	for {
+		select {
+		case <-done:
+			return
+		case <-ticker.C:
+			if n := mgr.KillIdleCursors(timeout); 0 < n {
+				log.Debugf("%s/%s %d idle cursors timed out", PackageName, Version, n)
+			}
+		}
+	}
+}
+
+// Stop stops removing idle cursors, and removes all cursors.
+func (mgr *CursorManager) Stop() error {
+	mgr.mutex.Lock()
+	if mgr.reaperDone != nil {
+		close(mgr.reaperDone)
+		mgr.reaperDone = nil
+	}
+	mgr.mutex.Unlock()
+	mgr.KillAllCursors()
+	return nil
+}
+
+// newCursorID returns a new positive cursor identifier, zero is reserved for the exhausted cursor.
+func newCursorID() (int64, error) {
+	b := make([]byte, 8)
+	for {
+		if _, err := rand.Read(b); err != nil {
+			return 0, err
+		}
+		id := int64(binary.LittleEndian.Uint64(b) & math.MaxInt64)
+		if id != 0 {
+			return id, nil
+		}
+	}
+}
@@ -0,0 +1,172 @@
+// Copyright (C) 2019 The go-mongo Authors. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package mongo
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"strconv"
+)
+
+const (
+	// NetworkTCP is the network name of TCP endpoints, which listen on IPv4 and IPv6.
+	NetworkTCP = "tcp"
+	// NetworkUnix is the network name of Unix domain socket endpoints.
+	NetworkUnix = "unix"
+	// DefaultUnixSocketFileMode is the default file mode of Unix domain sockets as mongod does.
+	DefaultUnixSocketFileMode os.FileMode = 0o700
+)
+
+// Endpoint represents a listening endpoint of the server.
+type Endpoint struct {
+	network    string
+	address    string
+	tlsEnabled bool
+	fileMode   os.FileMode
+}
+
+// NewTCPEndpoint returns a new TCP endpoint of the specified host and port, and the host may be an IPv6 address.
+func NewTCPEndpoint(host string, port int) *Endpoint {
+	return &Endpoint{
+		network:    NetworkTCP,
+		address:    net.JoinHostPort(host, strconv.Itoa(port)),
+		tlsEnabled: false,
+		fileMode:   0,
+	}
+}
+
+// NewUnixEndpoint returns a new Unix domain socket endpoint of the specified path such as /tmp/mongodb-27017.sock.
+func NewUnixEndpoint(path string) *Endpoint {
+	return &Endpoint{
+		network:    NetworkUnix,
+		address:    path,
+		tlsEnabled: false,
+		fileMode:   DefaultUnixSocketFileMode,
+	}
+}
+
+// UnixSocketPath returns the Unix domain socket path of the specified port as mongod does.
+func UnixSocketPath(port int) string {
+	return fmt.Sprintf("/tmp/mongodb-%d.sock", port)
+}
+
+// Network returns the network name.
+func (ep *Endpoint) Network() string {
+	return ep.network
+}
+
+// Address returns the listen address, which is a host and port or a socket path.
+func (ep *Endpoint) Address() string {
+	return ep.address
+}
+
+// SetTLSEnabled sets the flag to accept TLS connections on the endpoint.
+func (ep *Endpoint) SetTLSEnabled(enabled bool) *Endpoint {
+	ep.tlsEnabled = enabled
+	return ep
+}
+
+// IsTLSEnabled returns true if the endpoint accepts TLS connections.
+func (ep *Endpoint) IsTLSEnabled() bool {
+	return ep.tlsEnabled
+}
+
+// SetFileMode sets the file mode of the Unix domain socket.
+func (ep *Endpoint) SetFileMode(mode os.FileMode) *Endpoint {
+	ep.fileMode = mode
+	return ep
+}
+
+// FileMode returns the file mode of the Unix domain socket.
+func (ep *Endpoint) FileMode() os.FileMode {
+	return ep.fileMode
+}
+
+// String returns the string representation of the endpoint.
+func (ep *Endpoint) String() string {
+	return ep.network + "://" + ep.address
+}
+
+// listen opens a listener of the endpoint.
+func (ep *Endpoint) listen() (net.Listener, error) {
+	if ep.network != NetworkUnix {
+		return net.Listen(ep.network, ep.address)
+	}
+	// Remove a stale socket file which is left by a crashed server.
+	if fi, err := os.Lstat(ep.address); err == nil && fi.Mode()&os.ModeSocket != 0 {
+		if conn, err := net.Dial(NetworkUnix, ep.address); err == nil {
+			conn.Close()
+			return nil, fmt.Errorf("%w : %s", ErrAddressInUse, ep.address)
+		}
+		if err := os.Remove(ep.address); err != nil {
+			return nil, err
+		}
+	}
+	return ep.listenUnix()
+}
+
+// listenUnix binds the Unix domain socket in a private directory, and links it to the address after changing the file mode,
+// so that the socket is never accessible with the permissions derived from the umask.
+func (ep *Endpoint) listenUnix() (net.Listener, error) {
+	dir, err := os.MkdirTemp(filepath.Dir(ep.address), ".mongodb-")
+	if err != nil {
+		return nil, err
+	}
+	defer os.RemoveAll(dir)
+	tmpPath := filepath.Join(dir, "sock")
+	l, err := net.ListenUnix(NetworkUnix, &net.UnixAddr{Name: tmpPath, Net: NetworkUnix})
+	if err != nil {
+		return nil, err
+	}
+	l.SetUnlinkOnClose(false)
+	if err := os.Chmod(tmpPath, ep.fileMode); err != nil {
+		l.Close()
+		return nil, err
+	}
+	if err := os.Link(tmpPath, ep.address); err != nil {
+		l.Close()
+		if errors.Is(err, os.ErrExist) {
+			return nil, fmt.Errorf("%w : %s", ErrAddressInUse, ep.address)
+		}
+		return nil, err
+	}
+	return &unixListener{
+		UnixListener: l,
+		addr:         &net.UnixAddr{Name: ep.address, Net: NetworkUnix},
+	}, nil
+}
+
+// unixListener represents a Unix domain socket listener which removes the socket file of the address when it is closed.
+type unixListener struct {
+	*net.UnixListener
+	addr *net.UnixAddr
+}
+
+// Addr returns the address of the socket file.
+func (l *unixListener) Addr() net.Addr {
+	return l.addr
+}
+
+// Close closes the listener, and removes the socket file.
+func (l *unixListener) Close() error {
+	err := l.UnixListener.Close()
+	if err == nil {
+		os.Remove(l.addr.Name)
+	}
+	return err
+}
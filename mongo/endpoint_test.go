@@ -0,0 +1,142 @@
+// Copyright (C) 2022 The go-mongo Authors All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package mongo
+
+import (
+	"errors"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestTCPEndpoint(t *testing.T) {
+	tests := []struct {
+		host     string
+		port     int
+		expected string
+	}{
+		{"localhost", 27017, "localhost:27017"},
+		{"", 27017, ":27017"},
+		{"::1", 27017, "[::1]:27017"},
+	}
+	for _, test := range tests {
+		ep := NewTCPEndpoint(test.host, test.port)
+		if ep.Network() != NetworkTCP || ep.Address() != test.expected {
+			t.Errorf("%s != %s://%s", ep, NetworkTCP, test.expected)
+		}
+	}
+
+	ep := NewTCPEndpoint("localhost", 0)
+	l, err := ep.listen()
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	defer l.Close()
+	conn, err := net.Dial(NetworkTCP, l.Addr().String())
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	conn.Close()
+}
+
+func TestUnixEndpoint(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "mongodb.sock")
+
+	ep := NewUnixEndpoint(path)
+	if ep.Network() != NetworkUnix || ep.Address() != path || ep.FileMode() != DefaultUnixSocketFileMode {
+		t.Errorf("%s (%s)", ep, ep.FileMode())
+	}
+
+	for _, mode := range []os.FileMode{DefaultUnixSocketFileMode, 0o777, 0o600} {
+		ep.SetFileMode(mode)
+		l, err := ep.listen()
+		if err != nil {
+			t.Error(err)
+			return
+		}
+
+		// The socket has the file mode, and no temporary file is left
+
+		fi, err := os.Lstat(path)
+		if err != nil {
+			t.Error(err)
+		} else if fi.Mode()&os.ModeSocket == 0 || fi.Mode().Perm() != mode {
+			t.Errorf("%s != %s", fi.Mode(), os.ModeSocket|mode)
+		}
+		entries, err := os.ReadDir(dir)
+		if err != nil || len(entries) != 1 {
+			t.Errorf("temporary files are left : %v", entries)
+		}
+		if l.Addr().String() != path {
+			t.Errorf("%s != %s", l.Addr(), path)
+		}
+
+		conn, err := net.Dial(NetworkUnix, path)
+		if err != nil {
+			t.Error(err)
+		} else {
+			conn.Close()
+		}
+
+		// The socket in use is not replaced
+
+		if _, err := ep.listen(); !errors.Is(err, ErrAddressInUse) {
+			t.Errorf("%v != %s", err, ErrAddressInUse)
+		}
+
+		// The socket is removed when the listener is closed
+
+		l.Close()
+		if _, err := os.Lstat(path); !errors.Is(err, os.ErrNotExist) {
+			t.Errorf("socket is not removed : %v", err)
+		}
+	}
+}
+
+func TestUnixEndpointStaleSocket(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "mongodb.sock")
+	ep := NewUnixEndpoint(path)
+
+	// A stale socket left by a crashed server is replaced
+
+	stale, err := net.ListenUnix(NetworkUnix, &net.UnixAddr{Name: path, Net: NetworkUnix})
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	stale.SetUnlinkOnClose(false)
+	stale.Close()
+
+	l, err := ep.listen()
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	l.Close()
+
+	// A file which is not a socket is not replaced
+
+	if err := os.WriteFile(path, []byte{}, 0o600); err != nil {
+		t.Error(err)
+		return
+	}
+	if _, err := ep.listen(); !errors.Is(err, ErrAddressInUse) {
+		t.Errorf("%v != %s", err, ErrAddressInUse)
+	}
+}
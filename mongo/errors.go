@@ -7,11 +7,61 @@ package mongo
 import (
 	"errors"
 	"fmt"
+
+	"github.com/cybergarage/go-mongo/mongo/message"
+)
+
+// Error represents a MongoDB server error which has a code, code name and error message.
+type Error = message.Error
+
+// ErrorCode represents a MongoDB server error code.
+type ErrorCode = message.ErrorCode
+
+// WriteError represents an error of a document in write commands.
+type WriteError = message.WriteError
+
+// WriteConcernError represents a write concern error in write commands.
+type WriteConcernError = message.WriteConcernError
+
+// WriteException represents errors of write commands which are partially succeeded.
+type WriteException = message.WriteException
+
+// Well-known MongoDB server error codes.
+const (
+	ErrorCodeInternalError            = message.ErrorCodeInternalError
+	ErrorCodeBadValue                 = message.ErrorCodeBadValue
+	ErrorCodeUserNotFound             = message.ErrorCodeUserNotFound
+	ErrorCodeUnauthorized             = message.ErrorCodeUnauthorized
+	ErrorCodeAuthenticationFailed     = message.ErrorCodeAuthenticationFailed
+	ErrorCodeIllegalOperation         = message.ErrorCodeIllegalOperation
+	ErrorCodeNamespaceNotFound        = message.ErrorCodeNamespaceNotFound
+	ErrorCodeRoleNotFound             = message.ErrorCodeRoleNotFound
+	ErrorCodeCursorNotFound           = message.ErrorCodeCursorNotFound
+	ErrorCodeMaxTimeMSExpired         = message.ErrorCodeMaxTimeMSExpired
+	ErrorCodeCommandNotFound          = message.ErrorCodeCommandNotFound
+	ErrorCodeShutdownInProgress       = message.ErrorCodeShutdownInProgress
+	ErrorCodeOperationFailed          = message.ErrorCodeOperationFailed
+	ErrorCodeCommandNotSupported      = message.ErrorCodeCommandNotSupported
+	ErrorCodeClientDisconnect         = message.ErrorCodeClientDisconnect
+	ErrorCodeMechanismUnavailable     = message.ErrorCodeMechanismUnavailable
+	ErrorCodeReauthenticationRequired = message.ErrorCodeReauthenticationRequired
+	ErrorCodeNotWritablePrimary       = message.ErrorCodeNotWritablePrimary
+	ErrorCodeDuplicateKey             = message.ErrorCodeDuplicateKey
+	ErrorCodeNotPrimaryNoSecondaryOk  = message.ErrorCodeNotPrimaryNoSecondaryOk
+	ErrorCodeInterrupted              = message.ErrorCodeInterrupted
+	ErrorCodeRoleAlreadyExists        = message.ErrorCodeRoleAlreadyExists
+	ErrorCodeUserAlreadyExists        = message.ErrorCodeUserAlreadyExists
 )
 
 var ErrQuery = errors.New("query error")
 var ErrQueryNotSupported = errors.New("query not supported")
 var ErrCommand = errors.New("invalid command")
+var ErrCursorNotFound = errors.New("cursor not found")
+var ErrCertificateNotVerified = errors.New("certificate not verified")
+var ErrAddressInUse = errors.New("address already in use")
+var ErrTLSNotConfigured = errors.New("TLS is not configured")
+var ErrInvalidCertificate = errors.New("invalid certificate")
+var ErrTooManyConnections = errors.New("too many open connections")
 
 const (
 	errorLostConnection                    = "lost connection to %s:%d"
@@ -23,14 +73,102 @@ const (
 	errorOpMsgDocumentSequenceNotSupported = "document Sequence does not supported"
 )
 
+// NewError returns a new server error with the specified code and message.
+func NewError(code ErrorCode, msg string) *Error {
+	return message.NewError(code, msg)
+}
+
+// NewErrorf returns a new server error with the specified code and formatted message.
+func NewErrorf(code ErrorCode, format string, args ...any) *Error {
+	return message.NewErrorf(code, format, args...)
+}
+
+// NewWriteError returns a new write error of the document at the specified index.
+func NewWriteError(index int32, err error) *WriteError {
+	return message.NewWriteError(index, err)
+}
+
+// NewWriteException returns a new write exception with the specified write errors.
+func NewWriteException(writeErrs ...*WriteError) *WriteException {
+	return message.NewWriteException(writeErrs...)
+}
+
+// NewDuplicateKeyError returns a new duplicate key error of the specified collection and key.
+func NewDuplicateKeyError(fullCollectionName string, key any) *Error {
+	return message.NewErrorf(ErrorCodeDuplicateKey, "E11000 duplicate key error collection: %s dup key: %v", fullCollectionName, key)
+}
+
+// NewUnauthorizedError returns a new unauthorized error of the specified command.
+func NewUnauthorizedError(cmdName string) *Error {
+	return message.NewErrorf(ErrorCodeUnauthorized, "command %s requires authentication", cmdName)
+}
+
+// NewReauthenticationRequiredError returns a new reauthentication required error of the specified command.
+func NewReauthenticationRequiredError(cmdName string) *Error {
+	return message.NewErrorf(ErrorCodeReauthenticationRequired, "command %s requires reauthentication since the current authentication session has expired", cmdName)
+}
+
+// NewShutdownInProgressError returns a new shutdown in progress error for requests received while the server is shutting down.
+func NewShutdownInProgressError() *Error {
+	return message.NewError(ErrorCodeShutdownInProgress, "the server is shutting down")
+}
+
+// NewMaxTimeMSExpiredError returns a new error of operations which have exceeded the time limit of 'maxTimeMS'.
+func NewMaxTimeMSExpiredError() *Error {
+	return message.NewError(ErrorCodeMaxTimeMSExpired, "operation exceeded time limit")
+}
+
+// NewInterruptedError returns a new error of operations which have been killed by 'killOp'.
+func NewInterruptedError() *Error {
+	return message.NewError(ErrorCodeInterrupted, "operation was interrupted")
+}
+
+// NewClientDisconnectError returns a new error of operations which have been cancelled since the connection is closed.
+func NewClientDisconnectError() *Error {
+	return message.NewError(ErrorCodeClientDisconnect, "operation was interrupted because the connection is closed")
+}
+
+// NewCommandNotFoundError returns a new command not found error of the specified command.
+func NewCommandNotFoundError(cmdName string) *Error {
+	return message.NewErrorf(ErrorCodeCommandNotFound, "no such command: '%s'", cmdName)
+}
+
+// NewNotWritablePrimaryError returns a new error of write commands which are run on a non-primary instance.
+func NewNotWritablePrimaryError() *Error {
+	return message.NewError(ErrorCodeNotWritablePrimary, "not primary")
+}
+
+// NewNotPrimaryNoSecondaryOkError returns a new error of commands which are not allowed on secondaries.
+func NewNotPrimaryNoSecondaryOkError() *Error {
+	return message.NewError(ErrorCodeNotPrimaryNoSecondaryOk, "not primary and secondaryOk=false")
+}
+
+// NewReadOnlyError returns a new illegal operation error of the write command on a read-only instance.
+func NewReadOnlyError(cmdName string) *Error {
+	return message.NewErrorf(ErrorCodeIllegalOperation, "not allowed to run the write command %s in read-only mode", cmdName)
+}
+
+// NewNamespaceNotFoundError returns a new namespace not found error of the specified namespace.
+func NewNamespaceNotFoundError(ns string) *Error {
+	return message.NewErrorf(ErrorCodeNamespaceNotFound, "ns not found: %s", ns)
+}
+
 func NewQueryError(q *Query) error {
-	return fmt.Errorf("%w (%v)", ErrQuery, q)
+	return message.NewErrorWith(ErrorCodeOperationFailed, fmt.Errorf("%w (%v)", ErrQuery, q))
 }
 
 func NewNotSupported(q *Query) error {
-	return fmt.Errorf("%w (%v)", ErrQueryNotSupported, q)
+	return message.NewErrorWith(ErrorCodeCommandNotSupported, fmt.Errorf("%w (%v)", ErrQueryNotSupported, q))
+}
+
+func NewCommandNotSupported(cmd *Command) error {
+	return message.NewErrorf(ErrorCodeCommandNotSupported, "%s is not supported", cmd.Type())
 }
 
 func NewErrorCommand(cmd *Command) error {
-	return fmt.Errorf("%w (%v)", ErrCommand, cmd)
+	return message.NewErrorWith(ErrorCodeBadValue, fmt.Errorf("%w (%v)", ErrCommand, cmd))
+}
+
+func NewCursorNotFound(id int64) error {
+	return message.NewErrorWith(ErrorCodeCursorNotFound, fmt.Errorf("%w (%d)", ErrCursorNotFound, id))
 }
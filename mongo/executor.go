@@ -59,6 +59,7 @@ type DatabaseCommandExecutor interface {
 	ReplicationCommandExecutor
 	DiagnosticCommandExecutor
 	WriteOperationExecutor
+	AdministrationCommandExecutor
 }
 
 // ReplicationCommandExecutor represents an executor interface for MongoDB replication commands.
@@ -76,6 +77,42 @@ type WriteOperationExecutor interface {
 	GetLastError(*Conn, *Command) (bson.Document, error)
 }
 
+// AdministrationCommandExecutor represents an executor interface for MongoDB administration commands.
+type AdministrationCommandExecutor interface {
+	// KillOp handles 'killOp' command.
+	KillOp(*Conn, *Command) (bson.Document, error)
+	// CurrentOp handles 'currentOp' command.
+	CurrentOp(*Conn, *Command) (bson.Document, error)
+}
+
+// UserManagementCommandExecutor represents an executor interface for MongoDB user and role management commands.
+type UserManagementCommandExecutor interface {
+	// CreateUser handles 'createUser' command.
+	CreateUser(*Conn, *Command) (bson.Document, error)
+	// UpdateUser handles 'updateUser' command.
+	UpdateUser(*Conn, *Command) (bson.Document, error)
+	// DropUser handles 'dropUser' command.
+	DropUser(*Conn, *Command) (bson.Document, error)
+	// UsersInfo handles 'usersInfo' command.
+	UsersInfo(*Conn, *Command) (bson.Document, error)
+	// CreateRole handles 'createRole' command.
+	CreateRole(*Conn, *Command) (bson.Document, error)
+	// GrantRolesToUser handles 'grantRolesToUser' command.
+	GrantRolesToUser(*Conn, *Command) (bson.Document, error)
+	// RevokeRolesFromUser handles 'revokeRolesFromUser' command.
+	RevokeRolesFromUser(*Conn, *Command) (bson.Document, error)
+	// RolesInfo handles 'rolesInfo' command.
+	RolesInfo(*Conn, *Command) (bson.Document, error)
+}
+
+// Authorizer represents an interface to authorize commands and queries of connections.
+type Authorizer interface {
+	// Authorize returns an error if the connection is not allowed to execute the specified command.
+	Authorize(*Conn, *Command) error
+	// AuthorizeQuery returns an error if the connection is not allowed to execute the specified query.
+	AuthorizeQuery(*Conn, *Query) error
+}
+
 // AuthCommandExecutor represents an executor interface for MongoDB authentication commands.
 type AuthCommandExecutor interface {
 	// SASLSupportedMechs returns the supported SASL mechanisms.
@@ -84,4 +121,8 @@ type AuthCommandExecutor interface {
 	SASLStart(*Conn, *Command) (bson.Document, error)
 	// SASLContinue handles SASLContinue command.
 	SASLContinue(*Conn, *Command) (bson.Document, error)
+	// Authenticate handles 'authenticate' command.
+	Authenticate(*Conn, *Command) (bson.Document, error)
+	// Logout handles 'logout' command.
+	Logout(*Conn, *Command) (bson.Document, error)
 }
@@ -0,0 +1,135 @@
+// Copyright (C) 2022 The go-mongo Authors All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package mongo
+
+import (
+	"sync"
+
+	"github.com/cybergarage/go-mongo/mongo/bson"
+	"github.com/cybergarage/go-mongo/mongo/message"
+	"github.com/cybergarage/go-mongo/mongo/protocol"
+)
+
+// Request represents a parsed request which is passed through the interceptors.
+type Request struct {
+	// Command is the parsed command of the request, and the query is derived from the command.
+	Command *Command
+	// Documents is the document sequence payload of OP_MSG, such as the documents of insert, update and delete commands.
+	Documents []bson.Document
+	opCode    protocol.OpCode
+}
+
+// newQueryRequest returns a new request of the specified command of OP_QUERY.
+func newQueryRequest(cmd *Command) *Request {
+	return &Request{
+		Command:   cmd,
+		Documents: []bson.Document{},
+		opCode:    protocol.OpQuery,
+	}
+}
+
+// newMsgRequest returns a new request of the specified command and document sequences of OP_MSG.
+func newMsgRequest(cmd *Command, docs []bson.Document) *Request {
+	return &Request{
+		Command:   cmd,
+		Documents: docs,
+		opCode:    protocol.OpMsg,
+	}
+}
+
+// Query returns the query of the user database commands which is parsed from the current command,
+// and returns nil for the other commands.
+func (req *Request) Query() (*Query, error) {
+	switch req.opCode {
+	case protocol.OpQuery:
+		switch req.Command.Type() {
+		// For user database commands over OP_QUERY under MongoDB v3.6
+		case message.Insert, message.Delete, message.Update, message.Find:
+			return message.NewQueryWithQueryCommand(req.Command)
+		}
+	case protocol.OpMsg:
+		q, err := message.NewQueryWithCommand(req.Command, req.Documents)
+		if err != nil {
+			return nil, err
+		}
+		switch q.Type() {
+		// For user database commands over OP_MSG from MongoDB v3.6
+		case message.Insert, message.Delete, message.Update, message.Find, message.GetMore, message.KillCursors:
+			return q, nil
+		}
+	}
+	return nil, nil
+}
+
+// RequestHandler represents a handler which executes the request and returns the response document.
+type RequestHandler func(*Conn, *Request) (bson.Document, error)
+
+// Interceptor represents an interface to wrap the request handling.
+type Interceptor interface {
+	// Intercept calls the next handler to continue the chain, and can post-process the returned response document.
+	// It can short-circuit the chain by returning a response document or an error without calling the next handler.
+	Intercept(conn *Conn, req *Request, next RequestHandler) (bson.Document, error)
+}
+
+// InterceptorFunc is an adapter to use an ordinary function as an interceptor.
+type InterceptorFunc func(*Conn, *Request, RequestHandler) (bson.Document, error)
+
+// Intercept calls the function.
+func (fn InterceptorFunc) Intercept(conn *Conn, req *Request, next RequestHandler) (bson.Document, error) {
+	return fn(conn, req, next)
+}
+
+// InterceptorChain represents a chain of interceptors which are run in the registration order.
+type InterceptorChain struct {
+	interceptors []Interceptor
+	mutex        *sync.RWMutex
+}
+
+// NewInterceptorChain returns a new empty interceptor chain.
+func NewInterceptorChain() *InterceptorChain {
+	return &InterceptorChain{
+		interceptors: []Interceptor{},
+		mutex:        &sync.RWMutex{},
+	}
+}
+
+// AddInterceptor appends the specified interceptor to the end of the chain.
+func (chain *InterceptorChain) AddInterceptor(i Interceptor) {
+	chain.mutex.Lock()
+	defer chain.mutex.Unlock()
+	chain.interceptors = append(chain.interceptors, i)
+}
+
+// Interceptors returns the interceptors in the registration order.
+func (chain *InterceptorChain) Interceptors() []Interceptor {
+	chain.mutex.RLock()
+	defer chain.mutex.RUnlock()
+	return append([]Interceptor{}, chain.interceptors...)
+}
+
+// handleRequest runs the interceptors around the specified handler.
+func (chain *InterceptorChain) handleRequest(conn *Conn, req *Request, h RequestHandler) (bson.Document, error) {
+	interceptors := chain.Interceptors()
+	var next func(int) RequestHandler
+	next = func(n int) RequestHandler {
+		if len(interceptors) <= n {
+			return h
+		}
+		return func(conn *Conn, req *Request) (bson.Document, error) {
+			return interceptors[n].Intercept(conn, req, next(n+1))
+		}
+	}
+	return next(0)(conn, req)
+}
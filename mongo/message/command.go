@@ -16,9 +16,11 @@ package message
 
 import (
 	"strings"
+	"time"
 
 	"github.com/cybergarage/go-mongo/mongo/bson"
 	"github.com/cybergarage/go-mongo/mongo/protocol"
+	"go.mongodb.org/mongo-driver/x/bsonx/bsoncore"
 )
 
 // See : MongoDB Handshake
@@ -32,6 +34,7 @@ const (
 
 const (
 	adminCommand = "admin.$cmd"
+	maxTimeMS    = "maxTimeMS"
 )
 
 const (
@@ -44,13 +47,103 @@ const (
 	SASLSupportedMechs = "saslSupportedMechs"
 	Hello              = "hello"
 	HelloOk            = "helloOk"
+	Compression        = "compression"
+	Ping               = "ping"
+	Authenticate       = "authenticate"
+	Logout             = "logout"
+	KillOp             = "killop"
+	CurrentOp          = "currentop"
+	// See : Speculative Authentication
+	// https://github.com/mongodb/specifications/blob/master/source/auth/auth.md#speculative-authentication
+	SpeculativeAuthenticate = "speculativeAuthenticate"
 )
 
+// See : User Management Commands and Role Management Commands
+// https://www.mongodb.com/docs/manual/reference/command/nav-user-management/
+// https://www.mongodb.com/docs/manual/reference/command/nav-role-management/
+
+const (
+	CreateUser          = "createuser"
+	UpdateUser          = "updateuser"
+	DropUser            = "dropuser"
+	UsersInfo           = "usersinfo"
+	CreateRole          = "createrole"
+	GrantRolesToUser    = "grantrolestouser"
+	RevokeRolesFromUser = "revokerolesfromuser"
+	RolesInfo           = "rolesinfo"
+)
+
+// See : Database Commands
+// https://www.mongodb.com/docs/manual/reference/command/
+
+const (
+	Create          = "create"
+	Drop            = "drop"
+	DropDatabase    = "dropdatabase"
+	CreateIndexes   = "createindexes"
+	DropIndexes     = "dropindexes"
+	ListCollections = "listcollections"
+	ListIndexes     = "listindexes"
+	CollMod         = "collmod"
+	CollStats       = "collstats"
+	DBStats         = "dbstats"
+	Count           = "count"
+	Distinct        = "distinct"
+	Aggregate       = "aggregate"
+	FindAndModify   = "findandmodify"
+	EndSessions     = "endsessions"
+	ListCommands    = "listcommands"
+)
+
+// See : Wire Compression
+// https://github.com/mongodb/specifications/blob/master/source/compression/OP_COMPRESSED.md
+
+var uncompressibleCommands = map[string]bool{
+	IsMaster:          true,
+	Hello:             true,
+	SASLStart:         true,
+	SASLContinue:      true,
+	"getnonce":        true,
+	Authenticate:      true,
+	CreateUser:        true,
+	UpdateUser:        true,
+	"copydbsaslstart": true,
+	"copydbgetnonce":  true,
+	"copydb":          true,
+}
+
+// See : Generic Command Arguments
+// https://www.mongodb.com/docs/manual/reference/command/#std-label-generic-command-arguments
+
+var genericArguments = map[string]bool{
+	"apiVersion":           true,
+	"apiStrict":            true,
+	"apiDeprecationErrors": true,
+	"comment":              true,
+	"lsid":                 true,
+	"maxTimeMS":            true,
+	"readConcern":          true,
+	"writeConcern":         true,
+	"txnNumber":            true,
+	"autocommit":           true,
+	"startTransaction":     true,
+}
+
+// IsGenericArgument returns true if the specified key is a generic command argument or a '$' prefixed field such as '$db'.
+func IsGenericArgument(key string) bool {
+	switch key {
+	case And, Or, Nor:
+		return false
+	}
+	return genericArguments[key] || strings.HasPrefix(key, "$")
+}
+
 // Command represents a query command of MongoDB database command.
 type Command struct {
 	IsAdmin  bool
 	Elements []bson.Element
 	typ      string
+	database string
 }
 
 // NewCommandWithDocument returns a new command instance with the specified BSON document.
@@ -70,6 +163,7 @@ func NewCommandWithDocument(doc bson.Document) (*Command, error) {
 		IsAdmin:  false,
 		Elements: elements,
 		typ:      cmdType,
+		database: "",
 	}
 	return cmd, nil
 }
@@ -82,6 +176,7 @@ func NewCommandWithQuery(q *protocol.Query) (*Command, error) {
 	}
 
 	cmd.IsAdmin = q.IsCollection(adminCommand)
+	cmd.database, _, _ = strings.Cut(q.CollectionName(), ".")
 
 	return cmd, nil
 }
@@ -100,6 +195,7 @@ func NewCommandWithMsg(msg *protocol.Msg) (*Command, error) {
 	if err == nil {
 		dbStr, ok := dbVal.StringValueOK()
 		if ok {
+			cmd.database = dbStr
 			if dbStr == "admin" {
 				isAdmin = true
 			}
@@ -111,6 +207,26 @@ func NewCommandWithMsg(msg *protocol.Msg) (*Command, error) {
 	return cmd, nil
 }
 
+// Database returns the database name which the command is executed on.
+func (cmd *Command) Database() string {
+	return cmd.database
+}
+
+// SetDatabase sets the database name which the command is executed on.
+func (cmd *Command) SetDatabase(db string) {
+	cmd.database = db
+}
+
+// Value returns the value of the specified element.
+func (cmd *Command) Value(key string) (bson.Value, bool) {
+	for _, elem := range cmd.Elements {
+		if elem.Key() == key {
+			return elem.Value(), true
+		}
+	}
+	return bson.Value{}, false
+}
+
 // IsAdminCommand returns true when it is a admin command, otherwise false.
 func (cmd *Command) IsAdminCommand() bool {
 	return cmd.IsAdmin
@@ -121,11 +237,46 @@ func (cmd *Command) Type() string {
 	return cmd.typ
 }
 
+// MaxTime returns the time limit of the specified command document, and returns false if the command has no positive 'maxTimeMS'.
+func MaxTime(doc bson.Document) (time.Duration, bool) {
+	if doc == nil {
+		return 0, false
+	}
+	val, err := doc.LookupErr(maxTimeMS)
+	if err != nil {
+		return 0, false
+	}
+	ms, ok := val.AsInt64OK()
+	if !ok || ms <= 0 {
+		return 0, false
+	}
+	return time.Duration(ms) * time.Millisecond, true
+}
+
 // IsType returns true when the command has the specified element, otherwise false.
 func (cmd *Command) IsType(typeString string) bool {
 	return cmd.typ == typeString
 }
 
+// IsCompressible returns false when the reply to the command must not be compressed, otherwise true.
+func (cmd *Command) IsCompressible() bool {
+	return IsCompressibleCommand(cmd.typ)
+}
+
+// IsCompressibleCommand returns false when the specified command type must not be compressed, otherwise true.
+func IsCompressibleCommand(cmdType string) bool {
+	return !uncompressibleCommands[strings.ToLower(cmdType)]
+}
+
+// Document returns the BSON document of the command elements.
+func (cmd *Command) Document() bson.Document {
+	elems := make([][]byte, len(cmd.Elements))
+	for n, element := range cmd.Elements {
+		elems[n] = element
+	}
+	return bsoncore.BuildDocument(nil, elems...)
+}
+
 // String returns the string description.
 func (cmd *Command) String() string {
 	str := ""
@@ -0,0 +1,250 @@
+// Copyright (C) 2019 The go-mongo Authors. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package message
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// See : MongoDB Error Codes
+// https://www.mongodb.com/docs/manual/reference/error-codes/
+
+// ErrorCode represents a MongoDB server error code.
+type ErrorCode int32
+
+const (
+	ErrorCodeInternalError            ErrorCode = 1
+	ErrorCodeBadValue                 ErrorCode = 2
+	ErrorCodeNoSuchKey                ErrorCode = 4
+	ErrorCodeUnknownError             ErrorCode = 8
+	ErrorCodeFailedToParse            ErrorCode = 9
+	ErrorCodeUserNotFound             ErrorCode = 11
+	ErrorCodeUnauthorized             ErrorCode = 13
+	ErrorCodeTypeMismatch             ErrorCode = 14
+	ErrorCodeAuthenticationFailed     ErrorCode = 18
+	ErrorCodeIllegalOperation         ErrorCode = 20
+	ErrorCodeNamespaceNotFound        ErrorCode = 26
+	ErrorCodeRoleNotFound             ErrorCode = 31
+	ErrorCodeCursorNotFound           ErrorCode = 43
+	ErrorCodeNamespaceExists          ErrorCode = 48
+	ErrorCodeMaxTimeMSExpired         ErrorCode = 50
+	ErrorCodeCommandNotFound          ErrorCode = 59
+	ErrorCodeWriteConcernFailed       ErrorCode = 64
+	ErrorCodeInvalidOptions           ErrorCode = 72
+	ErrorCodeInvalidNamespace         ErrorCode = 73
+	ErrorCodeShutdownInProgress       ErrorCode = 91
+	ErrorCodeOperationFailed          ErrorCode = 96
+	ErrorCodeCommandNotSupported      ErrorCode = 115
+	ErrorCodeNotImplemented           ErrorCode = 238
+	ErrorCodeClientDisconnect         ErrorCode = 279
+	ErrorCodeMechanismUnavailable     ErrorCode = 334
+	ErrorCodeReauthenticationRequired ErrorCode = 391
+	ErrorCodeNotWritablePrimary       ErrorCode = 10107
+	ErrorCodeDuplicateKey             ErrorCode = 11000
+	ErrorCodeNotPrimaryNoSecondaryOk  ErrorCode = 13435
+	ErrorCodeInterrupted              ErrorCode = 11601
+)
+
+// The following codes have no code names, and are named as "Location<code>" as MongoDB does.
+const (
+	ErrorCodeRoleAlreadyExists ErrorCode = 51002
+	ErrorCodeUserAlreadyExists ErrorCode = 51003
+)
+
+var errorCodeNames = map[ErrorCode]string{
+	ErrorCodeInternalError:            "InternalError",
+	ErrorCodeBadValue:                 "BadValue",
+	ErrorCodeNoSuchKey:                "NoSuchKey",
+	ErrorCodeUnknownError:             "UnknownError",
+	ErrorCodeFailedToParse:            "FailedToParse",
+	ErrorCodeUserNotFound:             "UserNotFound",
+	ErrorCodeUnauthorized:             "Unauthorized",
+	ErrorCodeTypeMismatch:             "TypeMismatch",
+	ErrorCodeAuthenticationFailed:     "AuthenticationFailed",
+	ErrorCodeIllegalOperation:         "IllegalOperation",
+	ErrorCodeNamespaceNotFound:        "NamespaceNotFound",
+	ErrorCodeRoleNotFound:             "RoleNotFound",
+	ErrorCodeCursorNotFound:           "CursorNotFound",
+	ErrorCodeNamespaceExists:          "NamespaceExists",
+	ErrorCodeMaxTimeMSExpired:         "MaxTimeMSExpired",
+	ErrorCodeCommandNotFound:          "CommandNotFound",
+	ErrorCodeWriteConcernFailed:       "WriteConcernFailed",
+	ErrorCodeInvalidOptions:           "InvalidOptions",
+	ErrorCodeInvalidNamespace:         "InvalidNamespace",
+	ErrorCodeShutdownInProgress:       "ShutdownInProgress",
+	ErrorCodeOperationFailed:          "OperationFailed",
+	ErrorCodeCommandNotSupported:      "CommandNotSupported",
+	ErrorCodeNotImplemented:           "NotImplemented",
+	ErrorCodeClientDisconnect:         "ClientDisconnect",
+	ErrorCodeMechanismUnavailable:     "MechanismUnavailable",
+	ErrorCodeReauthenticationRequired: "ReauthenticationRequired",
+	ErrorCodeNotWritablePrimary:       "NotWritablePrimary",
+	ErrorCodeDuplicateKey:             "DuplicateKey",
+	ErrorCodeNotPrimaryNoSecondaryOk:  "NotPrimaryNoSecondaryOk",
+	ErrorCodeInterrupted:              "Interrupted",
+}
+
+// Name returns the code name of the error code.
+func (code ErrorCode) Name() string {
+	name, ok := errorCodeNames[code]
+	if !ok {
+		return fmt.Sprintf("Location%d", int32(code))
+	}
+	return name
+}
+
+// Error represents a MongoDB server error which has a code, code name and error message.
+type Error struct {
+	Code    ErrorCode
+	Message string
+	err     error
+}
+
+// NewError returns a new server error with the specified code and message.
+func NewError(code ErrorCode, msg string) *Error {
+	return &Error{
+		Code:    code,
+		Message: msg,
+		err:     nil,
+	}
+}
+
+// NewErrorf returns a new server error with the specified code and formatted message.
+func NewErrorf(code ErrorCode, format string, args ...any) *Error {
+	return NewError(code, fmt.Sprintf(format, args...))
+}
+
+// NewErrorWith returns a new server error with the specified code which wraps the specified error.
+func NewErrorWith(code ErrorCode, err error) *Error {
+	return &Error{
+		Code:    code,
+		Message: err.Error(),
+		err:     err,
+	}
+}
+
+// ErrorFrom returns the server error of the specified error, and returns an internal error if the error is not a server error.
+func ErrorFrom(err error) *Error {
+	var serverErr *Error
+	if errors.As(err, &serverErr) {
+		return serverErr
+	}
+	return NewErrorWith(ErrorCodeInternalError, err)
+}
+
+// CodeName returns the code name of the error.
+func (err *Error) CodeName() string {
+	return err.Code.Name()
+}
+
+// Error returns the string description of the error.
+func (err *Error) Error() string {
+	return fmt.Sprintf("(%s) %s", err.CodeName(), err.Message)
+}
+
+// Unwrap returns the wrapped error.
+func (err *Error) Unwrap() error {
+	return err.err
+}
+
+// Is returns true if the target is a server error with the same code.
+func (err *Error) Is(target error) bool {
+	var serverErr *Error
+	if !errors.As(target, &serverErr) {
+		return false
+	}
+	return err.Code == serverErr.Code
+}
+
+// WriteError represents an error of a document in write commands.
+type WriteError struct {
+	*Error
+	Index int32
+}
+
+// NewWriteError returns a new write error of the document at the specified index.
+func NewWriteError(index int32, err error) *WriteError {
+	return &WriteError{
+		Error: ErrorFrom(err),
+		Index: index,
+	}
+}
+
+// WriteConcernError represents a write concern error in write commands.
+type WriteConcernError struct {
+	*Error
+}
+
+// NewWriteConcernError returns a new write concern error.
+func NewWriteConcernError(err error) *WriteConcernError {
+	return &WriteConcernError{
+		Error: ErrorFrom(err),
+	}
+}
+
+// WriteException represents errors of write commands which are partially succeeded.
+type WriteException struct {
+	WriteErrors       []*WriteError
+	WriteConcernError *WriteConcernError
+}
+
+// NewWriteException returns a new write exception with the specified write errors.
+func NewWriteException(writeErrs ...*WriteError) *WriteException {
+	return &WriteException{
+		WriteErrors:       writeErrs,
+		WriteConcernError: nil,
+	}
+}
+
+// AddWriteError adds a write error.
+func (e *WriteException) AddWriteError(writeErr *WriteError) {
+	e.WriteErrors = append(e.WriteErrors, writeErr)
+}
+
+// SetWriteConcernError sets a write concern error.
+func (e *WriteException) SetWriteConcernError(writeConcernErr *WriteConcernError) {
+	e.WriteConcernError = writeConcernErr
+}
+
+// HasErrors returns true if the exception has any write errors or a write concern error.
+func (e *WriteException) HasErrors() bool {
+	return 0 < len(e.WriteErrors) || e.WriteConcernError != nil
+}
+
+// Error returns the string description of the errors.
+func (e *WriteException) Error() string {
+	errs := []string{}
+	for _, writeErr := range e.WriteErrors {
+		errs = append(errs, fmt.Sprintf("[%d] %s", writeErr.Index, writeErr.Error.Error()))
+	}
+	if e.WriteConcernError != nil {
+		errs = append(errs, e.WriteConcernError.Error.Error())
+	}
+	return "write exception : " + strings.Join(errs, ", ")
+}
+
+// Unwrap returns the write errors and the write concern error.
+func (e *WriteException) Unwrap() []error {
+	errs := []error{}
+	for _, writeErr := range e.WriteErrors {
+		errs = append(errs, writeErr.Error)
+	}
+	if e.WriteConcernError != nil {
+		errs = append(errs, e.WriteConcernError.Error)
+	}
+	return errs
+}
@@ -0,0 +1,360 @@
+// Copyright (C) 2019 The go-mongo Authors. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package message
+
+import (
+	"regexp"
+	"strings"
+
+	"github.com/cybergarage/go-mongo/mongo/bson"
+	"go.mongodb.org/mongo-driver/bson/bsontype"
+)
+
+// See : Query and Projection Operators
+// https://www.mongodb.com/docs/manual/reference/operator/query/
+
+const (
+	// Eq matches values that are equal to a specified value.
+	Eq = "$eq"
+	// Ne matches all values that are not equal to a specified value.
+	Ne = "$ne"
+	// Gt matches values that are greater than a specified value.
+	Gt = "$gt"
+	// Gte matches values that are greater than or equal to a specified value.
+	Gte = "$gte"
+	// Lt matches values that are less than a specified value.
+	Lt = "$lt"
+	// Lte matches values that are less than or equal to a specified value.
+	Lte = "$lte"
+	// In matches any of the values specified in an array.
+	In = "$in"
+	// Nin matches none of the values specified in an array.
+	Nin = "$nin"
+	// Exists matches documents that have the specified field.
+	Exists = "$exists"
+	// Regex selects documents where values match a specified regular expression.
+	Regex = "$regex"
+	// Options specifies the options of $regex.
+	Options = "$options"
+	// And joins query clauses with a logical AND.
+	And = "$and"
+	// Or joins query clauses with a logical OR.
+	Or = "$or"
+	// Nor joins query clauses with a logical NOR.
+	Nor = "$nor"
+)
+
+// MatchFilter returns true if the document matches the specified query filter. The filter supports the equality
+// conditions of dotted field paths, the comparison operators, $exists, $regex and the logical operators $and, $or and $nor.
+func MatchFilter(doc bson.Document, filter bson.Document) (bool, error) {
+	elems, err := filter.Elements()
+	if err != nil {
+		return false, err
+	}
+	return MatchFilterElements(doc, elems)
+}
+
+// MatchFilterElements returns true if the document matches all the specified query filter elements.
+func MatchFilterElements(doc bson.Document, elems []bson.Element) (bool, error) {
+	for _, elem := range elems {
+		ok, err := matchFilterElement(doc, elem.Key(), elem.Value())
+		if err != nil || !ok {
+			return false, err
+		}
+	}
+	return true, nil
+}
+
+func matchFilterElement(doc bson.Document, key string, cond bson.Value) (bool, error) {
+	switch key {
+	case And, Or, Nor:
+		return matchLogicalFilter(doc, key, cond)
+	}
+	vals := lookupFilterValues(doc, strings.Split(key, "."))
+	condDoc, ok := cond.DocumentOK()
+	if !ok || !isOperatorDocument(condDoc) {
+		return matchAnyValue(vals, func(val bson.Value) (bool, error) {
+			return matchValue(val, cond), nil
+		})
+	}
+	elems, err := condDoc.Elements()
+	if err != nil {
+		return false, err
+	}
+	for _, elem := range elems {
+		ok, err := matchOperator(vals, elem.Key(), elem.Value(), condDoc)
+		if err != nil || !ok {
+			return false, err
+		}
+	}
+	return true, nil
+}
+
+func matchLogicalFilter(doc bson.Document, op string, cond bson.Value) (bool, error) {
+	arr, ok := cond.ArrayOK()
+	if !ok {
+		return false, NewErrorf(ErrorCodeBadValue, "%s must be an array", op)
+	}
+	vals, err := arr.Values()
+	if err != nil {
+		return false, err
+	}
+	nMatched := 0
+	for _, val := range vals {
+		filter, ok := val.DocumentOK()
+		if !ok {
+			return false, NewErrorf(ErrorCodeBadValue, "%s argument's entries must be objects", op)
+		}
+		matched, err := MatchFilter(doc, filter)
+		if err != nil {
+			return false, err
+		}
+		if matched {
+			nMatched++
+		}
+	}
+	switch op {
+	case And:
+		return nMatched == len(vals), nil
+	case Or:
+		return 0 < nMatched, nil
+	default:
+		return nMatched == 0, nil
+	}
+}
+
+func matchOperator(vals []bson.Value, op string, arg bson.Value, condDoc bson.Document) (bool, error) {
+	switch op {
+	case Eq:
+		return matchAnyValue(vals, func(val bson.Value) (bool, error) {
+			return matchValue(val, arg), nil
+		})
+	case Ne:
+		ok, err := matchOperator(vals, Eq, arg, condDoc)
+		return !ok, err
+	case Gt, Gte, Lt, Lte:
+		return matchAnyValue(vals, func(val bson.Value) (bool, error) {
+			cmp, ok := compareValues(val, arg)
+			if !ok {
+				return false, nil
+			}
+			switch op {
+			case Gt:
+				return 0 < cmp, nil
+			case Gte:
+				return 0 <= cmp, nil
+			case Lt:
+				return cmp < 0, nil
+			default:
+				return cmp <= 0, nil
+			}
+		})
+	case In, Nin:
+		arr, ok := arg.ArrayOK()
+		if !ok {
+			return false, NewErrorf(ErrorCodeBadValue, "%s needs an array", op)
+		}
+		args, err := arr.Values()
+		if err != nil {
+			return false, err
+		}
+		ok, err = matchAnyValue(vals, func(val bson.Value) (bool, error) {
+			for _, arg := range args {
+				if matchValue(val, arg) {
+					return true, nil
+				}
+			}
+			return false, nil
+		})
+		if op == Nin {
+			return !ok, err
+		}
+		return ok, err
+	case Exists:
+		exists := 0 < len(vals)
+		if isTrueValue(arg) {
+			return exists, nil
+		}
+		return !exists, nil
+	case Regex:
+		pattern, ok := arg.StringValueOK()
+		if !ok {
+			return false, NewErrorf(ErrorCodeBadValue, "%s has to be a string", op)
+		}
+		options, _ := condDoc.Lookup(Options).StringValueOK()
+		re, err := compileRegex(pattern, options)
+		if err != nil {
+			return false, err
+		}
+		return matchAnyValue(vals, func(val bson.Value) (bool, error) {
+			return matchRegex(val, re), nil
+		})
+	case Options:
+		return true, nil
+	}
+	return false, NewErrorf(ErrorCodeBadValue, "unknown operator: %s", op)
+}
+
+// lookupFilterValues returns the values of the dotted field path, and traverses the documents in arrays.
+func lookupFilterValues(doc bson.Document, path []string) []bson.Value {
+	val, err := doc.LookupErr(path[0])
+	if err != nil {
+		return []bson.Value{}
+	}
+	if len(path) == 1 {
+		return []bson.Value{val}
+	}
+	if subDoc, ok := val.DocumentOK(); ok {
+		return lookupFilterValues(subDoc, path[1:])
+	}
+	vals := []bson.Value{}
+	if arr, ok := val.ArrayOK(); ok {
+		elemVals, _ := arr.Values()
+		for _, elemVal := range elemVals {
+			if subDoc, ok := elemVal.DocumentOK(); ok {
+				vals = append(vals, lookupFilterValues(subDoc, path[1:])...)
+			}
+		}
+	}
+	return vals
+}
+
+// matchAnyValue returns true if any value or any element of array values matches.
+func matchAnyValue(vals []bson.Value, match func(bson.Value) (bool, error)) (bool, error) {
+	for _, val := range vals {
+		ok, err := match(val)
+		if err != nil || ok {
+			return ok, err
+		}
+		arr, isArray := val.ArrayOK()
+		if !isArray {
+			continue
+		}
+		elemVals, err := arr.Values()
+		if err != nil {
+			return false, err
+		}
+		for _, elemVal := range elemVals {
+			ok, err := match(elemVal)
+			if err != nil || ok {
+				return ok, err
+			}
+		}
+	}
+	return false, nil
+}
+
+// matchValue returns true if the value is equal to the condition value, or matches the regular expression condition.
+func matchValue(val bson.Value, cond bson.Value) bool {
+	if pattern, options, ok := cond.RegexOK(); ok {
+		re, err := compileRegex(pattern, options)
+		return err == nil && matchRegex(val, re)
+	}
+	if cmp, ok := compareValues(val, cond); ok {
+		return cmp == 0
+	}
+	return val.Equal(cond)
+}
+
+func isOperatorDocument(doc bson.Document) bool {
+	elems, err := doc.Elements()
+	if err != nil || len(elems) == 0 {
+		return false
+	}
+	return strings.HasPrefix(elems[0].Key(), "$")
+}
+
+// compareValues compares the numeric or string values, and returns false if the values are not comparable.
+func compareValues(val bson.Value, other bson.Value) (int, bool) {
+	if v, ok := numberValue(val); ok {
+		o, ok := numberValue(other)
+		if !ok {
+			return 0, false
+		}
+		switch {
+		case v < o:
+			return -1, true
+		case o < v:
+			return 1, true
+		}
+		return 0, true
+	}
+	if v, ok := val.StringValueOK(); ok {
+		o, ok := other.StringValueOK()
+		if !ok {
+			return 0, false
+		}
+		return strings.Compare(v, o), true
+	}
+	if v, ok := val.DateTimeOK(); ok {
+		o, ok := other.DateTimeOK()
+		if !ok {
+			return 0, false
+		}
+		switch {
+		case v < o:
+			return -1, true
+		case o < v:
+			return 1, true
+		}
+		return 0, true
+	}
+	return 0, false
+}
+
+func numberValue(val bson.Value) (float64, bool) {
+	switch val.Type {
+	case bsontype.Int32:
+		return float64(val.Int32()), true
+	case bsontype.Int64:
+		return float64(val.Int64()), true
+	case bsontype.Double:
+		return val.Double(), true
+	}
+	return 0, false
+}
+
+func isTrueValue(val bson.Value) bool {
+	if b, ok := val.BooleanOK(); ok {
+		return b
+	}
+	if n, ok := numberValue(val); ok {
+		return n != 0
+	}
+	return true
+}
+
+// compileRegex compiles the regular expression with the i, m and s options.
+func compileRegex(pattern string, options string) (*regexp.Regexp, error) {
+	flags := ""
+	for _, opt := range options {
+		if strings.ContainsRune("ims", opt) {
+			flags += string(opt)
+		}
+	}
+	if 0 < len(flags) {
+		pattern = "(?" + flags + ")" + pattern
+	}
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		return nil, NewErrorWith(ErrorCodeBadValue, err)
+	}
+	return re, nil
+}
+
+func matchRegex(val bson.Value, re *regexp.Regexp) bool {
+	str, ok := val.StringValueOK()
+	return ok && re.MatchString(str)
+}
@@ -0,0 +1,160 @@
+// Copyright (C) 2019 The go-mongo Authors. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package message
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func TestMatchFilter(t *testing.T) {
+	doc, err := bson.Marshal(bson.D{
+		{Key: "name", Value: "Ash"},
+		{Key: "age", Value: int32(10)},
+		{Key: "height", Value: 1.65},
+		{Key: "badges", Value: int64(8)},
+		{Key: "active", Value: true},
+		{Key: "since", Value: primitive.NewDateTimeFromTime(time.Date(1997, 4, 1, 0, 0, 0, 0, time.UTC))},
+		{Key: "town", Value: bson.D{{Key: "name", Value: "Pallet Town"}, {Key: "region", Value: "Kanto"}}},
+		{Key: "tags", Value: bson.A{"trainer", "champion"}},
+		{Key: "pokemons", Value: bson.A{
+			bson.D{{Key: "name", Value: "Pikachu"}, {Key: "level", Value: int32(25)}},
+			bson.D{{Key: "name", Value: "Charizard"}, {Key: "level", Value: int32(36)}},
+		}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name     string
+		filter   bson.D
+		expected bool
+	}{
+		// Equality
+		{"empty", bson.D{}, true},
+		{"equal", bson.D{{Key: "name", Value: "Ash"}}, true},
+		{"not equal", bson.D{{Key: "name", Value: "Misty"}}, false},
+		{"equal number types", bson.D{{Key: "age", Value: 10.0}}, true},
+		{"missing field", bson.D{{Key: "unknown", Value: 1}}, false},
+		{"multiple fields", bson.D{{Key: "name", Value: "Ash"}, {Key: "age", Value: int64(10)}}, true},
+		{"regex value", bson.D{{Key: "name", Value: primitive.Regex{Pattern: "^a", Options: "i"}}}, true},
+		// Comparison operators
+		{"$eq", bson.D{{Key: "age", Value: bson.D{{Key: "$eq", Value: 10}}}}, true},
+		{"$ne", bson.D{{Key: "age", Value: bson.D{{Key: "$ne", Value: 10}}}}, false},
+		{"$ne missing", bson.D{{Key: "unknown", Value: bson.D{{Key: "$ne", Value: 10}}}}, true},
+		{"$gt", bson.D{{Key: "height", Value: bson.D{{Key: "$gt", Value: 1.6}}}}, true},
+		{"$gte", bson.D{{Key: "badges", Value: bson.D{{Key: "$gte", Value: 8}}}}, true},
+		{"$lt", bson.D{{Key: "age", Value: bson.D{{Key: "$lt", Value: 10}}}}, false},
+		{"$lte", bson.D{{Key: "age", Value: bson.D{{Key: "$lte", Value: 10}}}}, true},
+		{"range", bson.D{{Key: "age", Value: bson.D{{Key: "$gt", Value: 5}, {Key: "$lt", Value: 8}}}}, false},
+		{"string comparison", bson.D{{Key: "name", Value: bson.D{{Key: "$lt", Value: "Brock"}}}}, true},
+		{"date comparison", bson.D{{Key: "since", Value: bson.D{{Key: "$gte", Value: primitive.NewDateTimeFromTime(time.Date(1996, 1, 1, 0, 0, 0, 0, time.UTC))}}}}, true},
+		{"incomparable types", bson.D{{Key: "name", Value: bson.D{{Key: "$gt", Value: 1}}}}, false},
+		{"$in", bson.D{{Key: "name", Value: bson.D{{Key: "$in", Value: bson.A{"Misty", "Ash"}}}}}, true},
+		{"$nin", bson.D{{Key: "name", Value: bson.D{{Key: "$nin", Value: bson.A{"Misty", "Ash"}}}}}, false},
+		// Element operators
+		{"$exists", bson.D{{Key: "town", Value: bson.D{{Key: "$exists", Value: true}}}}, true},
+		{"$exists false", bson.D{{Key: "unknown", Value: bson.D{{Key: "$exists", Value: false}}}}, true},
+		{"$exists number", bson.D{{Key: "town", Value: bson.D{{Key: "$exists", Value: 0}}}}, false},
+		// Regular expressions
+		{"$regex", bson.D{{Key: "name", Value: bson.D{{Key: "$regex", Value: "^A"}}}}, true},
+		{"$regex options", bson.D{{Key: "name", Value: bson.D{{Key: "$regex", Value: "^ash$"}, {Key: "$options", Value: "i"}}}}, true},
+		{"$regex case", bson.D{{Key: "name", Value: bson.D{{Key: "$regex", Value: "^ash$"}}}}, false},
+		{"$regex non-string", bson.D{{Key: "age", Value: bson.D{{Key: "$regex", Value: "10"}}}}, false},
+		// Dotted paths
+		{"dotted path", bson.D{{Key: "town.region", Value: "Kanto"}}, true},
+		{"dotted path mismatch", bson.D{{Key: "town.region", Value: "Johto"}}, false},
+		{"dotted path missing", bson.D{{Key: "town.unknown.name", Value: "Kanto"}}, false},
+		{"dotted path of non-document", bson.D{{Key: "name.first", Value: "Ash"}}, false},
+		{"embedded document", bson.D{{Key: "town", Value: bson.D{{Key: "name", Value: "Pallet Town"}, {Key: "region", Value: "Kanto"}}}}, true},
+		// Arrays
+		{"array element", bson.D{{Key: "tags", Value: "champion"}}, true},
+		{"array element mismatch", bson.D{{Key: "tags", Value: "gym leader"}}, false},
+		{"whole array", bson.D{{Key: "tags", Value: bson.A{"trainer", "champion"}}}, true},
+		{"array $in", bson.D{{Key: "tags", Value: bson.D{{Key: "$in", Value: bson.A{"champion"}}}}}, true},
+		{"array $nin", bson.D{{Key: "tags", Value: bson.D{{Key: "$nin", Value: bson.A{"champion"}}}}}, false},
+		{"array $ne", bson.D{{Key: "tags", Value: bson.D{{Key: "$ne", Value: "trainer"}}}}, false},
+		{"array of documents", bson.D{{Key: "pokemons.name", Value: "Charizard"}}, true},
+		{"array of documents $gt", bson.D{{Key: "pokemons.level", Value: bson.D{{Key: "$gt", Value: 30}}}}, true},
+		{"array of documents $lt", bson.D{{Key: "pokemons.level", Value: bson.D{{Key: "$lt", Value: 20}}}}, false},
+		// Logical operators
+		{"$and", bson.D{{Key: "$and", Value: bson.A{bson.D{{Key: "name", Value: "Ash"}}, bson.D{{Key: "age", Value: 10}}}}}, true},
+		{"$and mismatch", bson.D{{Key: "$and", Value: bson.A{bson.D{{Key: "name", Value: "Ash"}}, bson.D{{Key: "age", Value: 11}}}}}, false},
+		{"$or", bson.D{{Key: "$or", Value: bson.A{bson.D{{Key: "name", Value: "Misty"}}, bson.D{{Key: "age", Value: 10}}}}}, true},
+		{"$or mismatch", bson.D{{Key: "$or", Value: bson.A{bson.D{{Key: "name", Value: "Misty"}}, bson.D{{Key: "age", Value: 11}}}}}, false},
+		{"$nor", bson.D{{Key: "$nor", Value: bson.A{bson.D{{Key: "name", Value: "Misty"}}, bson.D{{Key: "age", Value: 11}}}}}, true},
+		{"$nor mismatch", bson.D{{Key: "$nor", Value: bson.A{bson.D{{Key: "name", Value: "Ash"}}}}}, false},
+		{"nested logical", bson.D{{Key: "$or", Value: bson.A{
+			bson.D{{Key: "$and", Value: bson.A{bson.D{{Key: "active", Value: true}}, bson.D{{Key: "town.region", Value: "Kanto"}}}}},
+			bson.D{{Key: "name", Value: "Misty"}},
+		}}}, true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			filter, err := bson.Marshal(test.filter)
+			if err != nil {
+				t.Fatal(err)
+			}
+			ok, err := MatchFilter(doc, filter)
+			if err != nil {
+				t.Error(err)
+				return
+			}
+			if ok != test.expected {
+				t.Errorf("%t != %t", ok, test.expected)
+			}
+		})
+	}
+}
+
+func TestMatchFilterErrors(t *testing.T) {
+	doc, err := bson.Marshal(bson.D{{Key: "name", Value: "Ash"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name   string
+		filter bson.D
+	}{
+		{"unknown operator", bson.D{{Key: "name", Value: bson.D{{Key: "$unknown", Value: 1}}}}},
+		{"$in without array", bson.D{{Key: "name", Value: bson.D{{Key: "$in", Value: "Ash"}}}}},
+		{"$nin without array", bson.D{{Key: "name", Value: bson.D{{Key: "$nin", Value: "Ash"}}}}},
+		{"$regex without string", bson.D{{Key: "name", Value: bson.D{{Key: "$regex", Value: 1}}}}},
+		{"invalid $regex", bson.D{{Key: "name", Value: bson.D{{Key: "$regex", Value: "("}}}}},
+		{"$and without array", bson.D{{Key: "$and", Value: bson.D{{Key: "name", Value: "Ash"}}}}},
+		{"$or with non-document", bson.D{{Key: "$or", Value: bson.A{"Ash"}}}},
+		{"$nor with nested error", bson.D{{Key: "$nor", Value: bson.A{bson.D{{Key: "name", Value: bson.D{{Key: "$unknown", Value: 1}}}}}}}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			filter, err := bson.Marshal(test.filter)
+			if err != nil {
+				t.Fatal(err)
+			}
+			_, err = MatchFilter(doc, filter)
+			if !errors.Is(err, NewError(ErrorCodeBadValue, "")) {
+				t.Errorf("%v is not a BadValue error", err)
+			}
+		})
+	}
+}
@@ -15,6 +15,7 @@
 package message
 
 const (
+	errMsg       = "err"
 	syncMillis   = "syncMillis"
 	writtenTo    = "writtenTo"
 	connectionID = "connectionId"
@@ -23,7 +24,7 @@ const (
 // NewDefaultLastErrorResponse returns a default response instance.
 func NewDefaultLastErrorResponse() (*Response, error) {
 	defaultElements := map[string]interface{}{
-		"err":        nil,
+		errMsg:       nil,
 		"n":          int32(0),
 		syncMillis:   int32(0),
 		connectionID: int32(0),
@@ -38,3 +39,18 @@ func NewDefaultLastErrorResponse() (*Response, error) {
 
 	return res, nil
 }
+
+// NewLastErrorResponseWithError returns a response instance with the specified last error.
+func NewLastErrorResponseWithError(lastErr error) (*Response, error) {
+	res, err := NewDefaultLastErrorResponse()
+	if err != nil {
+		return nil, err
+	}
+	if lastErr != nil {
+		serverErr := ErrorFrom(lastErr)
+		res.SetStringElement(errMsg, serverErr.Message)
+		res.SetInt32Element(errorCode, int32(serverErr.Code))
+		res.SetStringElement(errorCodeName, serverErr.CodeName())
+	}
+	return res, nil
+}
@@ -29,7 +29,6 @@ const (
 	minWireVersion               = "minWireVersion"
 	maxWireVersion               = "maxWireVersion"
 	readOnly                     = "readOnly"
-	compression                  = "compression"
 
 	DefaultMaxBsonObjectSize            = 16 * 1024 * 1024
 	DefaultMaxMessageSizeBytes          = 48000000
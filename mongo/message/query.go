@@ -34,29 +34,40 @@ const (
 	Filter      = "filter"
 	Documents   = "documents"
 	KillCursors = "killCursors"
+	GetMore     = "getMore"
+	Collection  = "collection"
+	BatchSize   = "batchSize"
+	SingleBatch = "singleBatch"
+	Cursors     = "cursors"
 )
 
 // Query represents a message query.
 type Query struct {
-	database   string
-	collection string
-	typ        string
-	conditions []bson.Document
-	documents  []bson.Document
-	operator   string
-	limit      int
+	database    string
+	collection  string
+	typ         string
+	conditions  []bson.Document
+	documents   []bson.Document
+	operator    string
+	limit       int
+	batchSize   int
+	singleBatch bool
+	cursorIDs   []int64
 }
 
 // NewQuery returns a new query.
 func NewQuery() *Query {
 	q := &Query{
-		database:   "",
-		collection: "",
-		typ:        "",
-		conditions: make([]bson.Document, 0),
-		documents:  make([]bson.Document, 0),
-		operator:   "",
-		limit:      0,
+		database:    "",
+		collection:  "",
+		typ:         "",
+		conditions:  make([]bson.Document, 0),
+		documents:   make([]bson.Document, 0),
+		operator:    "",
+		limit:       0,
+		batchSize:   0,
+		singleBatch: false,
+		cursorIDs:   make([]int64, 0),
 	}
 	return q
 }
@@ -117,3 +128,26 @@ func (q *Query) Operator() string {
 func (q *Query) Limit() int {
 	return q.limit
 }
+
+// BatchSize returns the number of documents to return in each batch.
+func (q *Query) BatchSize() int {
+	return q.batchSize
+}
+
+// IsSingleBatch returns true if the cursor should be closed after the first batch.
+func (q *Query) IsSingleBatch() bool {
+	return q.singleBatch
+}
+
+// CursorID returns the cursor identifier of 'getMore' query.
+func (q *Query) CursorID() int64 {
+	if len(q.cursorIDs) == 0 {
+		return 0
+	}
+	return q.cursorIDs[0]
+}
+
+// CursorIDs returns the cursor identifiers of 'getMore' and 'killCursors' queries.
+func (q *Query) CursorIDs() []int64 {
+	return q.cursorIDs
+}
@@ -25,6 +25,22 @@ func NewQueryWithMessage(msg *protocol.Msg) (*Query, error) {
 	return q, q.ParseMsg(msg)
 }
 
+// NewQueryWithCommand returns a new query with the specified command and document sequences of OP_MSG.
+// The database of the query is the database of the command.
+func NewQueryWithCommand(cmd *Command, docs []bson.Document) (*Query, error) {
+	q := NewQuery()
+	err := q.parseBodyDocument(cmd.Document())
+	if err != nil {
+		return nil, err
+	}
+	err = q.parseDocuments(docs)
+	if err != nil {
+		return nil, err
+	}
+	q.database = cmd.Database()
+	return q, nil
+}
+
 // ParseMsg parses the specified OP_MSG.
 func (q *Query) ParseMsg(msg *protocol.Msg) error {
 	err := q.parseBodyDocument(msg.Body())
@@ -53,6 +69,41 @@ func (q *Query) parseBodyDocument(doc bson.Document) error {
 			if ok {
 				q.collection = col
 			}
+		case GetMore:
+			q.typ = key
+			id, ok := element.Value().AsInt64OK()
+			if ok {
+				q.cursorIDs = append(q.cursorIDs, id)
+			}
+		case Collection:
+			col, ok := element.Value().StringValueOK()
+			if ok {
+				q.collection = col
+			}
+		case BatchSize:
+			n, ok := element.Value().AsInt64OK()
+			if ok {
+				q.batchSize = int(n)
+			}
+		case SingleBatch:
+			b, ok := element.Value().BooleanOK()
+			if ok {
+				q.singleBatch = b
+			}
+		case Cursors:
+			ids, ok := element.Value().ArrayOK()
+			if ok {
+				vals, err := ids.Values()
+				if err != nil {
+					return err
+				}
+				for _, val := range vals {
+					id, ok := val.AsInt64OK()
+					if ok {
+						q.cursorIDs = append(q.cursorIDs, id)
+					}
+				}
+			}
 		case DB:
 			db, ok := element.Value().StringValueOK()
 			if ok {
@@ -15,6 +15,7 @@
 package message
 
 import (
+	"github.com/cybergarage/go-mongo/mongo/bson"
 	"github.com/cybergarage/go-mongo/mongo/protocol"
 	"go.mongodb.org/mongo-driver/bson/bsontype"
 )
@@ -25,12 +26,29 @@ func NewQueryWithQuery(msg *protocol.Query) (*Query, error) {
 	return q, q.ParseQuery(msg)
 }
 
-// ParseQuery parses the specified OP_MSG.
+// NewQueryWithQueryCommand returns a new query with the specified command of OP_QUERY.
+// The database of the query is the database of the command.
+func NewQueryWithQueryCommand(cmd *Command) (*Query, error) {
+	q := NewQuery()
+	err := q.parseQueryDocument(cmd.Document())
+	if err != nil {
+		return nil, err
+	}
+	q.database = cmd.Database()
+	return q, nil
+}
+
+// ParseQuery parses the specified OP_QUERY.
 func (q *Query) ParseQuery(msg *protocol.Query) error {
 	query := msg.Document()
 	if query == nil {
 		return nil
 	}
+	return q.parseQueryDocument(query)
+}
+
+// parseQueryDocument parses the specified query document of OP_QUERY.
+func (q *Query) parseQueryDocument(query bson.Document) error {
 	elements, err := query.Elements()
 	if err != nil {
 		return err
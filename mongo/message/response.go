@@ -15,6 +15,7 @@
 package message
 
 import (
+	"errors"
 	"strconv"
 
 	"github.com/cybergarage/go-mongo/mongo/bson"
@@ -25,9 +26,20 @@ const (
 	ok                        = "ok"
 	cursor                    = "cursor"
 	firstBatch                = "firstBatch"
+	nextBatch                 = "nextBatch"
+	cursorsKilled             = "cursorsKilled"
+	cursorsNotFound           = "cursorsNotFound"
+	cursorsAlive              = "cursorsAlive"
+	cursorsUnknown            = "cursorsUnknown"
 	nameSpace                 = "ns"
 	numberOfAffectedDocuments = "n"
 	numberOfModifiedDocuments = "nModified"
+	errorMessage              = "errmsg"
+	errorCode                 = "code"
+	errorCodeName             = "codeName"
+	writeErrors               = "writeErrors"
+	writeErrorIndex           = "index"
+	writeConcernError         = "writeConcernError"
 )
 
 // Response represents response elements.
@@ -70,6 +82,13 @@ func NewBadResponse() *Response {
 	return NewResponseWithStatus(false)
 }
 
+// NewErrorResponse returns a bad status response with the code, code name and message of the specified error.
+func NewErrorResponse(err error) *Response {
+	res := NewResponse()
+	res.SetErrorStatus(err)
+	return res
+}
+
 // SetStatus sets an int32 response result.
 func (res *Response) SetStatus(flag bool) {
 	if flag {
@@ -79,13 +98,59 @@ func (res *Response) SetStatus(flag bool) {
 	res.SetDoubleElement(ok, 0.0)
 }
 
-// SetErrorStatus sets an int32 response result.
+// SetErrorStatus sets a response result of the specified error.
+// Write exceptions are set as a good status with the write errors as MongoDB does.
 func (res *Response) SetErrorStatus(err error) {
 	if err == nil {
 		res.SetDoubleElement(ok, 1.0)
 		return
 	}
+	var writeException *WriteException
+	if errors.As(err, &writeException) {
+		res.SetDoubleElement(ok, 1.0)
+		res.SetWriteException(writeException)
+		return
+	}
+	res.SetError(err)
+}
+
+// SetError sets a bad status with the code, code name and message of the specified error.
+func (res *Response) SetError(err error) {
+	serverErr := ErrorFrom(err)
 	res.SetDoubleElement(ok, 0.0)
+	res.SetStringElement(errorMessage, serverErr.Message)
+	res.SetInt32Element(errorCode, int32(serverErr.Code))
+	res.SetStringElement(errorCodeName, serverErr.CodeName())
+}
+
+// SetWriteException sets the write errors and the write concern error of the specified exception.
+func (res *Response) SetWriteException(e *WriteException) {
+	if 0 < len(e.WriteErrors) {
+		writeErrs := []any{}
+		for _, writeErr := range e.WriteErrors {
+			dict := bson.NewDictionary()
+			dict.SetInt32Element(writeErrorIndex, writeErr.Index)
+			dict.SetInt32Element(errorCode, int32(writeErr.Code))
+			dict.SetStringElement(errorCodeName, writeErr.CodeName())
+			dict.SetStringElement(errorMessage, writeErr.Message)
+			doc, err := dict.BSONBytes()
+			if err != nil {
+				continue
+			}
+			writeErrs = append(writeErrs, doc)
+		}
+		res.SetArrayElements(writeErrors, writeErrs)
+	}
+	if e.WriteConcernError != nil {
+		dict := bson.NewDictionary()
+		dict.SetInt32Element(errorCode, int32(e.WriteConcernError.Code))
+		dict.SetStringElement(errorCodeName, e.WriteConcernError.CodeName())
+		dict.SetStringElement(errorMessage, e.WriteConcernError.Message)
+		doc, err := dict.BSONBytes()
+		if err == nil {
+			res.SetDocumentElement(writeConcernError, doc)
+		}
+	}
 }
 
 // SetNumberOfAffectedDocuments sets a number of affected documents.
@@ -100,15 +165,53 @@ func (res *Response) SetNumberOfModifiedDocuments(n int32) {
 
 // SetCursorDocuments sets a resultset.
 func (res *Response) SetCursorDocuments(fullCollectionName string, docs []bson.Document) {
+	res.SetCursorFirstBatch(fullCollectionName, 0, docs)
+}
+
+// SetCursorFirstBatch sets the first batch of the specified cursor.
+func (res *Response) SetCursorFirstBatch(fullCollectionName string, cursorID int64, docs []bson.Document) {
+	res.setCursorBatch(fullCollectionName, cursorID, firstBatch, docs)
+}
+
+// SetCursorNextBatch sets the next batch of the specified cursor.
+func (res *Response) SetCursorNextBatch(fullCollectionName string, cursorID int64, docs []bson.Document) {
+	res.setCursorBatch(fullCollectionName, cursorID, nextBatch, docs)
+}
+
+// SetKilledCursors sets the results of 'killCursors' command.
+func (res *Response) SetKilledCursors(killedIDs []int64, notFoundIDs []int64) {
+	toArray := func(ids []int64) []any {
+		vals := make([]any, len(ids))
+		for n, id := range ids {
+			vals[n] = id
+		}
+		return vals
+	}
+	res.SetArrayElements(cursorsKilled, toArray(killedIDs))
+	res.SetArrayElements(cursorsNotFound, toArray(notFoundIDs))
+	res.SetArrayElements(cursorsAlive, []any{})
+	res.SetArrayElements(cursorsUnknown, []any{})
+}
+
+// CursorID returns the cursor identifier of the specified response document.
+func CursorID(doc bson.Document) (int64, bool) {
+	val, err := doc.LookupErr(cursor, "id")
+	if err != nil {
+		return 0, false
+	}
+	return val.AsInt64OK()
+}
+
+func (res *Response) setCursorBatch(fullCollectionName string, cursorID int64, batch string, docs []bson.Document) {
 	var arrIdx int32
 	cursorIdx, cursorDoc := bsoncore.AppendDocumentStart(nil)
-	arrIdx, cursorDoc = bsoncore.AppendArrayElementStart(cursorDoc, firstBatch)
+	arrIdx, cursorDoc = bsoncore.AppendArrayElementStart(cursorDoc, batch)
 	for n, doc := range docs {
 		cursorDoc = bsoncore.AppendDocumentElement(cursorDoc, strconv.Itoa(n), doc)
 	}
 	cursorDoc, _ = bsoncore.AppendArrayEnd(cursorDoc, arrIdx)
 
-	cursorDoc = bsoncore.AppendInt64Element(cursorDoc, "id", 0)
+	cursorDoc = bsoncore.AppendInt64Element(cursorDoc, "id", cursorID)
 	cursorDoc = bsoncore.AppendStringElement(cursorDoc, nameSpace, fullCollectionName)
 	cursorDoc, _ = bsoncore.AppendDocumentEnd(cursorDoc, cursorIdx)
 
@@ -17,14 +17,23 @@ package mongo
 import (
 	"fmt"
 
+	"github.com/cybergarage/go-mongo/mongo/auth"
 	"github.com/cybergarage/go-mongo/mongo/bson"
 	"github.com/cybergarage/go-mongo/mongo/message"
 )
 
+const (
+	commandCollection = "$cmd"
+)
+
 // BaseMessageHandler is a complete hander for MessageHandler.
 type BaseMessageHandler struct {
 	CommandExecutor
 	MessageExecutor
+	Authorizer
+	*CursorManager
+	*InterceptorChain
+	registry *CommandRegistry
 }
 
 func newBaseMessageHandlerNotImplementedError(msg OpMessage) error {
@@ -34,8 +43,12 @@ func newBaseMessageHandlerNotImplementedError(msg OpMessage) error {
 // NewBaseMessageHandler returns a complete null handler for MessageHandler.
 func NewBaseMessageHandler() *BaseMessageHandler {
 	return &BaseMessageHandler{
-		CommandExecutor: nil,
-		MessageExecutor: nil,
+		CommandExecutor:  nil,
+		MessageExecutor:  nil,
+		Authorizer:       nil,
+		CursorManager:    NewCursorManager(),
+		InterceptorChain: NewInterceptorChain(),
+		registry:         nil,
 	}
 }
 
@@ -49,6 +62,83 @@ func (handler *BaseMessageHandler) SetMessageExecutor(fn MessageExecutor) {
 	handler.MessageExecutor = fn
 }
 
+// SetAuthorizer sets an authorizer for the commands of MongoDB wire protocol.
+func (handler *BaseMessageHandler) SetAuthorizer(fn Authorizer) {
+	handler.Authorizer = fn
+}
+
+// SetCommandRegistry sets a registry which dispatches the commands, and registers the user database commands into it.
+func (handler *BaseMessageHandler) SetCommandRegistry(registry *CommandRegistry) {
+	handler.registry = registry
+	for _, spec := range handler.queryCommandSpecs() {
+		registry.RegisterCommand(spec)
+	}
+}
+
+// queryCommandSpecs returns the user database commands which are parsed and dispatched as queries.
+func (handler *BaseMessageHandler) queryCommandSpecs() []*CommandSpec {
+	return []*CommandSpec{
+		NewQueryCommandSpec("find",
+			handler.runQuery,
+			WithCommandHelp("selects the documents in a collection"),
+			WithCommandSecondaryAllowed(true)),
+		NewQueryCommandSpec("insert",
+			handler.runQuery,
+			WithCommandHelp("inserts the documents into a collection"),
+			WithCommandWrite(true)),
+		NewQueryCommandSpec("update",
+			handler.runQuery,
+			WithCommandHelp("modifies the documents in a collection"),
+			WithCommandWrite(true)),
+		NewQueryCommandSpec("delete",
+			handler.runQuery,
+			WithCommandHelp("removes the documents from a collection"),
+			WithCommandWrite(true)),
+		NewQueryCommandSpec("getMore",
+			handler.runQuery,
+			WithCommandHelp("returns the next batch of a cursor"),
+			WithCommandSecondaryAllowed(true)),
+		NewQueryCommandSpec("killCursors",
+			handler.runQuery,
+			WithCommandHelp("kills the cursors of a collection"),
+			WithCommandSecondaryAllowed(true)),
+	}
+}
+
+// authorize returns an error if the connection is not allowed to execute the specified command.
+func (handler *BaseMessageHandler) authorize(conn *Conn, cmd *Command) error {
+	if handler.Authorizer == nil {
+		return nil
+	}
+	return handler.Authorizer.Authorize(conn, cmd)
+}
+
+// authorizeQuery returns an error if the connection is not allowed to execute the specified query.
+func (handler *BaseMessageHandler) authorizeQuery(conn *Conn, q *message.Query) error {
+	if handler.Authorizer == nil {
+		return nil
+	}
+	return handler.Authorizer.AuthorizeQuery(conn, q)
+}
+
+// dispatchOperation records the command name, namespace and authenticated user to the in-flight operation of the connection.
+func (handler *BaseMessageHandler) dispatchOperation(conn *Conn, cmdName string, ns string) {
+	op, ok := conn.Operation()
+	if !ok {
+		return
+	}
+	var userName *auth.UserName
+	if name, ok := conn.UserName(); ok {
+		userName = &name
+	}
+	op.setCommand(cmdName, ns, userName)
+}
+
+// commandNamespace returns the namespace of the database commands.
+func commandNamespace(db string) string {
+	return db + "." + commandCollection
+}
+
 // OpUpdate handles OP_UPDATE of MongoDB wire protocol.
 func (handler *BaseMessageHandler) OpUpdate(conn *Conn, msg *OpUpdate) (bson.Document, error) {
 	return nil, newBaseMessageHandlerNotImplementedError(msg)
@@ -70,29 +160,34 @@ func (handler *BaseMessageHandler) OpQuery(conn *Conn, msg *OpQuery) (bson.Docum
 		return nil, err
 	}
 
+	req := newQueryRequest(cmd)
+
+	return handler.handleRequest(conn, req, handler.executeQueryRequest)
+}
+
+// executeQueryRequest executes the request of OP_QUERY after the interceptors.
+func (handler *BaseMessageHandler) executeQueryRequest(conn *Conn, req *Request) (bson.Document, error) {
+	cmd := req.Command
+	err := handler.authorize(conn, cmd)
+	if err != nil {
+		return nil, err
+	}
+
 	cmdType := cmd.Type()
 	conn.StartSpan(cmdType)
 	defer conn.FinishSpan()
 
-	switch cmdType {
-	// For user database commands over OP_QUERY under MongoDB v3.6
-	case message.Insert, message.Delete, message.Update, message.Find:
-		q, err := message.NewQueryWithQuery(msg)
-		if err != nil {
-			return nil, err
-		}
-		res := message.NewResponse()
-		err = handler.executeQuery(conn, q, res)
-		if err != nil {
-			return nil, err
-		}
-		bsonRes, err := res.BSONBytes()
-		if err != nil {
-			return nil, err
-		}
-		return bsonRes, nil
+	q, err := req.Query()
+	if err != nil {
+		return nil, err
 	}
 
+	if q != nil {
+		handler.dispatchOperation(conn, cmdType, q.FullCollectionName())
+		return handler.executeQueryCommand(conn, cmd, q)
+	}
+
+	handler.dispatchOperation(conn, cmdType, commandNamespace(cmd.Database()))
 	return handler.CommandExecutor.ExecuteCommand(conn, cmd)
 }
 
@@ -124,72 +219,180 @@ func (handler *BaseMessageHandler) OpMsg(conn *Conn, msg *OpMsg) (bson.Document,
 		return nil, newBaseMessageHandlerNotImplementedError(msg)
 	}
 
-	q, err := message.NewQueryWithMessage(msg)
+	cmd, err := message.NewCommandWithMsg(msg)
 	if err != nil {
 		return nil, err
 	}
 
-	res := message.NewResponse()
+	req := newMsgRequest(cmd, msg.Documents())
 
-	queryType := q.Type()
-	switch queryType {
-	// For user database commands over OP_MSG from MongoDB v3.6
-	case message.Insert, message.Delete, message.Update, message.Find:
-		conn.StartSpan(queryType)
-		defer conn.FinishSpan()
-		err = handler.executeQuery(conn, q, res)
-		if err != nil {
-			return nil, err
-		}
-	case message.KillCursors:
-		conn.StartSpan(queryType)
-		defer conn.FinishSpan()
-		// TODO : Kill the specified cursors internally
-		res.SetStatus(true)
-	default: // Execute other messages as a database command
-		cmd, err := message.NewCommandWithMsg(msg)
-		if err != nil {
-			return nil, err
-		}
+	return handler.handleRequest(conn, req, func(conn *Conn, req *Request) (bson.Document, error) {
+		return handler.executeMsgRequest(conn, msg, req)
+	})
+}
+
+// executeMsgRequest executes the request of OP_MSG after the interceptors.
+func (handler *BaseMessageHandler) executeMsgRequest(conn *Conn, msg *OpMsg, req *Request) (bson.Document, error) {
+	cmd := req.Command
+	err := handler.authorize(conn, cmd)
+	if err != nil {
+		return nil, err
+	}
+
+	q, err := req.Query()
+	if err != nil {
+		return nil, err
+	}
+
+	if q == nil { // Execute other messages as a database command
 		conn.StartSpan(cmd.String())
 		defer conn.FinishSpan()
+		handler.dispatchOperation(conn, cmd.Type(), commandNamespace(cmd.Database()))
 		resDoc, err := handler.CommandExecutor.ExecuteCommand(conn, cmd)
 		if err != nil {
+			if msg.IsMoreToCome() {
+				conn.SetLastError(err)
+			}
 			return nil, err
 		}
 		return resDoc, nil
 	}
 
-	bsonRes, err := res.BSONBytes()
-	if err != nil {
-		return nil, err
+	queryType := q.Type()
+	conn.StartSpan(queryType)
+	defer conn.FinishSpan()
+	handler.dispatchOperation(conn, queryType, q.FullCollectionName())
+
+	return handler.executeQueryCommand(conn, cmd, q)
+}
+
+// executeQueryCommand runs the registered handler of the query command, or runs the query directly without a registry.
+func (handler *BaseMessageHandler) executeQueryCommand(conn *Conn, cmd *Command, q *message.Query) (bson.Document, error) {
+	if handler.registry == nil {
+		return handler.runQuery(conn, q)
+	}
+	spec, ok := handler.registry.LookupCommand(cmd.Type())
+	if !ok {
+		return nil, NewCommandNotFoundError(cmd.Type())
 	}
+	if !spec.IsQuery() {
+		return handler.CommandExecutor.ExecuteCommand(conn, cmd)
+	}
+	return spec.ExecuteQuery(conn, q)
+}
 
-	return bsonRes, nil
+// runQuery executes the specified query, and returns the response document.
+func (handler *BaseMessageHandler) runQuery(conn *Conn, q *message.Query) (bson.Document, error) {
+	res := message.NewResponse()
+	switch q.Type() {
+	case message.KillCursors:
+		err := handler.authorizeQuery(conn, q)
+		if err != nil {
+			res.SetErrorStatus(err)
+			break
+		}
+		handler.killCursors(conn, q, res)
+	default:
+		err := handler.executeQuery(conn, q, res)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return res.BSONBytes()
 }
 
 // executeQuery executes user database commands (insert, update, find and delete) over OP_MSG and OP_QUERY.
 func (handler *BaseMessageHandler) executeQuery(conn *Conn, q *message.Query, res *message.Response) error {
+	// Checks the privileges of the query before executing it.
+	err := handler.authorizeQuery(conn, q)
+	if err != nil {
+		res.SetErrorStatus(err)
+		conn.SetLastError(err)
+		return nil
+	}
+
 	switch q.Type() {
 	case message.Insert:
-		n, err := handler.MessageExecutor.Insert(conn, q)
+		var n int32
+		n, err = handler.MessageExecutor.Insert(conn, q)
+		err = conn.requestError(err)
 		res.SetErrorStatus(err)
 		res.SetNumberOfAffectedDocuments(n)
 	case message.Delete:
-		n, err := handler.MessageExecutor.Delete(conn, q)
+		var n int32
+		n, err = handler.MessageExecutor.Delete(conn, q)
+		err = conn.requestError(err)
 		res.SetErrorStatus(err)
 		res.SetNumberOfAffectedDocuments(n)
 	case message.Update:
-		n, err := handler.MessageExecutor.Update(conn, q)
+		var n int32
+		n, err = handler.MessageExecutor.Update(conn, q)
+		err = conn.requestError(err)
 		res.SetErrorStatus(err)
 		res.SetNumberOfAffectedDocuments(n)
 		res.SetNumberOfModifiedDocuments(n)
 	case message.Find:
-		docs, err := handler.MessageExecutor.Find(conn, q)
+		var docs []bson.Document
+		docs, err = handler.MessageExecutor.Find(conn, q)
+		err = conn.requestError(err)
+		if err == nil {
+			var cursorID int64
+			docs, cursorID, err = handler.openCursor(conn, q, docs)
+			res.SetCursorFirstBatch(q.FullCollectionName(), cursorID, docs)
+		}
 		res.SetErrorStatus(err)
-		res.SetCursorDocuments(q.FullCollectionName(), docs)
+	case message.GetMore:
+		cursor, ok := handler.ConnCursorByID(conn, q.CursorID())
+		if !ok {
+			err = NewCursorNotFound(q.CursorID())
+			res.SetErrorStatus(err)
+			break
+		}
+		if cursor.Namespace() != q.FullCollectionName() {
+			err = NewErrorf(ErrorCodeUnauthorized, "requested getMore on namespace '%s', but cursor belongs to a different namespace %s", q.FullCollectionName(), cursor.Namespace())
+			res.SetErrorStatus(err)
+			break
+		}
+		docs := cursor.Next(q.BatchSize())
+		cursorID := cursor.ID()
+		if cursor.IsExhausted() {
+			handler.KillCursor(cursorID)
+			cursorID = 0
+		}
+		res.SetCursorNextBatch(cursor.Namespace(), cursorID, docs)
+		res.SetStatus(true)
 	default:
 		res.SetStatus(false)
 	}
+	// Keep the result for getLastError, because unacknowledged writes have no reply.
+	conn.SetLastError(err)
 	return nil
 }
+
+// openCursor returns the first batch of the found documents, and keeps the remaining documents in a new cursor.
+func (handler *BaseMessageHandler) openCursor(conn *Conn, q *message.Query, docs []bson.Document) ([]bson.Document, int64, error) {
+	batchSize := q.BatchSize()
+	if q.IsSingleBatch() || batchSize <= 0 || len(docs) <= batchSize {
+		return docs, 0, nil
+	}
+	cursor, err := handler.OpenCursor(conn, q.FullCollectionName(), docs[batchSize:])
+	if err != nil {
+		return nil, 0, err
+	}
+	return docs[:batchSize], cursor.ID(), nil
+}
+
+// killCursors kills the cursors of the connection in the namespace of 'killCursors' query.
+func (handler *BaseMessageHandler) killCursors(conn *Conn, q *message.Query, res *message.Response) {
+	killedIDs := []int64{}
+	notFoundIDs := []int64{}
+	for _, id := range q.CursorIDs() {
+		if handler.KillConnCursor(conn, q.FullCollectionName(), id) {
+			killedIDs = append(killedIDs, id)
+		} else {
+			notFoundIDs = append(notFoundIDs, id)
+		}
+	}
+	res.SetKilledCursors(killedIDs, notFoundIDs)
+	res.SetStatus(true)
+}
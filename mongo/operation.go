@@ -0,0 +1,169 @@
+// Copyright (C) 2022 The go-mongo Authors All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package mongo
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/cybergarage/go-mongo/mongo/auth"
+	"github.com/cybergarage/go-mongo/mongo/bson"
+	"github.com/google/uuid"
+)
+
+// Operation represents an in-flight request of a connection.
+type Operation struct {
+	id       int32
+	conn     *Conn
+	doc      bson.Document
+	ts       time.Time
+	ctx      context.Context
+	cancel   context.CancelCauseFunc
+	stop     context.CancelFunc
+	mutex    sync.RWMutex
+	cmdName  string
+	ns       string
+	userName *auth.UserName
+}
+
+// newOperation returns a new operation of the specified request document. The context of the operation is cancelled
+// when the connection is closed, the time limit expires or the operation is killed.
+func newOperation(id int32, conn *Conn, doc bson.Document, maxTime time.Duration) *Operation {
+	ctx := conn.context()
+	stop := context.CancelFunc(func() {})
+	if 0 < maxTime {
+		ctx, stop = context.WithTimeoutCause(ctx, maxTime, NewMaxTimeMSExpiredError())
+	}
+	ctx, cancel := context.WithCancelCause(ctx)
+	return &Operation{
+		id:       id,
+		conn:     conn,
+		doc:      doc,
+		ts:       time.Now(),
+		ctx:      ctx,
+		cancel:   cancel,
+		stop:     stop,
+		mutex:    sync.RWMutex{},
+		cmdName:  "",
+		ns:       "",
+		userName: nil,
+	}
+}
+
+// ID returns the operation identifier.
+func (op *Operation) ID() int32 {
+	return op.id
+}
+
+// Conn returns the connection of the operation.
+func (op *Operation) Conn() *Conn {
+	return op.conn
+}
+
+// ConnUUID returns the UUID of the connection of the operation.
+func (op *Operation) ConnUUID() uuid.UUID {
+	return op.conn.UUID()
+}
+
+// ClientAddress returns the remote address of the connection of the operation.
+func (op *Operation) ClientAddress() string {
+	return op.conn.RemoteAddr().String()
+}
+
+// setCommand sets the command name, namespace and authenticated user of the dispatched operation.
+func (op *Operation) setCommand(name string, ns string, userName *auth.UserName) {
+	op.mutex.Lock()
+	defer op.mutex.Unlock()
+	op.cmdName = name
+	op.ns = ns
+	op.userName = userName
+}
+
+// CommandName returns the command name of the operation, and returns an empty string until the operation is dispatched.
+func (op *Operation) CommandName() string {
+	op.mutex.RLock()
+	defer op.mutex.RUnlock()
+	return op.cmdName
+}
+
+// Namespace returns the namespace of the operation, and returns an empty string until the operation is dispatched.
+func (op *Operation) Namespace() string {
+	op.mutex.RLock()
+	defer op.mutex.RUnlock()
+	return op.ns
+}
+
+// UserName returns the authenticated user of the operation.
+func (op *Operation) UserName() (auth.UserName, bool) {
+	op.mutex.RLock()
+	defer op.mutex.RUnlock()
+	if op.userName == nil {
+		return auth.UserName{}, false
+	}
+	return *op.userName, true
+}
+
+// Document returns the request document of the operation, and returns nil for legacy operations such as OP_INSERT.
+func (op *Operation) Document() bson.Document {
+	return op.doc
+}
+
+// Timestamp returns the start time of the operation.
+func (op *Operation) Timestamp() time.Time {
+	return op.ts
+}
+
+// Elapsed returns the elapsed time since the operation started.
+func (op *Operation) Elapsed() time.Duration {
+	return time.Since(op.ts)
+}
+
+// Context returns the context of the operation.
+func (op *Operation) Context() context.Context {
+	return op.ctx
+}
+
+// Err returns the server error of the cancelled operation, and returns nil if the operation is not cancelled.
+func (op *Operation) Err() error {
+	if op.ctx.Err() == nil {
+		return nil
+	}
+	return context.Cause(op.ctx)
+}
+
+// causeOf returns the server error of the cancelled operation if the specified error is the context error, and returns the error as it is otherwise.
+func (op *Operation) causeOf(err error) error {
+	if err == nil || op.ctx.Err() == nil {
+		return err
+	}
+	cause := context.Cause(op.ctx)
+	if !errors.Is(err, op.ctx.Err()) && !errors.Is(err, cause) {
+		return err
+	}
+	return cause
+}
+
+// Kill cancels the context of the operation as interrupted.
+func (op *Operation) Kill() {
+	op.cancel(NewInterruptedError())
+}
+
+// finish releases the context of the finished operation.
+func (op *Operation) finish() {
+	op.cancel(context.Canceled)
+	op.stop()
+}
@@ -0,0 +1,96 @@
+// Copyright (C) 2022 The go-mongo Authors All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package mongo
+
+import (
+	"cmp"
+	"math"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/cybergarage/go-mongo/mongo/bson"
+)
+
+// OperationManager represents an in-flight operation map.
+type OperationManager struct {
+	m      map[int32]*Operation
+	mutex  *sync.RWMutex
+	lastID int32
+}
+
+// NewOperationManager returns an in-flight operation map.
+func NewOperationManager() *OperationManager {
+	return &OperationManager{
+		m:      map[int32]*Operation{},
+		mutex:  &sync.RWMutex{},
+		lastID: 0,
+	}
+}
+
+// startOperation adds a new operation of the specified request document to the connection.
+func (mgr *OperationManager) startOperation(conn *Conn, doc bson.Document, maxTime time.Duration) *Operation {
+	mgr.mutex.Lock()
+	defer mgr.mutex.Unlock()
+	mgr.lastID++
+	if mgr.lastID == math.MaxInt32 {
+		mgr.lastID = 1
+	}
+	op := newOperation(mgr.lastID, conn, doc, maxTime)
+	mgr.m[op.ID()] = op
+	conn.setOperation(op)
+	return op
+}
+
+// finishOperation removes the specified operation from the connection.
+func (mgr *OperationManager) finishOperation(op *Operation) {
+	mgr.mutex.Lock()
+	defer mgr.mutex.Unlock()
+	delete(mgr.m, op.ID())
+	op.conn.setOperation(nil)
+	op.finish()
+}
+
+// Operations returns the in-flight operations in the order of the identifiers.
+func (mgr *OperationManager) Operations() []*Operation {
+	mgr.mutex.RLock()
+	defer mgr.mutex.RUnlock()
+	ops := make([]*Operation, 0, len(mgr.m))
+	for _, op := range mgr.m {
+		ops = append(ops, op)
+	}
+	slices.SortFunc(ops, func(a, b *Operation) int {
+		return cmp.Compare(a.ID(), b.ID())
+	})
+	return ops
+}
+
+// OperationByID returns the in-flight operation with the specified identifier.
+func (mgr *OperationManager) OperationByID(id int32) (*Operation, bool) {
+	mgr.mutex.RLock()
+	defer mgr.mutex.RUnlock()
+	op, ok := mgr.m[id]
+	return op, ok
+}
+
+// KillOperation kills the in-flight operation with the specified identifier, and returns false if the operation is not found.
+func (mgr *OperationManager) KillOperation(id int32) bool {
+	op, ok := mgr.OperationByID(id)
+	if !ok {
+		return false
+	}
+	op.Kill()
+	return true
+}
@@ -0,0 +1,140 @@
+// Copyright (C) 2019 The go-mongo Authors. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package protocol
+
+import (
+	"fmt"
+
+	"github.com/cybergarage/go-mongo/mongo/bson"
+)
+
+// Compressed represents a OP_COMPRESSED of MongoDB wire protocol.
+// See : MongoDB Wire Protocol
+// https://docs.mongodb.com/manual/reference/mongodb-wire-protocol/
+type Compressed struct {
+	*Header // A standard wire protocol header
+
+	OriginalOpCode   OpCode     // value of wrapped opcode
+	UncompressedSize int32      // size of deflated compressedMessage, excluding MsgHeader
+	CompressorID     Compressor // ID of compressor that compressed message
+	compressedBytes  []byte     // opcode itself, excluding MsgHeader
+}
+
+// NewCompressedWithMessage returns a new compressed instance of the specified message.
+func NewCompressedWithMessage(msg Message, c Compressor) (*Compressed, error) {
+	msgBytes := msg.Bytes()
+	if len(msgBytes) < HeaderSize {
+		return nil, newErrMessageRequest(OpCompressed, msgBytes)
+	}
+	uncompressedBytes := msgBytes[HeaderSize:]
+	compressedBytes, err := CompressBytes(c, uncompressedBytes)
+	if err != nil {
+		return nil, err
+	}
+
+	op := &Compressed{
+		Header:           NewHeaderWithOpCode(OpCompressed),
+		OriginalOpCode:   msg.OpCode(),
+		UncompressedSize: int32(len(uncompressedBytes)),
+		CompressorID:     c,
+		compressedBytes:  compressedBytes,
+	}
+	op.SetRequestID(msg.RequestID())
+	op.SetResponseTo(msg.ResponseTo())
+	op.SetMessageLength(op.Size())
+
+	return op, nil
+}
+
+// NewCompressedWithHeaderAndBody returns a new compressed instance with the specified bytes.
+func NewCompressedWithHeaderAndBody(header *Header, body []byte) (*Compressed, error) {
+	originalOpCode, offsetBody, ok := ReadInt32(body)
+	if !ok {
+		return nil, newErrMessageRequest(OpCompressed, body)
+	}
+
+	uncompressedSize, offsetBody, ok := ReadInt32(offsetBody)
+	if !ok || uncompressedSize < 0 {
+		return nil, newErrMessageRequest(OpCompressed, body)
+	}
+
+	if len(offsetBody) < 1 {
+		return nil, newErrMessageRequest(OpCompressed, body)
+	}
+	compressorID := Compressor(offsetBody[0])
+
+	op := &Compressed{
+		Header:           header,
+		OriginalOpCode:   OpCode(originalOpCode),
+		UncompressedSize: uncompressedSize,
+		CompressorID:     compressorID,
+		compressedBytes:  offsetBody[1:],
+	}
+
+	return op, nil
+}
+
+// Decompress returns the original message of the compressed message.
+func (op *Compressed) Decompress() (Message, error) {
+	if op.OriginalOpCode == OpCompressed {
+		return nil, newErrMessageRequest(OpCompressed, op.compressedBytes)
+	}
+
+	body, err := DecompressBytes(op.CompressorID, op.compressedBytes, op.UncompressedSize)
+	if err != nil {
+		return nil, err
+	}
+	if len(body) != int(op.UncompressedSize) {
+		return nil, newErrMessageRequest(OpCompressed, op.compressedBytes)
+	}
+
+	header := NewHeaderWithOpCode(op.OriginalOpCode)
+	header.SetMessageLength(int32(HeaderSize + len(body)))
+	header.SetRequestID(op.RequestID())
+	header.SetResponseTo(op.ResponseTo())
+
+	return NewMessageWithHeaderAndBytes(header, body)
+}
+
+// Documents returns the BSON documents.
+func (op *Compressed) Documents() []bson.Document {
+	return []bson.Document{}
+}
+
+// Size returns the message size including the header.
+func (op *Compressed) Size() int32 {
+	bodySize := 4 + 4 + 1 + len(op.compressedBytes)
+	return int32(HeaderSize + bodySize)
+}
+
+// Bytes returns the binary description of BSON format.
+func (op *Compressed) Bytes() []byte {
+	dst := op.Header.Bytes()
+	dst = AppendInt32(dst, int32(op.OriginalOpCode))
+	dst = AppendInt32(dst, op.UncompressedSize)
+	dst = AppendByte(dst, byte(op.CompressorID))
+	dst = append(dst, op.compressedBytes...)
+	return dst
+}
+
+// String returns the string description.
+func (op *Compressed) String() string {
+	return fmt.Sprintf("%s %d %d %d",
+		op.Header.String(),
+		op.OriginalOpCode,
+		op.UncompressedSize,
+		op.CompressorID,
+	)
+}
@@ -0,0 +1,86 @@
+// Copyright (C) 2019 The go-mongo Authors. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package protocol
+
+import (
+	"bytes"
+	"testing"
+
+	"go.mongodb.org/mongo-driver/x/bsonx/bsoncore"
+)
+
+func TestCompressed(t *testing.T) {
+	body := bsoncore.NewDocumentBuilder().
+		AppendString("find", "trainers").
+		AppendString("$db", "test").
+		Build()
+
+	compressors := []string{
+		CompressorNameNoop,
+		CompressorNameSnappy,
+		CompressorNameZlib,
+		CompressorNameZstd,
+	}
+
+	for _, name := range compressors {
+		t.Run(name, func(t *testing.T) {
+			c, ok := NewCompressorWithName(name)
+			if !ok {
+				t.Errorf("%s is not supported", name)
+				return
+			}
+
+			msg := NewMsgWithBody(body)
+			msg.SetRequestID(1)
+			msg.SetResponseTo(2)
+
+			cmsg, err := NewCompressedWithMessage(msg, c)
+			if err != nil {
+				t.Error(err)
+				return
+			}
+
+			parsedMsg, err := NewMessageWithBytes(cmsg.Bytes())
+			if err != nil {
+				t.Error(err)
+				return
+			}
+
+			parsedCmsg, ok := parsedMsg.(*Compressed)
+			if !ok {
+				t.Errorf("%v is not compressed", parsedMsg)
+				return
+			}
+
+			if parsedCmsg.CompressorID != c {
+				t.Errorf("%d != %d", parsedCmsg.CompressorID, c)
+			}
+
+			orgMsg, err := parsedCmsg.Decompress()
+			if err != nil {
+				t.Error(err)
+				return
+			}
+
+			if orgMsg.RequestID() != msg.RequestID() || orgMsg.ResponseTo() != msg.ResponseTo() {
+				t.Errorf("%s != %s", orgMsg.String(), msg.String())
+			}
+
+			if !bytes.Equal(orgMsg.Bytes(), msg.Bytes()) {
+				t.Errorf("%s != %s", orgMsg.String(), msg.String())
+			}
+		})
+	}
+}
@@ -0,0 +1,107 @@
+// Copyright (C) 2019 The go-mongo Authors. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package protocol
+
+import (
+	"compress/zlib"
+
+	"go.mongodb.org/mongo-driver/x/mongo/driver"
+	"go.mongodb.org/mongo-driver/x/mongo/driver/wiremessage"
+)
+
+// See : Wire Compression
+// https://github.com/mongodb/specifications/blob/master/source/compression/OP_COMPRESSED.md
+
+// Compressor represents a compressor identifier of MongoDB wire protocol.
+type Compressor = wiremessage.CompressorID
+
+const (
+	// CompressorNoop (0) represents the noop compressor, the message is not compressed.
+	CompressorNoop Compressor = wiremessage.CompressorNoOp
+	// CompressorSnappy (1) represents the snappy compressor.
+	CompressorSnappy Compressor = wiremessage.CompressorSnappy
+	// CompressorZlib (2) represents the zlib compressor.
+	CompressorZlib Compressor = wiremessage.CompressorZLib
+	// CompressorZstd (3) represents the zstd compressor.
+	CompressorZstd Compressor = wiremessage.CompressorZstd
+)
+
+const (
+	// CompressorNameNoop is the negotiation name of the noop compressor.
+	CompressorNameNoop = "noop"
+	// CompressorNameSnappy is the negotiation name of the snappy compressor.
+	CompressorNameSnappy = "snappy"
+	// CompressorNameZlib is the negotiation name of the zlib compressor.
+	CompressorNameZlib = "zlib"
+	// CompressorNameZstd is the negotiation name of the zstd compressor.
+	CompressorNameZstd = "zstd"
+)
+
+const (
+	defaultZlibLevel = zlib.DefaultCompression
+	defaultZstdLevel = 6
+)
+
+// NewCompressorWithName returns the compressor of the specified negotiation name.
+func NewCompressorWithName(name string) (Compressor, bool) {
+	switch name {
+	case CompressorNameNoop:
+		return CompressorNoop, true
+	case CompressorNameSnappy:
+		return CompressorSnappy, true
+	case CompressorNameZlib:
+		return CompressorZlib, true
+	case CompressorNameZstd:
+		return CompressorZstd, true
+	}
+	return CompressorNoop, false
+}
+
+// CompressorName returns the negotiation name of the specified compressor.
+func CompressorName(c Compressor) string {
+	switch c {
+	case CompressorNoop:
+		return CompressorNameNoop
+	case CompressorSnappy:
+		return CompressorNameSnappy
+	case CompressorZlib:
+		return CompressorNameZlib
+	case CompressorZstd:
+		return CompressorNameZstd
+	}
+	return ""
+}
+
+// CompressBytes compresses the specified bytes with the specified compressor.
+func CompressBytes(c Compressor, src []byte) ([]byte, error) {
+	opts := driver.CompressionOpts{
+		Compressor:       c,
+		ZlibLevel:        defaultZlibLevel,
+		ZstdLevel:        defaultZstdLevel,
+		UncompressedSize: 0,
+	}
+	return driver.CompressPayload(src, opts)
+}
+
+// DecompressBytes decompresses the specified bytes with the specified compressor.
+func DecompressBytes(c Compressor, src []byte, uncompressedSize int32) ([]byte, error) {
+	opts := driver.CompressionOpts{
+		Compressor:       c,
+		ZlibLevel:        defaultZlibLevel,
+		ZstdLevel:        defaultZstdLevel,
+		UncompressedSize: uncompressedSize,
+	}
+	return driver.DecompressPayload(src, opts)
+}
@@ -21,6 +21,8 @@ import (
 
 var ErrNotSupported = errors.New("not supported")
 var ErrInvalid = errors.New("invalid")
+var ErrChecksum = errors.New("checksum mismatch")
+var ErrTooLarge = errors.New("too large")
 
 func newErrOpCodeNotSupported(op OpCode) error {
 	return fmt.Errorf("OpCode (%d)  %w", op, ErrNotSupported)
@@ -33,3 +35,15 @@ func newErrMessageRequest(op OpCode, body []byte) error {
 func newErrMessageHeader(s string) error {
 	return fmt.Errorf("%w message header : %s", ErrInvalid, s)
 }
+
+func newErrMessageChecksum(op OpCode, expected uint32, actual uint32) error {
+	return fmt.Errorf("OpCode (%d)  %w : %08X != %08X", op, ErrChecksum, actual, expected)
+}
+
+func newErrMessageSize(size int32, maxSize int32) error {
+	return fmt.Errorf("message size %w : %d > %d", ErrTooLarge, size, maxSize)
+}
+
+func newErrDocumentSize(op OpCode, size int, maxSize int32) error {
+	return fmt.Errorf("OpCode (%d)  document size %w : %d > %d", op, ErrTooLarge, size, maxSize)
+}
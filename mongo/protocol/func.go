@@ -35,6 +35,11 @@ func AppendInt32(dst []byte, val int32) []byte {
 	return bsoncore.AppendInt32(dst, val)
 }
 
+// AppendUint32 appends the uint32 value to the buffer.
+func AppendUint32(dst []byte, val uint32) []byte {
+	return append(dst, byte(val), byte(val>>8), byte(val>>16), byte(val>>24))
+}
+
 // AppendInt64 appends the int64 value to the buffer.
 func AppendInt64(dst []byte, val int64) []byte {
 	return bsoncore.AppendInt64(dst, val)
@@ -81,8 +86,13 @@ func ReadCString(src []byte) (string, []byte, bool) {
 // ReadCursorIDs reads numIDs cursor IDs from src.
 func ReadCursorIDs(src []byte, numIDs int32) ([]int64, []byte, bool) {
 	cursorIDs := []int64{}
+	if numIDs < 0 || (len(src)/8) < int(numIDs) {
+		return cursorIDs, src, false
+	}
 	for range numIDs {
-		id, src, ok := ReadInt64(src)
+		var id int64
+		var ok bool
+		id, src, ok = ReadInt64(src)
 		if !ok {
 			return cursorIDs, src, false
 		}
@@ -122,7 +132,7 @@ func ReadDocuments(src []byte) ([]bson.Document, []byte, bool) {
 // ReadDocumentSequence reads an identifier and document sequence from src.
 func ReadDocumentSequence(src []byte) (string, []bsoncore.Document, []byte, bool) {
 	length, rem, ok := ReadInt32(src)
-	if !ok || int(length) > len(src) {
+	if !ok || length < 4 || int(length) > len(src) {
 		return "", nil, rem, false
 	}
 
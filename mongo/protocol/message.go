@@ -73,6 +73,8 @@ func NewMessageWithHeaderAndBytes(header *Header, body []byte) (Message, error)
 		return NewMsgWithHeaderAndBody(header, body)
 	case OpReply:
 		return NewReplyWithHeaderAndBody(header, body)
+	case OpCompressed:
+		return NewCompressedWithHeaderAndBody(header, body)
 	default:
 	}
 	return nil, newErrOpCodeNotSupported(header.opCode)
@@ -16,6 +16,7 @@ package protocol
 
 import (
 	"fmt"
+	"hash/crc32"
 
 	"github.com/cybergarage/go-mongo/mongo/bson"
 	"go.mongodb.org/mongo-driver/x/mongo/driver/wiremessage"
@@ -24,11 +25,13 @@ import (
 const (
 	checksumPresent             = MsgFlag(0x01)
 	moreToCome                  = MsgFlag(0x02)
-	exhaustAllowed              = MsgFlag(0xF0)
+	exhaustAllowed              = MsgFlag(1 << 16)
 	sectionTypeBody             = SectionType(0)
 	sectionTypeDocumentSequence = SectionType(1)
 )
 
+var crc32cTable = crc32.MakeTable(crc32.Castagnoli)
+
 // MsgFlag represents a MsgFlag of MongoDB wire protocol.
 type MsgFlag = wiremessage.MsgFlag
 
@@ -77,6 +80,22 @@ func NewMsgWithHeaderAndBody(header *Header, body []byte) (*Msg, error) {
 		return nil, newErrMessageRequest(OpMsg, body)
 	}
 
+	// The checksum is the last four bytes, and covers all bytes of the message before it.
+
+	checksum := uint32(0)
+	if (MsgFlag(flagBits) & checksumPresent) != 0 {
+		if len(offsetBody) < 4 {
+			return nil, newErrMessageRequest(OpMsg, body)
+		}
+		checksumOffset := len(body) - 4
+		checksum, _, _ = ReadUint32(body[checksumOffset:])
+		expectedChecksum := msgChecksum(header.Bytes(), body[:checksumOffset])
+		if checksum != expectedChecksum {
+			return nil, newErrMessageChecksum(OpMsg, expectedChecksum, checksum)
+		}
+		offsetBody = offsetBody[:len(offsetBody)-4]
+	}
+
 	var docBody bson.Document
 	documentIDs := make([]string, 0)
 	documents := make([]bson.Document, 0)
@@ -103,17 +122,15 @@ func NewMsgWithHeaderAndBody(header *Header, body []byte) (*Msg, error) {
 			}
 			documentIDs = append(documentIDs, docID)
 			documents = append(documents, docs...)
+		default:
+			return nil, newErrMessageRequest(OpMsg, body)
 		}
 		offsetBodyLen = len(offsetBody)
 	}
 
-	checksum := uint32(0)
-	if (MsgFlag(flagBits) & checksumPresent) != 0 {
-		checksum, _, ok = ReadUint32(offsetBody)
-		if !ok {
-			return nil, newErrMessageRequest(OpMsg, body)
-		}
-		// TODO : Check the CRC-32C checksum
+	// A message must contain the body section.
+	if docBody == nil {
+		return nil, newErrMessageRequest(OpMsg, body)
 	}
 
 	op := &Msg{
@@ -146,6 +163,49 @@ func (op *Msg) AddDocuments(docIDs []string, docs []bson.Document) {
 	op.documents = append(op.documents, docs...)
 }
 
+// SetChecksumPresent sets the checksumPresent flag to add a CRC-32C checksum to the message.
+func (op *Msg) SetChecksumPresent(enabled bool) {
+	if enabled {
+		op.FlagBits |= checksumPresent
+	} else {
+		op.FlagBits &^= checksumPresent
+	}
+	op.SetMessageLength(op.Size())
+}
+
+// IsChecksumPresent returns true if the checksumPresent flag is set.
+func (op *Msg) IsChecksumPresent() bool {
+	return (op.FlagBits & checksumPresent) != 0
+}
+
+// SetMoreToCome sets the moreToCome flag which indicates that the receiver must not reply.
+func (op *Msg) SetMoreToCome(enabled bool) {
+	if enabled {
+		op.FlagBits |= moreToCome
+	} else {
+		op.FlagBits &^= moreToCome
+	}
+}
+
+// IsMoreToCome returns true if the moreToCome flag is set.
+func (op *Msg) IsMoreToCome() bool {
+	return (op.FlagBits & moreToCome) != 0
+}
+
+// SetExhaustAllowed sets the exhaustAllowed flag which indicates that the client is prepared for multiple replies.
+func (op *Msg) SetExhaustAllowed(enabled bool) {
+	if enabled {
+		op.FlagBits |= exhaustAllowed
+	} else {
+		op.FlagBits &^= exhaustAllowed
+	}
+}
+
+// IsExhaustAllowed returns true if the exhaustAllowed flag is set.
+func (op *Msg) IsExhaustAllowed() bool {
+	return (op.FlagBits & exhaustAllowed) != 0
+}
+
 // Body returns the body document.
 func (op *Msg) Body() bson.Document {
 	return op.body
@@ -184,9 +244,10 @@ func (op *Msg) Bytes() []byte {
 		dst = AppendCString(dst, op.documentIDs[n])
 		dst = AppendDocument(dst, doc)
 	}
-	// if (op.FlagBits & checksumPresent) != 0 {
-	// 	// TODO : Add a CRC-32C checksum.
-	// }
+	if (op.FlagBits & checksumPresent) != 0 {
+		op.Checksum = msgChecksum(dst)
+		dst = AppendUint32(dst, op.Checksum)
+	}
 	return dst
 }
 
@@ -210,3 +271,12 @@ func (op *Msg) String() string {
 
 	return str
 }
+
+// msgChecksum returns the CRC-32C checksum of the specified bytes.
+func msgChecksum(msgBytes ...[]byte) uint32 {
+	checksum := uint32(0)
+	for _, b := range msgBytes {
+		checksum = crc32.Update(checksum, crc32cTable, b)
+	}
+	return checksum
+}
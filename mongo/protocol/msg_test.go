@@ -14,7 +14,79 @@
 
 package protocol
 
-import "testing"
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"testing"
+
+	"go.mongodb.org/mongo-driver/x/bsonx/bsoncore"
+)
 
 func TestMsg(t *testing.T) {
 }
+
+func TestMsgChecksum(t *testing.T) {
+	body := bsoncore.NewDocumentBuilder().
+		AppendInt32("ping", 1).
+		AppendString("$db", "admin").
+		Build()
+
+	newMsgBytes := func() []byte {
+		msg := NewMsgWithBody(body)
+		msg.SetRequestID(1)
+		msg.SetChecksumPresent(true)
+		return msg.Bytes()
+	}
+
+	t.Run("valid", func(t *testing.T) {
+		msgBytes := newMsgBytes()
+		msg, err := NewMessageWithBytes(msgBytes)
+		if err != nil {
+			t.Error(err)
+			return
+		}
+		opMsg, ok := msg.(*Msg)
+		if !ok {
+			t.Errorf("%v is not OP_MSG", msg)
+			return
+		}
+		if !opMsg.IsChecksumPresent() {
+			t.Errorf("checksumPresent is not set : %s", opMsg.String())
+		}
+		if !bytes.Equal(opMsg.Body(), body) {
+			t.Errorf("%s != %s", opMsg.Body().String(), body.String())
+		}
+		if !bytes.Equal(opMsg.Bytes(), msgBytes) {
+			t.Errorf("%X != %X", opMsg.Bytes(), msgBytes)
+		}
+	})
+
+	t.Run("corrupted", func(t *testing.T) {
+		msgBytes := newMsgBytes()
+		msgBytes[len(msgBytes)-1] ^= 0xFF
+		_, err := NewMessageWithBytes(msgBytes)
+		if !errors.Is(err, ErrChecksum) {
+			t.Errorf("corrupted checksum is accepted : %v", err)
+		}
+	})
+
+	t.Run("corrupted body", func(t *testing.T) {
+		msgBytes := newMsgBytes()
+		msgBytes[HeaderSize+4+1+4+1] ^= 0xFF
+		_, err := NewMessageWithBytes(msgBytes)
+		if !errors.Is(err, ErrChecksum) {
+			t.Errorf("corrupted body is accepted : %v", err)
+		}
+	})
+
+	t.Run("missing", func(t *testing.T) {
+		msgBytes := newMsgBytes()
+		msgBytes = msgBytes[:len(msgBytes)-4]
+		binary.LittleEndian.PutUint32(msgBytes, uint32(len(msgBytes)))
+		_, err := NewMessageWithBytes(msgBytes)
+		if err == nil {
+			t.Errorf("missing checksum is accepted")
+		}
+	})
+}
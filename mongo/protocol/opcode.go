@@ -40,6 +40,8 @@ const (
 	OpCommand OpCode = wiremessage.OpCommand
 	// OpCommandReply (OP_COMMANDREPLY:2011) clusters internal protocol representing a reply to an OP_COMMAND.
 	OpCommandReply OpCode = wiremessage.OpCommandReply
+	// OpCompressed (OP_COMPRESSED:2012) wraps other opcodes using compression.
+	OpCompressed OpCode = wiremessage.OpCompressed
 	// OpMsg (OP_MSG:2013) sends a message using the format introduced in MongoDB 3.6.
 	OpMsg OpCode = wiremessage.OpMsg
 )
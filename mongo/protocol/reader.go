@@ -0,0 +1,125 @@
+// Copyright (C) 2019 The go-mongo Authors. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package protocol
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"math"
+
+	"github.com/cybergarage/go-mongo/mongo/bson"
+)
+
+// Reader reads complete messages of MongoDB wire protocol from a stream.
+type Reader struct {
+	io.Reader
+	maxMessageSize    int32
+	maxBsonObjectSize int32
+}
+
+// NewReaderWith returns a new message reader of the specified stream.
+func NewReaderWith(r io.Reader) *Reader {
+	return &Reader{
+		Reader:            r,
+		maxMessageSize:    math.MaxInt32,
+		maxBsonObjectSize: math.MaxInt32,
+	}
+}
+
+// SetMaxMessageSize sets the max limitation of the message size including the header.
+func (reader *Reader) SetMaxMessageSize(size int32) {
+	reader.maxMessageSize = size
+}
+
+// MaxMessageSize returns the max limitation of the message size including the header.
+func (reader *Reader) MaxMessageSize() int32 {
+	return reader.maxMessageSize
+}
+
+// SetMaxBsonObjectSize sets the max limitation of the BSON document size in the message.
+func (reader *Reader) SetMaxBsonObjectSize(size int32) {
+	reader.maxBsonObjectSize = size
+}
+
+// MaxBsonObjectSize returns the max limitation of the BSON document size in the message.
+func (reader *Reader) MaxBsonObjectSize() int32 {
+	return reader.maxBsonObjectSize
+}
+
+// ReadMessage reads a complete message from the stream, and returns the message decompressed if compressed.
+// ReadMessage returns io.EOF only if the stream is closed before a new message.
+func (reader *Reader) ReadMessage() (Message, error) {
+	headerBytes := make([]byte, HeaderSize)
+	_, err := io.ReadFull(reader.Reader, headerBytes)
+	if err != nil {
+		return nil, err
+	}
+
+	header, err := NewHeaderWithBytes(headerBytes)
+	if err != nil {
+		return nil, err
+	}
+
+	msgSize := header.MessageLength()
+	if msgSize < HeaderSize {
+		return nil, newErrMessageHeader(fmt.Sprintf("message length (%d)", msgSize))
+	}
+	if reader.maxMessageSize < msgSize {
+		return nil, newErrMessageSize(msgSize, reader.maxMessageSize)
+	}
+
+	bodyBytes := make([]byte, header.BodySize())
+	_, err = io.ReadFull(reader.Reader, bodyBytes)
+	if err != nil {
+		if errors.Is(err, io.EOF) {
+			return nil, io.ErrUnexpectedEOF
+		}
+		return nil, err
+	}
+
+	msg, err := NewMessageWithHeaderAndBytes(header, bodyBytes)
+	if err != nil {
+		return nil, err
+	}
+
+	if cmsg, ok := msg.(*Compressed); ok {
+		uncompressedSize := int64(HeaderSize) + int64(cmsg.UncompressedSize)
+		if int64(reader.maxMessageSize) < uncompressedSize {
+			return nil, newErrMessageSize(int32(min(uncompressedSize, math.MaxInt32)), reader.maxMessageSize)
+		}
+		msg, err = cmsg.Decompress()
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	for _, doc := range messageDocuments(msg) {
+		if reader.maxBsonObjectSize < int32(min(len(doc), math.MaxInt32)) {
+			return nil, newErrDocumentSize(msg.OpCode(), len(doc), reader.maxBsonObjectSize)
+		}
+	}
+
+	return msg, nil
+}
+
+// messageDocuments returns all documents of the specified message including the body document of OP_MSG.
+func messageDocuments(msg Message) []bson.Document {
+	opMsg, ok := msg.(*Msg)
+	if !ok {
+		return msg.Documents()
+	}
+	return append([]bson.Document{opMsg.Body()}, opMsg.Documents()...)
+}
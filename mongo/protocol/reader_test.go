@@ -0,0 +1,203 @@
+// Copyright (C) 2019 The go-mongo Authors. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package protocol
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"io"
+	"net"
+	"testing"
+
+	"go.mongodb.org/mongo-driver/x/bsonx/bsoncore"
+)
+
+func newTestReaderMessage() *Msg {
+	body := bsoncore.NewDocumentBuilder().
+		AppendString("find", "trainers").
+		AppendString("$db", "test").
+		Build()
+	msg := NewMsgWithBody(body)
+	msg.SetRequestID(1)
+	return msg
+}
+
+func readPipeMessages(t *testing.T, write func(conn net.Conn), config func(reader *Reader)) ([]Message, error) {
+	t.Helper()
+	client, server := net.Pipe()
+	defer server.Close()
+
+	go func() {
+		defer client.Close()
+		write(client)
+	}()
+
+	reader := NewReaderWith(server)
+	if config != nil {
+		config(reader)
+	}
+
+	msgs := []Message{}
+	for {
+		msg, err := reader.ReadMessage()
+		if err != nil {
+			return msgs, err
+		}
+		msgs = append(msgs, msg)
+	}
+}
+
+func TestReaderFragmented(t *testing.T) {
+	msg := newTestReaderMessage()
+	msgBytes := msg.Bytes()
+
+	t.Run("byte by byte", func(t *testing.T) {
+		msgs, err := readPipeMessages(t, func(conn net.Conn) {
+			for n := range msgBytes {
+				if _, err := conn.Write(msgBytes[n : n+1]); err != nil {
+					return
+				}
+			}
+		}, nil)
+		if !errors.Is(err, io.EOF) {
+			t.Error(err)
+		}
+		if len(msgs) != 1 || !bytes.Equal(msgs[0].Bytes(), msgBytes) {
+			t.Errorf("%v != %s", msgs, msg.String())
+		}
+	})
+
+	t.Run("back to back", func(t *testing.T) {
+		msgs, err := readPipeMessages(t, func(conn net.Conn) {
+			_, _ = conn.Write(append(append([]byte{}, msgBytes...), msgBytes...))
+		}, nil)
+		if !errors.Is(err, io.EOF) {
+			t.Error(err)
+		}
+		if len(msgs) != 2 {
+			t.Errorf("%d != %d", len(msgs), 2)
+		}
+	})
+}
+
+func TestReaderHostile(t *testing.T) {
+	msgBytes := newTestReaderMessage().Bytes()
+
+	withLength := func(n int32) []byte {
+		b := append([]byte{}, msgBytes...)
+		binary.LittleEndian.PutUint32(b, uint32(n))
+		return b
+	}
+
+	withOpCode := func(op OpCode) []byte {
+		b := append([]byte{}, msgBytes...)
+		binary.LittleEndian.PutUint32(b[12:], uint32(op))
+		return b
+	}
+
+	withBody := func(op OpCode, body []byte) []byte {
+		header := NewHeaderWithOpCode(op)
+		header.SetMessageLength(int32(HeaderSize + len(body)))
+		return append(header.Bytes(), body...)
+	}
+
+	cmsg, err := NewCompressedWithMessage(newTestReaderMessage(), CompressorZlib)
+	if err != nil {
+		t.Fatal(err)
+	}
+	bombBytes := cmsg.Bytes()
+	binary.LittleEndian.PutUint32(bombBytes[HeaderSize+4:], uint32(1<<30))
+
+	tests := []struct {
+		name   string
+		stream []byte
+		config func(reader *Reader)
+		err    error
+	}{
+		{
+			name:   "truncated header",
+			stream: msgBytes[:HeaderSize-1],
+			err:    io.ErrUnexpectedEOF,
+		},
+		{
+			name:   "truncated body",
+			stream: msgBytes[:len(msgBytes)-1],
+			err:    io.ErrUnexpectedEOF,
+		},
+		{
+			name:   "negative length",
+			stream: withLength(-1),
+			err:    ErrInvalid,
+		},
+		{
+			name:   "short length",
+			stream: withLength(HeaderSize - 1),
+			err:    ErrInvalid,
+		},
+		{
+			name:   "oversized message",
+			stream: msgBytes,
+			config: func(reader *Reader) { reader.SetMaxMessageSize(int32(len(msgBytes) - 1)) },
+			err:    ErrTooLarge,
+		},
+		{
+			name:   "oversized document",
+			stream: msgBytes,
+			config: func(reader *Reader) { reader.SetMaxBsonObjectSize(8) },
+			err:    ErrTooLarge,
+		},
+		{
+			name:   "oversized uncompressed message",
+			stream: bombBytes,
+			config: func(reader *Reader) { reader.SetMaxMessageSize(1024) },
+			err:    ErrTooLarge,
+		},
+		{
+			name:   "unknown opcode",
+			stream: withOpCode(9999),
+			err:    ErrNotSupported,
+		},
+		{
+			name:   "negative document sequence length",
+			stream: withBody(OpMsg, []byte{0x00, 0x00, 0x00, 0x00, 0x01, 0xFF, 0xFF, 0xFF, 0xFF}),
+			err:    ErrInvalid,
+		},
+		{
+			name:   "hostile cursor id count",
+			stream: withBody(OpKillCursors, []byte{0x00, 0x00, 0x00, 0x00, 0xFF, 0xFF, 0xFF, 0x7F, 0x01, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00}),
+			err:    ErrInvalid,
+		},
+		{
+			name:   "garbage body",
+			stream: append(append(msgBytes[:HeaderSize:HeaderSize], 0x00, 0x00, 0x00, 0x00), bytes.Repeat([]byte{0xFF}, len(msgBytes)-HeaderSize-4)...),
+			err:    ErrInvalid,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			msgs, err := readPipeMessages(t, func(conn net.Conn) {
+				_, _ = conn.Write(test.stream)
+			}, test.config)
+			if len(msgs) != 0 {
+				t.Errorf("%v", msgs)
+			}
+			if !errors.Is(err, test.err) {
+				t.Errorf("%v != %v", err, test.err)
+			}
+		})
+	}
+}
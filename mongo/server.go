@@ -15,6 +15,9 @@
 package mongo
 
 import (
+	"context"
+	"net"
+
 	"github.com/cybergarage/go-mongo/mongo/auth"
 	"github.com/cybergarage/go-mongo/mongo/protocol"
 	"github.com/cybergarage/go-tracing/tracer"
@@ -25,6 +28,12 @@ type MessageListener interface {
 	protocol.MessageListener
 }
 
+// HandshakeListener represents a listener for TLS handshakes.
+type HandshakeListener interface {
+	// HandshakeFailed is called after the connection is closed when the TLS handshake or the certificate verification fails.
+	HandshakeFailed(conn net.Conn, err error)
+}
+
 // Server represents a server interface.
 type Server interface {
 	Config
@@ -38,6 +47,8 @@ type Server interface {
 
 	// SetMessageListener sets a message listener.
 	SetMessageListener(l MessageListener)
+	// SetHandshakeListener sets a listener for TLS handshakes.
+	SetHandshakeListener(l HandshakeListener)
 	// SetMessageHandler sets a message handler.
 	SetMessageHandler(h OpMessageHandler)
 	// SetUserCommandExecutor sets a command exector for database operation commands.
@@ -46,11 +57,51 @@ type Server interface {
 	SetDatabaseCommandExecutor(fn DatabaseCommandExecutor)
 	// SetAuthCommandExecutor  sets a command exector for auth operation commands.
 	SetAuthCommandExecutor(fn AuthCommandExecutor)
+	// SetUserManagementCommandExecutor sets a command exector for user and role management commands.
+	SetUserManagementCommandExecutor(fn UserManagementCommandExecutor)
+	// AccessManager returns the role-based access control manager.
+	AccessManager() auth.AccessManager
+	// ReloadTLSConfig reloads the key, certificate and root certificate files without interrupting existing connections.
+	ReloadTLSConfig() error
+
+	// RegisterCommand registers the specified command, and replaces the command of the same name.
+	RegisterCommand(spec *CommandSpec)
+	// UnregisterCommand removes the command of the specified name.
+	UnregisterCommand(name string)
+	// LookupCommand returns the command of the specified name case-insensitively.
+	LookupCommand(name string) (*CommandSpec, bool)
+	// Commands returns the registered commands in the order of the names.
+	Commands() []*CommandSpec
+
+	// AddInterceptor appends the specified interceptor which wraps the handling of the commands and queries.
+	AddInterceptor(i Interceptor)
+	// Interceptors returns the interceptors in the registration order.
+	Interceptors() []Interceptor
+
+	// NumConns returns the current number of connections.
+	NumConns() int
+	// PeakNumConns returns the peak number of connections.
+	PeakNumConns() int
+
+	// Cursors returns the open cursors.
+	Cursors() []*Cursor
+
+	// Operations returns the in-flight operations in the order of the identifiers.
+	Operations() []*Operation
+	// KillOperation kills the in-flight operation with the specified identifier, and returns false if the operation is not found.
+	KillOperation(id int32) bool
+
+	// Serve accepts connections on the specified listener, and serves them until the listener is closed.
+	Serve(l net.Listener) error
+	// ServeConn serves the specified connection until it is closed.
+	ServeConn(conn net.Conn) error
 
 	// Start starts a server.
 	Start() error
 	// Stop stops a server.
 	Stop() error
+	// Shutdown stops a server gracefully after the in-flight requests have finished or the context is done.
+	Shutdown(ctx context.Context) (*ShutdownResult, error)
 	// Restart restarts a server.
 	Restart() error
 }
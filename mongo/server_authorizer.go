@@ -0,0 +1,210 @@
+// Copyright (C) 2019 The go-mongo Authors. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package mongo
+
+import (
+	"strings"
+
+	"github.com/cybergarage/go-mongo/mongo/auth"
+	"github.com/cybergarage/go-mongo/mongo/message"
+)
+
+// commandActions represents the privilege actions of the commands which are checked with the database resource.
+var commandActions = map[string]auth.Action{
+	message.CreateUser:          auth.ActionCreateUser,
+	message.UpdateUser:          auth.ActionChangePassword,
+	message.DropUser:            auth.ActionDropUser,
+	message.UsersInfo:           auth.ActionViewUser,
+	message.CreateRole:          auth.ActionCreateRole,
+	message.GrantRolesToUser:    auth.ActionGrantRole,
+	message.RevokeRolesFromUser: auth.ActionRevokeRole,
+	message.RolesInfo:           auth.ActionViewRole,
+	message.KillOp:              auth.ActionKillOp,
+	message.CurrentOp:           auth.ActionInprog,
+	message.DropDatabase:        auth.ActionDropDatabase,
+	message.ListCollections:     auth.ActionListCollections,
+	message.DBStats:             auth.ActionDBStats,
+}
+
+// roleGrantingCommands represents the commands which grant the roles of the 'roles' element, and require the grantRole
+// privilege on the database of each granted role.
+var roleGrantingCommands = map[string]bool{
+	message.CreateUser:       true,
+	message.UpdateUser:       true,
+	message.CreateRole:       true,
+	message.GrantRolesToUser: true,
+}
+
+// collectionCommandActions represents the privilege actions of the commands which are checked with the collection resource of the command value.
+var collectionCommandActions = map[string][]auth.Action{
+	message.Create:        {auth.ActionCreateCollection},
+	message.Drop:          {auth.ActionDropCollection},
+	message.CreateIndexes: {auth.ActionCreateIndex},
+	message.DropIndexes:   {auth.ActionDropIndex},
+	message.ListIndexes:   {auth.ActionListIndexes},
+	message.CollMod:       {auth.ActionCollMod},
+	message.CollStats:     {auth.ActionCollStats},
+	message.Count:         {auth.ActionFind},
+	message.Distinct:      {auth.ActionFind},
+	message.Aggregate:     {auth.ActionFind},
+	message.FindAndModify: {auth.ActionFind, auth.ActionUpdate, auth.ActionRemove},
+}
+
+// unprivilegedCommands represents the commands which any authenticated user is allowed to execute.
+var unprivilegedCommands = map[string]bool{
+	message.IsMaster:     true,
+	message.Hello:        true,
+	message.BuildInfo:    true,
+	message.GetLastError: true,
+	message.Ping:         true,
+	message.Logout:       true,
+	message.EndSessions:  true,
+	message.ListCommands: true,
+}
+
+// queryActions represents the privilege actions of the queries which are checked with the collection resource.
+var queryActions = map[string]auth.Action{
+	message.Insert:      auth.ActionInsert,
+	message.Update:      auth.ActionUpdate,
+	message.Delete:      auth.ActionRemove,
+	message.Find:        auth.ActionFind,
+	message.GetMore:     auth.ActionFind,
+	message.KillCursors: auth.ActionKillCursors,
+}
+
+// Authorize returns an unauthorized error if the authorization is enabled and the connection is not authenticated yet,
+// or the authenticated user does not have the privilege of the command. Commands which have no privilege action are denied.
+// It returns a reauthentication required error if the authentication of the connection has expired.
+func (server *server) Authorize(conn *Conn, cmd *Command) error {
+	if !server.IsAuthrizationEnabled() {
+		return nil
+	}
+	if !server.isAuthenticationRequired(cmd) {
+		return nil
+	}
+	if !conn.IsAuthrized() {
+		return NewUnauthorizedError(cmd.Type())
+	}
+	if conn.IsAuthenticationExpired() {
+		return NewReauthenticationRequiredError(cmd.Type())
+	}
+	cmdType := cmd.Type()
+	if unprivilegedCommands[cmdType] {
+		return nil
+	}
+	// currentOp with '$ownOps' lists only the operations of the user, and requires no inprog privilege.
+	if cmdType == message.CurrentOp && isOwnOpsCommand(cmd) {
+		return nil
+	}
+	// The queries are checked with the collection resource by AuthorizeQuery.
+	if isQueryCommand(cmdType) {
+		return nil
+	}
+	if action, ok := commandActions[cmdType]; ok {
+		err := server.authorizeAction(conn, cmdType, auth.NewDatabaseResource(cmd.Database()), action)
+		if err != nil {
+			return err
+		}
+		if roleGrantingCommands[cmdType] {
+			return server.authorizeGrantedRoles(conn, cmd)
+		}
+		return nil
+	}
+	if actions, ok := collectionCommandActions[cmdType]; ok {
+		res := auth.NewResource(cmd.Database(), commandCollectionName(cmd))
+		for _, action := range actions {
+			err := server.authorizeAction(conn, cmdType, res, action)
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	return newUnauthorizedActionError(cmd.Database(), cmdType)
+}
+
+// authorizeGrantedRoles returns an unauthorized error if the user does not have the grantRole privilege on the database of any granted role.
+// Invalid roles are not checked here, and are rejected by the command executor.
+func (server *server) authorizeGrantedRoles(conn *Conn, cmd *Command) error {
+	roles, _, err := commandRoleNames(cmd)
+	if err != nil {
+		return nil
+	}
+	for _, role := range roles {
+		err := server.authorizeAction(conn, cmd.Type(), auth.NewDatabaseResource(role.DB), auth.ActionGrantRole)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// isQueryCommand returns true if the lower-cased command type is a query which is checked by AuthorizeQuery.
+func isQueryCommand(cmdType string) bool {
+	for queryType := range queryActions {
+		if strings.EqualFold(queryType, cmdType) {
+			return true
+		}
+	}
+	return false
+}
+
+// commandCollectionName returns the collection name of the first element value of the command.
+func commandCollectionName(cmd *Command) string {
+	if len(cmd.Elements) == 0 {
+		return ""
+	}
+	name, _ := cmd.Elements[0].Value().StringValueOK()
+	return name
+}
+
+// isAuthenticationRequired returns the authentication requirement of the registered command, and true for unregistered commands.
+func (server *server) isAuthenticationRequired(cmd *Command) bool {
+	spec, ok := server.LookupCommand(cmd.Type())
+	if !ok {
+		return true
+	}
+	return spec.IsAuthRequired()
+}
+
+// AuthorizeQuery returns an unauthorized error if the authorization is enabled and the authenticated user does not have the privilege of the query.
+func (server *server) AuthorizeQuery(conn *Conn, q *Query) error {
+	if !server.IsAuthrizationEnabled() {
+		return nil
+	}
+	if !conn.IsAuthrized() {
+		return NewUnauthorizedError(q.Type())
+	}
+	if conn.IsAuthenticationExpired() {
+		return NewReauthenticationRequiredError(q.Type())
+	}
+	action, ok := queryActions[q.Type()]
+	if !ok {
+		return nil
+	}
+	return server.authorizeAction(conn, q.Type(), auth.NewResource(q.Database(), q.Collection()), action)
+}
+
+func (server *server) authorizeAction(conn *Conn, cmdName string, res auth.Resource, action auth.Action) error {
+	userName, ok := conn.UserName()
+	if ok && server.accessManager.IsAllowed(userName, res, action) {
+		return nil
+	}
+	return newUnauthorizedActionError(res.DB, cmdName)
+}
+
+func newUnauthorizedActionError(db string, cmdName string) *Error {
+	return NewErrorf(ErrorCodeUnauthorized, "not authorized on %s to execute command %s", db, cmdName)
+}
@@ -0,0 +1,86 @@
+// Copyright (C) 2022 The go-mongo Authors All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package mongo
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/cybergarage/go-mongo/mongo/auth"
+	"github.com/cybergarage/go-mongo/mongo/message"
+)
+
+func TestAuthorizerCommandActions(t *testing.T) {
+	// The commands are looked up by the lower-cased command types.
+
+	commandTypes := map[string]int{}
+	for cmdType, action := range commandActions {
+		if !action.IsValid() {
+			t.Errorf("%s : %s is not a valid action", cmdType, action)
+		}
+		commandTypes[cmdType]++
+	}
+	for cmdType, actions := range collectionCommandActions {
+		for _, action := range actions {
+			if !action.IsValid() {
+				t.Errorf("%s : %s is not a valid action", cmdType, action)
+			}
+		}
+		commandTypes[cmdType]++
+	}
+	for cmdType := range unprivilegedCommands {
+		commandTypes[cmdType]++
+	}
+	for cmdType, n := range commandTypes {
+		if cmdType != strings.ToLower(cmdType) {
+			t.Errorf("%s is not lower-cased", cmdType)
+		}
+		if n != 1 {
+			t.Errorf("%s is mapped %d times", cmdType, n)
+		}
+		if isQueryCommand(cmdType) {
+			t.Errorf("%s is mapped as a command and a query", cmdType)
+		}
+	}
+
+	// The granted roles are checked after the privilege of the command.
+
+	for cmdType := range roleGrantingCommands {
+		if _, ok := commandActions[cmdType]; !ok {
+			t.Errorf("%s has no privilege action", cmdType)
+		}
+	}
+}
+
+func TestAuthorizerQueryActions(t *testing.T) {
+	for queryType, action := range queryActions {
+		if !action.IsValid() {
+			t.Errorf("%s : %s is not a valid action", queryType, action)
+		}
+		for _, cmdType := range []string{queryType, strings.ToLower(queryType)} {
+			if !isQueryCommand(cmdType) {
+				t.Errorf("%s is not a query", cmdType)
+			}
+		}
+	}
+	for _, cmdType := range []string{"hello", "createuser", "count"} {
+		if isQueryCommand(cmdType) {
+			t.Errorf("%s is a query", cmdType)
+		}
+	}
+	if action := queryActions[message.GetMore]; action != auth.ActionFind {
+		t.Errorf("%s != %s", action, auth.ActionFind)
+	}
+}
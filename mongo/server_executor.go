@@ -15,10 +15,90 @@
 package mongo
 
 import (
+	"math"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/cybergarage/go-logger/log"
+	"github.com/cybergarage/go-mongo/mongo/auth/sasl"
 	"github.com/cybergarage/go-mongo/mongo/bson"
 	"github.com/cybergarage/go-mongo/mongo/message"
+	"github.com/cybergarage/go-mongo/mongo/protocol"
+	"github.com/google/uuid"
+	"go.mongodb.org/mongo-driver/x/bsonx/bsoncore"
+)
+
+// See : killOp and currentOp - MongoDB Manual
+// https://www.mongodb.com/docs/manual/reference/command/killOp/
+// https://www.mongodb.com/docs/manual/reference/command/currentOp/
+
+const (
+	opElement               = "op"
+	infoElement             = "info"
+	inprogElement           = "inprog"
+	allElement              = "$all"
+	ownOpsElement           = "$ownOps"
+	typeElement             = "type"
+	activeElement           = "active"
+	opidElement             = "opid"
+	connectionUUIDElement   = "connectionUUID"
+	clientElement           = "client"
+	nsElement               = "ns"
+	commandElement          = "command"
+	startTimeElement        = "startTime"
+	secsRunningElement      = "secs_running"
+	microsecsRunningElement = "microsecs_running"
+	effectiveUsersElement   = "effectiveUsers"
+	keyElement              = "key"
+)
+
+const (
+	operationTypeOp = "op"
+	redactedValue   = "xxx"
 )
 
+// redactedCommandElements represents the sensitive elements of the commands which are redacted in the 'currentOp' documents.
+var redactedCommandElements = map[string][]string{
+	message.CreateUser:   {pwdElement},
+	message.UpdateUser:   {pwdElement},
+	message.SASLStart:    {sasl.Payload},
+	message.SASLContinue: {sasl.Payload},
+	message.Authenticate: {keyElement},
+	message.Hello:        {message.SpeculativeAuthenticate},
+	message.IsMaster:     {message.SpeculativeAuthenticate},
+}
+
+// operationTypes represents the 'op' field values of the queries, and the other commands are "command".
+var operationTypes = map[string]string{
+	message.Insert:      "insert",
+	message.Update:      "update",
+	message.Delete:      "remove",
+	message.Find:        "query",
+	message.GetMore:     "getmore",
+	message.KillCursors: "killcursors",
+}
+
+//////////////////////////////////////////////////
+// CommandExecutor
+//////////////////////////////////////////////////
+
+// ExecuteCommand runs the registered command after checking whether the command is allowed in the current read-only mode and replica set role.
+func (server *server) ExecuteCommand(conn *Conn, cmd *Command) (bson.Document, error) {
+	spec, ok := server.LookupCommand(cmd.Type())
+	if ok {
+		switch {
+		case spec.IsWrite() && server.IsReadOnly():
+			return nil, NewReadOnlyError(spec.Name())
+		case spec.IsWrite() && !server.IsMaster():
+			return nil, NewNotWritablePrimaryError()
+		case !spec.IsSecondaryAllowed() && !server.IsMaster():
+			return nil, NewNotPrimaryNoSecondaryOkError()
+		}
+	}
+	return server.BaseCommandExecutor.ExecuteCommand(conn, cmd)
+}
+
 //////////////////////////////////////////////////
 // DatabaseCommandExecutor
 //////////////////////////////////////////////////
@@ -54,6 +134,19 @@ func (server *server) Hello(conn *Conn, cmd *Command) (bson.Document, error) {
 				supportedMechs = append(supportedMechs, mech.Name())
 			}
 			reply.SetArrayElements(message.SASLSupportedMechs, supportedMechs)
+		case message.Compression:
+			// Compression Negotiation
+			// https://github.com/mongodb/specifications/blob/master/source/compression/OP_COMPRESSED.md
+			compressions, err := server.negotiateCompressions(conn, elem.Value())
+			if err != nil {
+				return nil, err
+			}
+			reply.SetArrayElements(message.Compression, compressions)
+		case message.SpeculativeAuthenticate:
+			res, ok := server.speculativeAuthenticate(conn, cmd.Database(), elem.Value())
+			if ok {
+				reply.SetDocumentElement(message.SpeculativeAuthenticate, res)
+			}
 		}
 	}
 
@@ -76,3 +169,223 @@ func (server *server) BuildInfo(conn *Conn, cmd *Command) (bson.Document, error)
 	}
 	return replyDoc, nil
 }
+
+// KillOp kills the in-flight operation of the 'op' identifier, and the context of the operation is cancelled as interrupted.
+func (server *server) KillOp(conn *Conn, cmd *Command) (bson.Document, error) {
+	val, ok := cmd.Value(opElement)
+	if !ok {
+		return nil, NewErrorf(ErrorCodeBadValue, "did not provide \"%s\" field", opElement)
+	}
+	id, ok := val.AsInt64OK()
+	if !ok || id < math.MinInt32 || math.MaxInt32 < id {
+		return nil, NewErrorf(ErrorCodeBadValue, "invalid \"%s\" field : %s", opElement, val.String())
+	}
+	if !server.KillOperation(int32(id)) {
+		log.Debugf("%s/%s operation (%d) not found", PackageName, Version, id)
+	}
+	reply := message.NewOkResponse()
+	reply.SetStringElement(infoElement, "attempting to kill op")
+	replyDoc, err := reply.BSONBytes()
+	if err != nil {
+		return nil, err
+	}
+	return replyDoc, nil
+}
+
+// CurrentOp returns the in-flight operations which match the filter of the command.
+// The '$all' option includes the idle connections, and the '$ownOps' option limits the operations to the current user.
+func (server *server) CurrentOp(conn *Conn, cmd *Command) (bson.Document, error) {
+	isAll := false
+	isOwnOps := false
+	filter := []bson.Element{}
+	for n, elem := range cmd.Elements {
+		switch key := elem.Key(); {
+		case n == 0:
+			continue
+		case key == allElement:
+			isAll, _ = elem.Value().BooleanOK()
+		case key == ownOpsElement:
+			isOwnOps, _ = elem.Value().BooleanOK()
+		case message.IsGenericArgument(key):
+			continue
+		default:
+			filter = append(filter, elem)
+		}
+	}
+
+	// Unauthenticated callers own no operations.
+	userName, isAuthenticated := conn.UserName()
+	isOwnOp := func(op *Operation) bool {
+		opUserName, ok := op.UserName()
+		return isAuthenticated && ok && opUserName == userName
+	}
+
+	docs := []bson.Document{}
+	activeConns := map[uuid.UUID]bool{}
+	for _, op := range server.Operations() {
+		activeConns[op.ConnUUID()] = true
+		if isOwnOps && !isOwnOp(op) {
+			continue
+		}
+		docs = append(docs, operationDocument(op))
+	}
+	if isAll && !isOwnOps {
+		for _, c := range server.Conns() {
+			if activeConns[c.UUID()] {
+				continue
+			}
+			docs = append(docs, idleConnectionDocument(c))
+		}
+	}
+
+	inprog := []any{}
+	for _, doc := range docs {
+		ok, err := message.MatchFilterElements(doc, filter)
+		if err != nil {
+			return nil, err
+		}
+		if ok {
+			inprog = append(inprog, doc)
+		}
+	}
+
+	res := message.NewOkResponse()
+	res.SetArrayElements(inprogElement, inprog)
+	return res.BSONBytes()
+}
+
+// isOwnOpsCommand returns true if the '$ownOps' option of the specified command is true.
+func isOwnOpsCommand(cmd *Command) bool {
+	v, ok := cmd.Value(ownOpsElement)
+	if !ok {
+		return false
+	}
+	isOwnOps, _ := v.BooleanOK()
+	return isOwnOps
+}
+
+// operationDocument returns the 'currentOp' document of the in-flight operation.
+func operationDocument(op *Operation) bson.Document {
+	elapsed := op.Elapsed()
+	opType, ok := operationTypes[op.CommandName()]
+	if !ok {
+		opType = "command"
+	}
+	builder := bsoncore.NewDocumentBuilder().
+		AppendString(typeElement, operationTypeOp).
+		AppendBoolean(activeElement, true).
+		AppendInt32(opidElement, op.ID()).
+		AppendString(connectionUUIDElement, op.ConnUUID().String()).
+		AppendString(clientElement, op.ClientAddress()).
+		AppendString(opElement, opType).
+		AppendString(nsElement, op.Namespace()).
+		AppendDateTime(startTimeElement, op.Timestamp().UnixMilli()).
+		AppendInt64(secsRunningElement, int64(elapsed/time.Second)).
+		AppendInt64(microsecsRunningElement, elapsed.Microseconds())
+	if doc := op.Document(); doc != nil {
+		builder.AppendDocument(commandElement, redactCommandDocument(doc))
+	}
+	if userName, ok := op.UserName(); ok {
+		builder.AppendArray(effectiveUsersElement, bsoncore.NewArrayBuilder().
+			AppendDocument(bsoncore.NewDocumentBuilder().
+				AppendString(userElement, userName.User).
+				AppendString(dbElement, userName.DB).
+				Build()).
+			Build())
+	}
+	return bson.Document(builder.Build())
+}
+
+// redactCommandDocument returns the command document whose sensitive elements are replaced as MongoDB does.
+func redactCommandDocument(doc bson.Document) bson.Document {
+	elems, err := doc.Elements()
+	if err != nil || len(elems) == 0 {
+		return doc
+	}
+	keys, ok := redactedCommandElements[strings.ToLower(elems[0].Key())]
+	if !ok {
+		return doc
+	}
+	redactedElems := make([][]byte, len(elems))
+	for n, elem := range elems {
+		if slices.Contains(keys, elem.Key()) {
+			redactedElems[n] = bsoncore.AppendStringElement(nil, elem.Key(), redactedValue)
+			continue
+		}
+		redactedElems[n] = elem
+	}
+	return bsoncore.BuildDocumentFromElements(nil, redactedElems...)
+}
+
+// idleConnectionDocument returns the 'currentOp' document of the idle connection.
+func idleConnectionDocument(conn *Conn) bson.Document {
+	return bson.Document(bsoncore.NewDocumentBuilder().
+		AppendString(typeElement, operationTypeOp).
+		AppendBoolean(activeElement, false).
+		AppendString(connectionUUIDElement, conn.UUID().String()).
+		AppendString(clientElement, conn.RemoteAddr().String()).
+		Build())
+}
+
+// speculativeAuthenticate runs the authentication command embedded in the hello command, and returns false when the reply should omit the result.
+// The embedded command runs on the database of the hello command unless it has the 'db' element.
+func (server *server) speculativeAuthenticate(conn *Conn, helloDB string, val bson.Value) (bson.Document, bool) {
+	doc, ok := val.DocumentOK()
+	if !ok {
+		return nil, false
+	}
+	cmd, err := message.NewCommandWithDocument(doc)
+	if err != nil {
+		return nil, false
+	}
+	db, ok := doc.Lookup(dbElement).StringValueOK()
+	if !ok {
+		db = helloDB
+	}
+	cmd.SetDatabase(db)
+	var res bson.Document
+	switch cmd.Type() {
+	case message.SASLStart:
+		res, err = server.AuthCommandExecutor.SASLStart(conn, cmd)
+	case message.Authenticate:
+		res, err = server.AuthCommandExecutor.Authenticate(conn, cmd)
+	default:
+		return nil, false
+	}
+	if err != nil {
+		log.Debugf("speculative authentication failed : %s", err)
+		return nil, false
+	}
+	return res, true
+}
+
+// negotiateCompressions returns the compressor names supported by both the client and the server, and sets the first one to the connection.
+func (server *server) negotiateCompressions(conn *Conn, val bson.Value) ([]any, error) {
+	compressions := []any{}
+	clientCompressions, ok := val.ArrayOK()
+	if !ok {
+		return compressions, nil
+	}
+	clientValues, err := clientCompressions.Values()
+	if err != nil {
+		return nil, err
+	}
+	for _, clientValue := range clientValues {
+		name, ok := clientValue.StringValueOK()
+		if !ok {
+			continue
+		}
+		if !slices.Contains(server.Compressions(), name) {
+			continue
+		}
+		c, ok := protocol.NewCompressorWithName(name)
+		if !ok {
+			continue
+		}
+		if len(compressions) == 0 {
+			conn.SetCompressor(c)
+		}
+		compressions = append(compressions, name)
+	}
+	return compressions, nil
+}
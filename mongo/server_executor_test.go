@@ -0,0 +1,73 @@
+// Copyright (C) 2022 The go-mongo Authors All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package mongo
+
+import (
+	"testing"
+
+	"go.mongodb.org/mongo-driver/x/bsonx/bsoncore"
+)
+
+func TestRedactCommandDocument(t *testing.T) {
+	tests := []struct {
+		doc      bsoncore.Document
+		key      string
+		expected string
+	}{
+		{
+			bsoncore.NewDocumentBuilder().AppendString("createUser", "alice").AppendString("pwd", "secret").Build(),
+			"pwd",
+			redactedValue,
+		},
+		{
+			bsoncore.NewDocumentBuilder().AppendString("updateUser", "alice").AppendString("pwd", "secret").Build(),
+			"pwd",
+			redactedValue,
+		},
+		{
+			bsoncore.NewDocumentBuilder().AppendInt32("saslStart", 1).AppendString("mechanism", "PLAIN").AppendString("payload", "secret").Build(),
+			"payload",
+			redactedValue,
+		},
+		{
+			bsoncore.NewDocumentBuilder().AppendInt32("saslContinue", 1).AppendString("payload", "secret").Build(),
+			"payload",
+			redactedValue,
+		},
+		{
+			bsoncore.NewDocumentBuilder().AppendInt32("hello", 1).AppendString("speculativeAuthenticate", "secret").Build(),
+			"speculativeAuthenticate",
+			redactedValue,
+		},
+		{
+			bsoncore.NewDocumentBuilder().AppendString("find", "coll").AppendString("pwd", "value").Build(),
+			"pwd",
+			"value",
+		},
+	}
+
+	for _, test := range tests {
+		doc := redactCommandDocument(test.doc)
+		val, ok := doc.Lookup(test.key).StringValueOK()
+		if !ok || val != test.expected {
+			t.Errorf("%s : %s != %s", doc, val, test.expected)
+		}
+		firstElem, _ := doc.IndexErr(0)
+		expectedElem, _ := test.doc.IndexErr(0)
+		if firstElem.Key() != expectedElem.Key() {
+			t.Errorf("%s != %s", firstElem.Key(), expectedElem.Key())
+		}
+	}
+}
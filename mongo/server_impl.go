@@ -16,10 +16,14 @@ package mongo
 
 import (
 	"crypto/tls"
+	"errors"
 	"fmt"
+	"io"
 	"math"
 	"net"
-	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
 
 	"github.com/cybergarage/go-logger/log"
 	"github.com/cybergarage/go-mongo/mongo/auth"
@@ -32,16 +36,23 @@ import (
 type server struct {
 	*config
 	*ConnManager
+	*OperationManager
 
-	tlsConfig *tls.Config
+	tlsConfig      atomic.Pointer[tls.Config]
+	tlsMutex       sync.Mutex
+	tlsWatcherDone chan struct{}
 	tracer.Tracer
 	messageListener      MessageListener
-	tcpListener          net.Listener
+	handshakeListener    HandshakeListener
+	listeners            []*listener
+	listenerMutex        sync.Mutex
+	shuttingDown         atomic.Bool
 	MessageHandler       OpMessageHandler
-	lastMessageRequestID int32
+	lastMessageRequestID atomic.Int32
 	*BaseMessageHandler
 	*BaseCommandExecutor
 	auth.Manager
+	accessManager auth.AccessManager
 }
 
 // NewServer returns a new server instance.
@@ -49,23 +60,34 @@ func NewServer() Server {
 	server := &server{
 		config:               newDefaultConfig(),
 		ConnManager:          NewConnManager(),
-		tlsConfig:            nil,
+		OperationManager:     NewOperationManager(),
+		tlsConfig:            atomic.Pointer[tls.Config]{},
+		tlsMutex:             sync.Mutex{},
+		tlsWatcherDone:       nil,
 		Tracer:               tracer.NullTracer,
 		messageListener:      nil,
+		handshakeListener:    nil,
 		MessageHandler:       nil,
-		tcpListener:          nil,
-		lastMessageRequestID: 0,
+		listeners:            []*listener{},
+		listenerMutex:        sync.Mutex{},
+		shuttingDown:         atomic.Bool{},
+		lastMessageRequestID: atomic.Int32{},
 		BaseMessageHandler:   NewBaseMessageHandler(),
 		BaseCommandExecutor:  NewBaseCommandExecutor(),
 		Manager:              auth.NewManager(),
+		accessManager:        auth.NewAccessManager(),
 	}
 
 	server.SetMessageHandler(server)
 	server.SetCommandExecutor(server)
+	server.SetCommandRegistry(server.BaseCommandExecutor.CommandRegistry)
 	server.SetMessageExecutor(server)
 	server.SetDatabaseCommandExecutor(server)
 	server.SetUserCommandExecutor(server)
 	server.SetAuthCommandExecutor(server)
+	server.SetUserManagementCommandExecutor(server)
+	server.SetAuthorizer(server)
+	server.SetCredentialStore(auth.NewSCRAMCredentialStore())
 
 	return server
 }
@@ -75,6 +97,11 @@ func (server *server) Config() Config {
 	return server
 }
 
+// AccessManager returns the role-based access control manager.
+func (server *server) AccessManager() auth.AccessManager {
+	return server.accessManager
+}
+
 // SetTracer sets a tracing tracer.
 func (server *server) SetTracer(t tracer.Tracer) {
 	server.Tracer = t
@@ -85,6 +112,11 @@ func (server *server) SetMessageListener(l MessageListener) {
 	server.messageListener = l
 }
 
+// SetHandshakeListener sets a listener for TLS handshakes.
+func (server *server) SetHandshakeListener(l HandshakeListener) {
+	server.handshakeListener = l
+}
+
 // SetMessageHandler sets a message handler.
 func (server *server) SetMessageHandler(h OpMessageHandler) {
 	server.MessageHandler = h
@@ -92,43 +124,87 @@ func (server *server) SetMessageHandler(h OpMessageHandler) {
 
 // Start starts the server.
 func (server *server) Start() error {
+	server.shuttingDown.Store(false)
+
+	if err := server.start(); err != nil {
+		// Undoes the started reapers and watcher not to leak them.
+		server.stopTLSWatcher()
+		server.CursorManager.Stop()
+		server.ConnManager.Stop()
+		return err
+	}
+
+	log.Infof("%s/%s (%s) started", PackageName, Version, endpointsString(server.Endpoints()))
+
+	return nil
+}
+
+// start starts the reapers, the TLS watcher and the listeners of the endpoints.
+func (server *server) start() error {
+	server.ConnManager.setIdleTimeout(server.IdleTimeout())
 	if err := server.ConnManager.Start(); err != nil {
 		return err
 	}
 
-	if server.IsTLSEnabled() {
+	server.CursorManager.setCursorTimeout(server.CursorTimeout())
+	if err := server.CursorManager.Start(); err != nil {
+		return err
+	}
+
+	endpoints := server.Endpoints()
+
+	if isTLSEndpointIncluded(endpoints) {
 		tlsConfig, err := server.TLSConfig()
 		if err != nil {
 			return err
 		}
-		server.tlsConfig = tlsConfig
+		if tlsConfig == nil {
+			return ErrTLSNotConfigured
+		}
+		server.tlsConfig.Store(tlsConfig)
+		if interval := server.TLSReloadInterval(); 0 < interval {
+			server.tlsWatcherDone = make(chan struct{})
+			go server.watchCertFiles(interval, server.tlsWatcherDone)
+		}
 	}
 
-	err := server.open()
+	listeners, err := server.open(endpoints)
 	if err != nil {
 		return err
 	}
 
-	go server.serve()
-
-	addr := net.JoinHostPort(server.Address(), strconv.Itoa(server.Port()))
-	log.Infof("%s/%s (%s) started", PackageName, Version, addr)
+	for _, l := range listeners {
+		go server.serve(l)
+	}
 
 	return nil
 }
 
+// stopTLSWatcher stops watching the certificate files.
+func (server *server) stopTLSWatcher() {
+	if server.tlsWatcherDone != nil {
+		close(server.tlsWatcherDone)
+		server.tlsWatcherDone = nil
+	}
+}
+
 // Stop stops the server.
 func (server *server) Stop() error {
+	server.stopTLSWatcher()
+
+	if err := server.close(); err != nil {
+		return err
+	}
+
 	if err := server.ConnManager.Stop(); err != nil {
 		return err
 	}
 
-	if err := server.close(); err != nil {
+	if err := server.CursorManager.Stop(); err != nil {
 		return err
 	}
 
-	addr := net.JoinHostPort(server.Address(), strconv.Itoa(server.Port()))
-	log.Infof("%s/%s (%s) terminated", PackageName, Version, addr)
+	log.Infof("%s/%s (%s) terminated", PackageName, Version, endpointsString(server.Endpoints()))
 
 	return nil
 }
@@ -141,95 +217,233 @@ func (server *server) Restart() error {
 	return server.Start()
 }
 
-// open opens a listen socket.
-func (server *server) open() error {
-	var err error
-	addr := net.JoinHostPort(server.Address(), strconv.Itoa(server.Port()))
-	server.tcpListener, err = net.Listen("tcp", addr)
-	if err != nil {
-		return err
+// listener represents a listening socket of an endpoint.
+type listener struct {
+	net.Listener
+	endpoint *Endpoint
+}
+
+// open opens the listening sockets of the endpoints.
+func (server *server) open(endpoints []*Endpoint) ([]*listener, error) {
+	server.listenerMutex.Lock()
+	defer server.listenerMutex.Unlock()
+	listeners := []*listener{}
+	for _, ep := range endpoints {
+		l, err := ep.listen()
+		if err != nil {
+			for _, l := range listeners {
+				l.Close()
+			}
+			return nil, err
+		}
+		listeners = append(listeners, &listener{Listener: l, endpoint: ep})
 	}
-	return nil
+	server.listeners = append(server.listeners, listeners...)
+	return listeners, nil
 }
 
-// close closes a listening socket.
+// close closes the listening sockets.
 func (server *server) close() error {
-	if server.tcpListener != nil {
-		err := server.tcpListener.Close()
-		if err != nil {
-			return err
+	server.listenerMutex.Lock()
+	defer server.listenerMutex.Unlock()
+	var errs error
+	for _, l := range server.listeners {
+		if err := l.Close(); err != nil && !errors.Is(err, net.ErrClosed) {
+			errs = errors.Join(errs, err)
 		}
 	}
+	server.listeners = []*listener{}
+	return errs
+}
 
-	server.tcpListener = nil
+// Serve accepts connections on the specified listener, and serves them until the listener is closed.
+// The listener is closed when the server stops, and a TLS listener such as tls.NewListener is served as TLS connections.
+func (server *server) Serve(l net.Listener) error {
+	sl := &listener{Listener: l, endpoint: nil}
+	server.listenerMutex.Lock()
+	server.listeners = append(server.listeners, sl)
+	server.listenerMutex.Unlock()
+	err := server.serve(sl)
+	if errors.Is(err, net.ErrClosed) {
+		return nil
+	}
+	return err
+}
 
-	return nil
+// ServeConn serves the specified connection until it is closed. A *tls.Conn runs the TLS handshake before serving.
+func (server *server) ServeConn(conn net.Conn) error {
+	err := server.serveConn(conn)
+	if errors.Is(err, io.EOF) || errors.Is(err, net.ErrClosed) {
+		return nil
+	}
+	return err
 }
 
-// serve handles client requests.
-func (server *server) serve() error {
-	defer server.close()
+// serveConn reserves a slot of the connection limit before the TLS handshake, and handles the client messages.
+// The handshaking connections are managed as the other connections to be closed when the server stops.
+func (server *server) serveConn(conn net.Conn) error {
+	log.Debugf("%s/%s (%s) accepted", PackageName, Version, conn.RemoteAddr().String())
 
-	l := server.tcpListener
-	for l != nil {
-		conn, err := l.Accept()
+	handlerConn := newConnWith(conn, nil)
+	handlerConn.setTimeouts(server.ReadTimeout(), server.WriteTimeout())
+	if !server.addConnWithLimit(handlerConn, server.MaxIncomingConnections()) {
+		log.Warnf("%s/%s (%s) refused : %s (%d)", PackageName, Version, conn.RemoteAddr().String(), ErrTooManyConnections, server.MaxIncomingConnections())
+		conn.Close()
+		return ErrTooManyConnections
+	}
+	defer func() {
+		handlerConn.Close()
+		server.RemoveConn(handlerConn)
+		server.KillConnCursors(handlerConn)
+		server.ConversationManager().RemoveConnection(handlerConn.UUID().String())
+	}()
+
+	if tlsConn, ok := conn.(*tls.Conn); ok {
+		state, err := server.handshake(tlsConn)
 		if err != nil {
 			return err
 		}
+		handlerConn.setTLSConnectionState(&state)
+	}
 
-		var tlsState *tls.ConnectionState
-		if server.IsTLSEnabled() {
-			tlsConn := tls.Server(conn, server.tlsConfig)
-			if err := tlsConn.Handshake(); err != nil {
-				return err
-			}
-			ok, err := server.Manager.VerifyCertificate(tlsConn)
-			if !ok {
-				log.Error(err)
-				return err
+	return server.receive(handlerConn)
+}
+
+// serve handles client requests of the listener until it is closed.
+func (server *server) serve(l *listener) error {
+	for {
+		conn, err := l.Accept()
+		if err != nil {
+			if !errors.Is(err, net.ErrClosed) {
+				log.Errorf("%s/%s (%s) %s", PackageName, Version, l.Addr().String(), err)
 			}
-			tlsStateObj := tlsConn.ConnectionState()
-			tlsState = &tlsStateObj
-			conn = tlsConn
+			return err
+		}
+
+		if l.endpoint != nil && l.endpoint.IsTLSEnabled() {
+			conn = tls.Server(conn, server.currentTLSConfig())
 		}
 
-		go server.receive(conn, tlsState)
+		go server.ServeConn(conn)
 	}
+}
 
-	return nil
+// isTLSEndpointIncluded returns true if an endpoint accepts TLS connections.
+func isTLSEndpointIncluded(endpoints []*Endpoint) bool {
+	for _, ep := range endpoints {
+		if ep.IsTLSEnabled() {
+			return true
+		}
+	}
+	return false
+}
+
+// endpointsString returns the string representation of the endpoints.
+func endpointsString(endpoints []*Endpoint) string {
+	strs := make([]string, len(endpoints))
+	for n, ep := range endpoints {
+		strs[n] = ep.Address()
+	}
+	return strings.Join(strs, ", ")
 }
 
 // receive handles client messages.
-func (server *server) receive(conn net.Conn, tlsState *tls.ConnectionState) error {
+func (server *server) receive(handlerConn *Conn) error {
 	var err error
-	var reqMsg, resMsg protocol.Message
+	var reqMsg protocol.Message
 
-	log.Debugf("%s/%s (%s) accepted", PackageName, Version, conn.RemoteAddr().String())
-
-	handlerConn := newConnWith(conn, tlsState)
-	server.AddConn(handlerConn)
-	defer func() {
-		handlerConn.Close()
-		server.RemoveConn(handlerConn)
-	}()
+	// Reads the next messages in the background to cancel the in-flight request when the client disconnects.
+	done := make(chan struct{})
+	defer close(done)
+	reqMsgs := server.readMessages(handlerConn, done)
 
 	for err == nil {
 		loopSpan := server.Tracer.StartSpan(PackageName)
 		handlerConn.SetSpanContext(loopSpan)
 
 		loopSpan.StartSpan("parse")
-		reqMsg, err = server.readMessage(conn)
+		res := <-reqMsgs
+		reqMsg, err = res.msg, res.err
 		loopSpan.FinishSpan()
 		if err != nil {
+			// Closes only the connection of the broken stream
+			if !errors.Is(err, io.EOF) && !errors.Is(err, net.ErrClosed) {
+				log.Errorf("%s/%s (%s) %s", PackageName, Version, handlerConn.RemoteAddr().String(), err)
+			}
 			defer loopSpan.FinishSpan()
 			break
 		}
 
-		resMsg, err = server.handleMessage(handlerConn, reqMsg)
+		handlerConn.beginRequest()
+		err = server.serveMessage(handlerConn, reqMsg)
+		handlerConn.endRequest()
 
+		loopSpan.FinishSpan()
 		if err != nil {
-			// FIXME : Check MongoDB implementation, and update to return a more standard error response
-			badReply, _ := message.NewBadResponse().BSONBytes()
+			break
+		}
+	}
+
+	return err
+}
+
+// readResult represents a result of reading a request message.
+type readResult struct {
+	msg protocol.Message
+	err error
+}
+
+// readMessages reads the request messages of the connection until a read error occurs or done is closed.
+// The context of the connection is cancelled with the read error, and the error is sent as the last result.
+// Since the messages are read ahead, a client which half-closes the connection after sending a request
+// is treated as disconnected like mongod, and the in-flight request is cancelled as 'ClientDisconnect'.
+func (server *server) readMessages(conn *Conn, done chan struct{}) <-chan readResult {
+	results := make(chan readResult)
+	reader := server.newMessageReader(conn)
+	go func() {
+		for {
+			msg, err := reader.ReadMessage()
+			if err == nil {
+				err = conn.finishReading()
+			}
+			if err != nil {
+				conn.closeContext(NewClientDisconnectError())
+			}
+			select {
+			case results <- readResult{msg: msg, err: err}:
+			case <-done:
+				return
+			}
+			if err != nil {
+				return
+			}
+		}
+	}()
+	return results
+}
+
+// serveMessage handles the specified request message, and sends the response messages.
+func (server *server) serveMessage(conn *Conn, reqMsg protocol.Message) error {
+	op := server.startOperation(conn, reqMsg)
+	defer server.finishOperation(op)
+
+	responseTo := reqMsg.RequestID()
+	for {
+		resMsg, err := server.handleMessage(conn, reqMsg)
+
+		// Replies the cause instead of the context error if the executor has been cancelled.
+		err = op.causeOf(err)
+
+		// OP_MSG with moreToCome (unacknowledged writes) must not be replied.
+		if opMsg, ok := reqMsg.(*OpMsg); ok && opMsg.IsMoreToCome() {
+			if err != nil {
+				conn.SetLastError(err)
+			}
+			return nil
+		}
+
+		if err != nil {
+			badReply, _ := message.NewErrorResponse(err).BSONBytes()
 			switch reqMsg.OpCode() {
 			case protocol.OpMsg:
 				resMsg = protocol.NewMsgWithBody(badReply)
@@ -239,28 +453,100 @@ func (server *server) receive(conn net.Conn, tlsState *tls.ConnectionState) erro
 		}
 
 		resMsg.SetRequestID(server.nextMessageRequestID())
-		resMsg.SetResponseTo(reqMsg.RequestID())
+		resMsg.SetResponseTo(responseTo)
 
-		loopSpan.StartSpan("response")
-		err = server.responseMessage(conn, resMsg)
-		loopSpan.FinishSpan()
+		// Exhaust cursors stream the following batches with moreToCome until the cursor is exhausted.
+		isExhaust := server.isExhaustReply(reqMsg, resMsg)
 
-		loopSpan.FinishSpan()
+		if opMsg, ok := resMsg.(*OpMsg); ok {
+			opMsg.SetMoreToCome(isExhaust)
+			if server.IsChecksumEnabled() {
+				opMsg.SetChecksumPresent(true)
+			}
+		}
+
+		responseTo = resMsg.RequestID()
+
+		resMsg, err = server.compressMessage(conn, requestCommandName(reqMsg), resMsg)
 		if err != nil {
-			break
+			return err
+		}
+
+		conn.StartSpan("response")
+		err = server.responseMessage(conn, resMsg)
+		conn.FinishSpan()
+		if err != nil {
+			return err
+		}
+
+		if !isExhaust {
+			return nil
 		}
 	}
+}
 
-	return err
+// startOperation starts a new operation of the specified request message with the time limit of 'maxTimeMS'.
+func (server *server) startOperation(conn *Conn, reqMsg protocol.Message) *Operation {
+	doc := requestDocument(reqMsg)
+	maxTime, _ := message.MaxTime(doc)
+	return server.OperationManager.startOperation(conn, doc, maxTime)
+}
+
+// requestDocument returns the command document of the specified request message, or nil for the legacy opcodes.
+func requestDocument(reqMsg protocol.Message) bson.Document {
+	switch msg := reqMsg.(type) {
+	case *OpMsg:
+		return msg.Body()
+	case *OpQuery:
+		return msg.Document()
+	}
+	return nil
+}
+
+// requestCommandName returns the first key of the command document of the specified request message,
+// even if the request is rejected before it is dispatched.
+func requestCommandName(reqMsg protocol.Message) string {
+	doc := requestDocument(reqMsg)
+	if doc == nil {
+		return ""
+	}
+	elem, err := doc.IndexErr(0)
+	if err != nil {
+		return ""
+	}
+	return elem.Key()
+}
+
+// isExhaustReply returns true when the specified reply is a batch of an exhaust cursor which has more batches.
+func (server *server) isExhaustReply(reqMsg protocol.Message, resMsg protocol.Message) bool {
+	reqOpMsg, ok := reqMsg.(*OpMsg)
+	if !ok || !reqOpMsg.IsExhaustAllowed() {
+		return false
+	}
+	resOpMsg, ok := resMsg.(*OpMsg)
+	if !ok {
+		return false
+	}
+	cmd, err := message.NewCommandWithDocument(reqOpMsg.Body())
+	if err != nil || !cmd.IsType(strings.ToLower(message.GetMore)) {
+		return false
+	}
+	cursorID, ok := message.CursorID(resOpMsg.Body())
+	return ok && cursorID != 0
 }
 
-// nextMessageRequestID returns a next message request identifier.
+// nextMessageRequestID returns a next message request identifier, and is safe for concurrent use.
 func (server *server) nextMessageRequestID() int32 {
-	server.lastMessageRequestID++
-	if math.MaxInt32 == server.lastMessageRequestID {
-		server.lastMessageRequestID = 0
+	for {
+		lastID := server.lastMessageRequestID.Load()
+		nextID := lastID + 1
+		if math.MaxInt32 == nextID {
+			nextID = 0
+		}
+		if server.lastMessageRequestID.CompareAndSwap(lastID, nextID) {
+			return nextID
+		}
 	}
-	return server.lastMessageRequestID
 }
 
 // responseMessage returns a specified message to the request connection.
@@ -275,42 +561,35 @@ func (server *server) responseMessage(conn net.Conn, msg protocol.Message) error
 	return err
 }
 
-// readMessage handles client messages.
-func (server *server) readMessage(conn net.Conn) (protocol.Message, error) {
-	headerBytes := make([]byte, protocol.HeaderSize)
-	nRead, err := conn.Read(headerBytes)
-	if err != nil {
-		if nRead <= 0 {
-			return nil, err
-		}
-		log.Fatal(err.Error())
-		return nil, err
-	}
+// newMessageReader returns a message reader of the specified connection with the configured size limitations.
+func (server *server) newMessageReader(conn net.Conn) *protocol.Reader {
+	reader := protocol.NewReaderWith(conn)
+	reader.SetMaxMessageSize(server.MaxMessageSizeBytes())
+	reader.SetMaxBsonObjectSize(server.MaxBsonObjectSize())
+	return reader
+}
 
-	header, err := protocol.NewHeaderWithBytes(headerBytes)
-	if err != nil {
-		log.Fatal(err.Error())
-		return nil, err
+// compressMessage returns the compressed response message when the connection has negotiated a compressor.
+// The response is not compressed when the requested command must not be compressed, such as hello and saslStart.
+func (server *server) compressMessage(conn *Conn, cmdName string, resMsg protocol.Message) (protocol.Message, error) {
+	c := conn.Compressor()
+	if c == protocol.CompressorNoop {
+		return resMsg, nil
 	}
-
-	bodyBytes := make([]byte, header.BodySize())
-	_, err = conn.Read(bodyBytes)
-	if err != nil {
-		log.Fatal(err.Error())
-		return nil, err
-	}
-
-	msg, err := protocol.NewMessageWithHeaderAndBytes(header, bodyBytes)
-	if err != nil {
-		log.Fatal(err.Error())
-		return nil, err
+	if !message.IsCompressibleCommand(cmdName) {
+		return resMsg, nil
 	}
-
-	return msg, nil
+	return protocol.NewCompressedWithMessage(resMsg, c)
 }
 
 // handleMessage handles client messages.
 func (server *server) handleMessage(conn *Conn, reqMsg protocol.Message) (protocol.Message, error) {
+	// New requests are not handled while shutting down
+
+	if server.isShuttingDown() {
+		return nil, NewShutdownInProgressError()
+	}
+
 	// MessageListener
 
 	if server.messageListener != nil {
@@ -383,7 +662,7 @@ func (server *server) handleMessage(conn *Conn, reqMsg protocol.Message) (protoc
 		}
 		queryType := q.Type()
 		switch queryType {
-		case message.Insert, message.Delete, message.Update, message.Find, message.KillCursors:
+		case message.Insert, message.Delete, message.Update, message.Find, message.GetMore, message.KillCursors:
 			resMsg = protocol.NewMsgWithBody(resDoc)
 		default:
 			resMsg = protocol.NewReplyWithDocuments(resDocs)
@@ -15,9 +15,20 @@
 package mongo
 
 import (
+	"strings"
+	"time"
+
+	"github.com/cybergarage/go-mongo/mongo/auth"
 	"github.com/cybergarage/go-mongo/mongo/auth/sasl"
+	"github.com/cybergarage/go-mongo/mongo/auth/sasl/oidc"
+	"github.com/cybergarage/go-mongo/mongo/auth/sasl/plain"
 	"github.com/cybergarage/go-mongo/mongo/auth/sasl/scram"
 	"github.com/cybergarage/go-mongo/mongo/bson"
+	"github.com/cybergarage/go-mongo/mongo/message"
+)
+
+const (
+	scramMechanismPrefix = "SCRAM-"
 )
 
 // MongoDB Handshake
@@ -26,8 +37,15 @@ import (
 // https://github.com/mongodb/specifications/blob/master/source/auth/auth.md
 
 // SASLSupportedMechs returns the supported SASL mechanisms.
-func (server *server) SASLSupportedMechs(*Conn, string) ([]sasl.SASLMechanism, error) {
-	return server.Mechanisms(), nil
+func (server *server) SASLSupportedMechs(conn *Conn, user string) ([]sasl.SASLMechanism, error) {
+	mechs := []sasl.SASLMechanism{}
+	for _, mech := range server.Mechanisms() {
+		if !server.isMechanismAllowed(conn, mech) {
+			continue
+		}
+		mechs = append(mechs, mech)
+	}
+	return mechs, nil
 }
 
 // SASLStart handles SASLStart command.
@@ -54,35 +72,66 @@ func (server *server) SASLStart(conn *Conn, cmd *Command) (bson.Document, error)
 
 	mech, err := server.Mechanism(reqMech)
 	if err != nil {
-		return nil, err
+		return nil, message.NewErrorWith(ErrorCodeMechanismUnavailable, err)
 	}
+	if !server.isMechanismAllowed(conn, mech) {
+		return nil, NewErrorf(ErrorCodeMechanismUnavailable, "%s authentication requires a TLS connection", mech.Name())
+	}
+
+	// A new conversation discards the current authentication to allow re-authenticating as a different user,
+	// but an attempt rejected while backing off keeps it.
+
+	convMgr := server.ConversationManager()
+	connID := conn.UUID().String()
+	username := saslUsernameFrom(mech.Name(), reqPayload)
+	user := auth.NewUserName(username, cmd.Database())
+	if err := convMgr.CheckAttempt(connID); err != nil {
+		return nil, message.NewErrorWith(ErrorCodeAuthenticationFailed, err)
+	}
+	convMgr.FinishConversation(connID)
+	conn.resetAuthentication()
 
 	// Start the SASL context
 
-	opts := []sasl.SASLOption{}
+	opts := []sasl.SASLOption{
+		sasl.SASLIterationCount(server.SCRAMIterationCount()),
+	}
 
 	ctx, err := mech.Start(opts...)
 	if err != nil {
 		return nil, err
 	}
+	ctx.SetValue(scram.DatabaseID, cmd.Database())
 
 	mechRes, err := ctx.Next(sasl.SASLPayload(reqPayload))
 	if err != nil {
+		waitFailureDelay(conn, convMgr.RecordFailure(connID, user))
 		if !scram.IsStandardError(err) {
-			return nil, err
+			return nil, message.NewErrorWith(ErrorCodeAuthenticationFailed, err)
 		}
 		mechRes = scram.NewMessageWithError(err)
 	}
+	isFailed := err != nil
+	isAuthenticated := !isFailed && ctx.Done()
+
+	if strings.HasPrefix(mech.Name(), scramMechanismPrefix) && 0 < len(username) {
+		ctx.SetValue(scram.UsernameID, username)
+	}
 
 	// Response to the client
 
-	conversationID := server.ConversationCounter().Inc()
+	conv := convMgr.StartConversation(connID, user, ctx)
+	conversationID := conv.ID
 	ctx.SetValue(sasl.ConversationId, conversationID)
 
 	var resMsg *MessageResponse
-	if err == nil {
+	switch {
+	case isAuthenticated:
+		// Single-step mechanisms such as PLAIN are completed in saslStart.
+		resMsg, err = sasl.NewServerFinalResponse(conversationID, mechRes.Bytes())
+	case err == nil:
 		resMsg, err = sasl.NewServerFirstResponse(conversationID, mechRes.Bytes())
-	} else {
+	default:
 		resMsg, err = sasl.NewServerErrorResponse(conversationID, err)
 	}
 	if err != nil {
@@ -94,18 +143,19 @@ func (server *server) SASLStart(conn *Conn, cmd *Command) (bson.Document, error)
 		return nil, err
 	}
 
-	conn.SetSASLContext(ctx)
+	if isAuthenticated || isFailed {
+		convMgr.FinishConversation(connID)
+	}
+	if isAuthenticated {
+		convMgr.RecordSuccess(connID, user)
+	}
+	server.setAuthenticated(conn, cmd, ctx, isAuthenticated)
 
 	return resDoc, nil
 }
 
 // SASLContinue handles SASLContinue command.
 func (server *server) SASLContinue(conn *Conn, cmd *Command) (bson.Document, error) {
-	ctx := conn.SASLContext()
-	if ctx == nil {
-		return nil, NewErrorCommand(cmd)
-	}
-
 	var clientConversationID int32
 	var reqPayload []byte
 	var ok bool
@@ -128,27 +178,29 @@ func (server *server) SASLContinue(conn *Conn, cmd *Command) (bson.Document, err
 
 	// Check the conversation ID
 
-	v, ok := ctx.Value(sasl.ConversationId)
-	if !ok {
-		return nil, NewErrorCommand(cmd)
-	}
-	conversationID, ok := v.(int32)
-	if !ok {
-		return nil, NewErrorCommand(cmd)
-	}
-
-	if clientConversationID != conversationID {
-		return nil, NewErrorCommand(cmd)
+	convMgr := server.ConversationManager()
+	connID := conn.UUID().String()
+	conv, err := convMgr.Conversation(connID, clientConversationID)
+	if err != nil {
+		return nil, message.NewErrorWith(ErrorCodeAuthenticationFailed, err)
 	}
+	convMgr.FinishConversation(connID)
+	ctx := conv.Context
+	conversationID := conv.ID
 
 	// Response to the client
 
 	mechRes, err := ctx.Next(sasl.SASLPayload(reqPayload))
 	if err != nil {
+		waitFailureDelay(conn, convMgr.RecordFailure(connID, conv.User))
 		if !scram.IsStandardError(err) {
-			return nil, err
+			return nil, message.NewErrorWith(ErrorCodeAuthenticationFailed, err)
 		}
 	}
+	isAuthenticated := err == nil && ctx.Done()
+	if isAuthenticated {
+		convMgr.RecordSuccess(connID, conv.User)
+	}
 
 	var resMsg *MessageResponse
 	if err == nil {
@@ -165,7 +217,70 @@ func (server *server) SASLContinue(conn *Conn, cmd *Command) (bson.Document, err
 		return nil, err
 	}
 
-	conn.SetSASLContext(nil)
+	server.setAuthenticated(conn, cmd, ctx, isAuthenticated)
 
 	return resDoc, nil
 }
+
+// Logout handles 'logout' command, and discards the SASL conversation and the authentication of the connection.
+func (server *server) Logout(conn *Conn, cmd *Command) (bson.Document, error) {
+	server.ConversationManager().FinishConversation(conn.UUID().String())
+	conn.resetAuthentication()
+	return message.NewOkResponse().BSONBytes()
+}
+
+// saslUsernameFrom returns the user name of the first client message to track failed attempts per user.
+func saslUsernameFrom(mechName string, payload []byte) string {
+	var username string
+	switch {
+	case strings.HasPrefix(mechName, scramMechanismPrefix):
+		username, _ = scram.UsernameFrom(payload)
+	case mechName == plain.Mechanism:
+		username, _ = plain.UsernameFrom(payload)
+	}
+	return username
+}
+
+// isMechanismAllowed returns false when the mechanism sends cleartext passwords on a non-TLS connection which is not allowed.
+func (server *server) isMechanismAllowed(conn *Conn, mech sasl.SASLMechanism) bool {
+	if mech.Name() != plain.Mechanism {
+		return true
+	}
+	return conn.IsTLSConnection() || server.IsPlainWithoutTLSAllowed()
+}
+
+// setAuthenticated sets the authenticated state, the user name and the expiration of the SASL context to the connection.
+func (server *server) setAuthenticated(conn *Conn, cmd *Command, ctx sasl.SASLContext, isAuthenticated bool) {
+	conn.SetAuthrized(isAuthenticated)
+	if !isAuthenticated {
+		return
+	}
+	conn.SetAuthenticationExpiration(time.Time{})
+	if v, ok := ctx.Value(oidc.ExpirationID); ok {
+		if exp, ok := v.(time.Time); ok {
+			conn.SetAuthenticationExpiration(exp)
+		}
+	}
+	v, ok := ctx.Value(scram.UsernameID)
+	if !ok {
+		return
+	}
+	username, ok := v.(string)
+	if !ok {
+		return
+	}
+	conn.SetUserName(auth.NewUserName(username, cmd.Database()))
+}
+
+// waitFailureDelay waits the delay of the failure response of the user, or until the connection is closed.
+func waitFailureDelay(conn *Conn, d time.Duration) {
+	if d <= 0 {
+		return
+	}
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+	case <-conn.context().Done():
+	}
+}
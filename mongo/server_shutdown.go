@@ -0,0 +1,101 @@
+// Copyright (C) 2019 The go-mongo Authors. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package mongo
+
+import (
+	"context"
+	"time"
+
+	"github.com/cybergarage/go-logger/log"
+)
+
+// shutdownPollInterval is the interval to check whether in-flight requests have finished.
+const shutdownPollInterval = 10 * time.Millisecond
+
+// ShutdownResult represents the result of a graceful shutdown.
+type ShutdownResult struct {
+	// Drained is the number of in-flight requests which finished and sent their replies.
+	Drained int
+	// ForceClosed is the number of connections which were closed while handling a request.
+	ForceClosed int
+}
+
+// Shutdown stops the server gracefully. It stops accepting new connections and waits for in-flight requests
+// to send their replies until the context is done, while new requests on the open connections are answered
+// with a ShutdownInProgress error. Then, it closes all remaining connections, and returns the context error
+// if some requests were still in flight.
+func (server *server) Shutdown(ctx context.Context) (*ShutdownResult, error) {
+	server.shuttingDown.Store(true)
+
+	server.stopTLSWatcher()
+
+	if err := server.close(); err != nil {
+		return nil, err
+	}
+
+	// Waits for the requests which are in flight at this point
+
+	pending := map[*Conn]uint64{}
+	for _, conn := range server.Conns() {
+		if isActive, nRequests := conn.requestState(); isActive {
+			pending[conn] = nRequests
+		}
+	}
+
+	nInFlight := len(pending)
+	ticker := time.NewTicker(shutdownPollInterval)
+	defer ticker.Stop()
+
+	var ctxErr error
+	for 0 < len(pending) && ctxErr == nil {
+		for conn, nRequests := range pending {
+			if _, n := conn.requestState(); nRequests < n {
+				delete(pending, conn)
+			}
+		}
+		if len(pending) == 0 {
+			break
+		}
+		select {
+		case <-ctx.Done():
+			ctxErr = ctx.Err()
+		case <-ticker.C:
+		}
+	}
+
+	// Closes the remaining connections
+
+	if err := server.ConnManager.Stop(); err != nil {
+		return nil, err
+	}
+
+	if err := server.CursorManager.Stop(); err != nil {
+		return nil, err
+	}
+
+	res := &ShutdownResult{
+		Drained:     nInFlight - len(pending),
+		ForceClosed: len(pending),
+	}
+
+	log.Infof("%s/%s (%s) terminated (drained:%d, force closed:%d)", PackageName, Version, endpointsString(server.Endpoints()), res.Drained, res.ForceClosed)
+
+	return res, ctxErr
+}
+
+// isShuttingDown returns true if the server is shutting down and does not handle new requests.
+func (server *server) isShuttingDown() bool {
+	return server.shuttingDown.Load()
+}
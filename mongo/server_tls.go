@@ -0,0 +1,136 @@
+// Copyright (C) 2019 The go-mongo Authors. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package mongo
+
+import (
+	"context"
+	"crypto/tls"
+	"os"
+	"time"
+
+	"github.com/cybergarage/go-logger/log"
+)
+
+// certFileStamp represents the modification time and size of a certificate file to detect changes.
+type certFileStamp struct {
+	modTime time.Time
+	size    int64
+}
+
+// ReloadTLSConfig reloads the key, certificate and root certificate files, and new handshakes use the reloaded configuration.
+// Existing connections are not interrupted, and the current configuration is kept if the files are invalid.
+func (server *server) ReloadTLSConfig() error {
+	server.tlsMutex.Lock()
+	defer server.tlsMutex.Unlock()
+	if err := server.reloadCertFiles(); err != nil {
+		return err
+	}
+	tlsConfig, err := server.TLSConfig()
+	if err != nil {
+		return err
+	}
+	if tlsConfig == nil {
+		return nil
+	}
+	server.tlsConfig.Store(tlsConfig)
+	log.Infof("%s/%s TLS configuration reloaded", PackageName, Version)
+	return nil
+}
+
+// currentTLSConfig returns the TLS configuration for new handshakes.
+func (server *server) currentTLSConfig() *tls.Config {
+	return server.tlsConfig.Load()
+}
+
+// certFileStamps returns the stamps of the certificate files.
+func (server *server) certFileStamps() map[string]certFileStamp {
+	stamps := map[string]certFileStamp{}
+	for _, file := range server.certFiles() {
+		fi, err := os.Stat(file)
+		if err != nil {
+			continue
+		}
+		stamps[file] = certFileStamp{modTime: fi.ModTime(), size: fi.Size()}
+	}
+	return stamps
+}
+
+// watchCertFiles reloads the TLS configuration when the certificate files change until the done channel is closed.
+func (server *server) watchCertFiles(interval time.Duration, done <-chan struct{}) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	stamps := server.certFileStamps()
+	for {
+		select {
+		case <-done:
+			return
+		case <-ticker.C:
+			newStamps := server.certFileStamps()
+			if isCertFileStampsEqual(stamps, newStamps) {
+				continue
+			}
+			// The stamps are not updated on failures to retry while the files are being replaced.
+			if err := server.ReloadTLSConfig(); err != nil {
+				log.Errorf("%s/%s TLS configuration not reloaded : %s", PackageName, Version, err)
+				continue
+			}
+			stamps = newStamps
+		}
+	}
+}
+
+func isCertFileStampsEqual(a, b map[string]certFileStamp) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for file, stamp := range a {
+		other, ok := b[file]
+		if !ok || !stamp.modTime.Equal(other.modTime) || stamp.size != other.size {
+			return false
+		}
+	}
+	return true
+}
+
+// handshake runs the TLS handshake and the certificate verification of the accepted connection.
+// A failed handshake closes only the connection.
+func (server *server) handshake(tlsConn *tls.Conn) (tls.ConnectionState, error) {
+	ctx := context.Background()
+	if timeout := server.TLSHandshakeTimeout(); 0 < timeout {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+
+	err := tlsConn.HandshakeContext(ctx)
+	if err == nil {
+		var ok bool
+		ok, err = server.Manager.VerifyCertificate(tlsConn)
+		if !ok && err == nil {
+			err = ErrCertificateNotVerified
+		}
+	}
+	if err != nil {
+		log.Errorf("%s/%s (%s) TLS handshake failed : %s", PackageName, Version, tlsConn.RemoteAddr().String(), err)
+		tlsConn.Close()
+		if server.handshakeListener != nil {
+			server.handshakeListener.HandshakeFailed(tlsConn, err)
+		}
+		return tls.ConnectionState{}, err
+	}
+
+	return tlsConn.ConnectionState(), nil
+}
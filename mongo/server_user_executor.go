@@ -0,0 +1,481 @@
+// Copyright (C) 2019 The go-mongo Authors. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package mongo
+
+import (
+	"errors"
+
+	"github.com/cybergarage/go-mongo/mongo/auth"
+	"github.com/cybergarage/go-mongo/mongo/bson"
+	"github.com/cybergarage/go-mongo/mongo/message"
+	"go.mongodb.org/mongo-driver/x/bsonx/bsoncore"
+)
+
+// See : User Management Commands and Role Management Commands
+// https://www.mongodb.com/docs/manual/reference/command/nav-user-management/
+// https://www.mongodb.com/docs/manual/reference/command/nav-role-management/
+
+const (
+	userElement             = "user"
+	usersElement            = "users"
+	dbElement               = "db"
+	idElement               = "_id"
+	pwdElement              = "pwd"
+	roleElement             = "role"
+	rolesElement            = "roles"
+	isBuiltinElement        = "isBuiltin"
+	privilegesElement       = "privileges"
+	resourceElement         = "resource"
+	collectionElement       = "collection"
+	actionsElement          = "actions"
+	forAllDBsElement        = "forAllDBs"
+	showPrivilegesElement   = "showPrivileges"
+	showBuiltinRolesElement = "showBuiltinRoles"
+)
+
+// CreateUser handles 'createUser' command.
+func (server *server) CreateUser(conn *Conn, cmd *Command) (bson.Document, error) {
+	name, err := commandUserName(cmd)
+	if err != nil {
+		return nil, err
+	}
+	roles, ok, err := commandRoleNames(cmd)
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		return nil, newErrMissingElement(cmd, rolesElement)
+	}
+	err = server.accessManager.CreateUser(auth.NewUser(name, roles...))
+	if err != nil {
+		return nil, newAccessManagerError(err)
+	}
+	err = server.updateCredential(cmd, name)
+	if err != nil {
+		_ = server.accessManager.DropUser(name)
+		return nil, err
+	}
+	return message.NewOkResponse().BSONBytes()
+}
+
+// UpdateUser handles 'updateUser' command.
+func (server *server) UpdateUser(conn *Conn, cmd *Command) (bson.Document, error) {
+	name, err := commandUserName(cmd)
+	if err != nil {
+		return nil, err
+	}
+	user, ok := server.accessManager.LookupUser(name)
+	if !ok {
+		return nil, newAccessManagerError(auth.ErrUserNotFound)
+	}
+	roles, hasRoles, err := commandRoleNames(cmd)
+	if err != nil {
+		return nil, err
+	}
+	_, hasPwd := cmd.Value(pwdElement)
+	if !hasRoles && !hasPwd {
+		return nil, NewErrorf(ErrorCodeBadValue, "must specify at least one field to update in %s", cmd.Type())
+	}
+	if hasRoles {
+		user.Roles = roles
+		err = server.accessManager.UpdateUser(user)
+		if err != nil {
+			return nil, newAccessManagerError(err)
+		}
+	}
+	err = server.updateCredential(cmd, name)
+	if err != nil {
+		return nil, err
+	}
+	return message.NewOkResponse().BSONBytes()
+}
+
+// DropUser handles 'dropUser' command.
+func (server *server) DropUser(conn *Conn, cmd *Command) (bson.Document, error) {
+	name, err := commandUserName(cmd)
+	if err != nil {
+		return nil, err
+	}
+	err = server.accessManager.DropUser(name)
+	if err != nil {
+		return nil, newAccessManagerError(err)
+	}
+	if updater, ok := server.CredentialStore().(auth.CredentialUpdater); ok {
+		err = updater.RemoveCredential(name)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return message.NewOkResponse().BSONBytes()
+}
+
+// UsersInfo handles 'usersInfo' command.
+func (server *server) UsersInfo(conn *Conn, cmd *Command) (bson.Document, error) {
+	val := cmd.Elements[0].Value()
+
+	var users []*auth.User
+	if doc, ok := val.DocumentOK(); ok && doc.Lookup(forAllDBsElement).Boolean() {
+		users = server.accessManager.Users("")
+	} else if names, ok, err := parseUserNames(val, cmd.Database()); ok {
+		if err != nil {
+			return nil, err
+		}
+		users = []*auth.User{}
+		for _, name := range names {
+			if user, ok := server.accessManager.LookupUser(name); ok {
+				users = append(users, user)
+			}
+		}
+	} else {
+		users = server.accessManager.Users(cmd.Database())
+	}
+
+	userDocs := []any{}
+	for _, user := range users {
+		userDocs = append(userDocs, bson.Document(bsoncore.NewDocumentBuilder().
+			AppendString(idElement, user.ID()).
+			AppendString(userElement, user.User).
+			AppendString(dbElement, user.DB).
+			AppendArray(rolesElement, roleNamesArray(user.Roles)).
+			Build()))
+	}
+
+	res := message.NewOkResponse()
+	res.SetArrayElements(usersElement, userDocs)
+	return res.BSONBytes()
+}
+
+// CreateRole handles 'createRole' command.
+func (server *server) CreateRole(conn *Conn, cmd *Command) (bson.Document, error) {
+	role, ok := cmd.Elements[0].Value().StringValueOK()
+	if !ok || len(role) == 0 {
+		return nil, newErrMissingElement(cmd, cmd.Elements[0].Key())
+	}
+	privilegesVal, ok := cmd.Value(privilegesElement)
+	if !ok {
+		return nil, newErrMissingElement(cmd, privilegesElement)
+	}
+	privileges, err := parsePrivileges(privilegesVal, cmd.Database())
+	if err != nil {
+		return nil, err
+	}
+	roles, ok, err := commandRoleNames(cmd)
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		return nil, newErrMissingElement(cmd, rolesElement)
+	}
+	err = server.accessManager.CreateRole(auth.NewRole(auth.NewRoleName(role, cmd.Database()), privileges, roles))
+	if err != nil {
+		return nil, newAccessManagerError(err)
+	}
+	return message.NewOkResponse().BSONBytes()
+}
+
+// GrantRolesToUser handles 'grantRolesToUser' command.
+func (server *server) GrantRolesToUser(conn *Conn, cmd *Command) (bson.Document, error) {
+	name, roles, err := commandUserRoleNames(cmd)
+	if err != nil {
+		return nil, err
+	}
+	err = server.accessManager.GrantRolesToUser(name, roles...)
+	if err != nil {
+		return nil, newAccessManagerError(err)
+	}
+	return message.NewOkResponse().BSONBytes()
+}
+
+// RevokeRolesFromUser handles 'revokeRolesFromUser' command.
+func (server *server) RevokeRolesFromUser(conn *Conn, cmd *Command) (bson.Document, error) {
+	name, roles, err := commandUserRoleNames(cmd)
+	if err != nil {
+		return nil, err
+	}
+	err = server.accessManager.RevokeRolesFromUser(name, roles...)
+	if err != nil {
+		return nil, newAccessManagerError(err)
+	}
+	return message.NewOkResponse().BSONBytes()
+}
+
+// RolesInfo handles 'rolesInfo' command.
+func (server *server) RolesInfo(conn *Conn, cmd *Command) (bson.Document, error) {
+	showPrivileges := false
+	if val, ok := cmd.Value(showPrivilegesElement); ok {
+		showPrivileges = val.Boolean()
+	}
+
+	var roles []*auth.Role
+	if names, ok, err := parseRoleNames(cmd.Elements[0].Value(), cmd.Database()); ok {
+		if err != nil {
+			return nil, err
+		}
+		roles = []*auth.Role{}
+		for _, name := range names {
+			if role, ok := server.accessManager.LookupRole(name); ok {
+				roles = append(roles, role)
+			}
+		}
+	} else {
+		roles = server.accessManager.Roles(cmd.Database())
+		if val, ok := cmd.Value(showBuiltinRolesElement); ok && val.Boolean() {
+			for _, name := range auth.BuiltinRoleNames(cmd.Database()) {
+				if role, ok := server.accessManager.LookupRole(name); ok {
+					roles = append(roles, role)
+				}
+			}
+		}
+	}
+
+	roleDocs := []any{}
+	for _, role := range roles {
+		builder := bsoncore.NewDocumentBuilder().
+			AppendString(roleElement, role.Role).
+			AppendString(dbElement, role.DB).
+			AppendBoolean(isBuiltinElement, role.IsBuiltin()).
+			AppendArray(rolesElement, roleNamesArray(role.Roles))
+		if showPrivileges {
+			builder = builder.AppendArray(privilegesElement, privilegesArray(role.Privileges))
+		}
+		roleDocs = append(roleDocs, bson.Document(builder.Build()))
+	}
+
+	res := message.NewOkResponse()
+	res.SetArrayElements(rolesElement, roleDocs)
+	return res.BSONBytes()
+}
+
+// updateCredential updates the credential of the specified user if the command has a password.
+func (server *server) updateCredential(cmd *Command, name auth.UserName) error {
+	val, ok := cmd.Value(pwdElement)
+	if !ok {
+		return nil
+	}
+	pwd, ok := val.StringValueOK()
+	if !ok || len(pwd) == 0 {
+		return NewErrorf(ErrorCodeBadValue, "%s must be a non-empty string", pwdElement)
+	}
+	if store, ok := server.CredentialStore().(auth.SCRAMCredentialStore); ok {
+		return store.UpdateSCRAMCredential(name, pwd, server.SCRAMIterationCount())
+	}
+	updater, ok := server.CredentialStore().(auth.CredentialUpdater)
+	if !ok {
+		return NewErrorf(ErrorCodeCommandNotSupported, "credential store does not support updating passwords")
+	}
+	return updater.UpdateCredential(name, pwd)
+}
+
+func newAccessManagerError(err error) error {
+	switch {
+	case errors.Is(err, auth.ErrUserNotFound):
+		return message.NewErrorWith(ErrorCodeUserNotFound, err)
+	case errors.Is(err, auth.ErrUserExists):
+		return message.NewErrorWith(ErrorCodeUserAlreadyExists, err)
+	case errors.Is(err, auth.ErrRoleNotFound):
+		return message.NewErrorWith(ErrorCodeRoleNotFound, err)
+	case errors.Is(err, auth.ErrRoleExists):
+		return message.NewErrorWith(ErrorCodeRoleAlreadyExists, err)
+	}
+	return err
+}
+
+func newErrMissingElement(cmd *Command, key string) error {
+	return NewErrorf(ErrorCodeBadValue, "%s requires a valid '%s' field", cmd.Type(), key)
+}
+
+func commandUserName(cmd *Command) (auth.UserName, error) {
+	user, ok := cmd.Elements[0].Value().StringValueOK()
+	if !ok || len(user) == 0 {
+		return auth.UserName{}, newErrMissingElement(cmd, cmd.Elements[0].Key())
+	}
+	return auth.NewUserName(user, cmd.Database()), nil
+}
+
+func commandRoleNames(cmd *Command) ([]auth.RoleName, bool, error) {
+	val, ok := cmd.Value(rolesElement)
+	if !ok {
+		return nil, false, nil
+	}
+	if _, ok := val.ArrayOK(); !ok {
+		return nil, true, newErrMissingElement(cmd, rolesElement)
+	}
+	names, _, err := parseRoleNames(val, cmd.Database())
+	return names, true, err
+}
+
+func commandUserRoleNames(cmd *Command) (auth.UserName, []auth.RoleName, error) {
+	name, err := commandUserName(cmd)
+	if err != nil {
+		return auth.UserName{}, nil, err
+	}
+	roles, ok, err := commandRoleNames(cmd)
+	if err != nil {
+		return auth.UserName{}, nil, err
+	}
+	if !ok {
+		return auth.UserName{}, nil, newErrMissingElement(cmd, rolesElement)
+	}
+	return name, roles, nil
+}
+
+// parseUserNames parses a user name, a user document or an array of them, and returns false if the value is not any of them.
+func parseUserNames(val bson.Value, db string) ([]auth.UserName, bool, error) {
+	parseUserName := func(val bson.Value) (auth.UserName, error) {
+		if user, ok := val.StringValueOK(); ok {
+			return auth.NewUserName(user, db), nil
+		}
+		doc, ok := val.DocumentOK()
+		if !ok {
+			return auth.UserName{}, NewErrorf(ErrorCodeBadValue, "invalid user : %s", val.String())
+		}
+		user, ok := doc.Lookup(userElement).StringValueOK()
+		if !ok {
+			return auth.UserName{}, NewErrorf(ErrorCodeBadValue, "invalid user : %s", val.String())
+		}
+		userDB, ok := doc.Lookup(dbElement).StringValueOK()
+		if !ok {
+			userDB = db
+		}
+		return auth.NewUserName(user, userDB), nil
+	}
+	return parseNames(val, parseUserName)
+}
+
+// parseRoleNames parses a role name, a role document or an array of them, and returns false if the value is not any of them.
+func parseRoleNames(val bson.Value, db string) ([]auth.RoleName, bool, error) {
+	parseRoleName := func(val bson.Value) (auth.RoleName, error) {
+		if role, ok := val.StringValueOK(); ok {
+			return auth.NewRoleName(role, db), nil
+		}
+		doc, ok := val.DocumentOK()
+		if !ok {
+			return auth.RoleName{}, NewErrorf(ErrorCodeBadValue, "invalid role : %s", val.String())
+		}
+		role, ok := doc.Lookup(roleElement).StringValueOK()
+		if !ok {
+			return auth.RoleName{}, NewErrorf(ErrorCodeBadValue, "invalid role : %s", val.String())
+		}
+		roleDB, ok := doc.Lookup(dbElement).StringValueOK()
+		if !ok {
+			return auth.RoleName{}, NewErrorf(ErrorCodeBadValue, "invalid role : %s", val.String())
+		}
+		return auth.NewRoleName(role, roleDB), nil
+	}
+	return parseNames(val, parseRoleName)
+}
+
+func parseNames[T any](val bson.Value, parseName func(bson.Value) (T, error)) ([]T, bool, error) {
+	vals := []bson.Value{val}
+	if arr, ok := val.ArrayOK(); ok {
+		var err error
+		vals, err = arr.Values()
+		if err != nil {
+			return nil, true, err
+		}
+	} else if _, ok := val.StringValueOK(); !ok {
+		if _, ok := val.DocumentOK(); !ok {
+			return nil, false, nil
+		}
+	}
+	names := []T{}
+	for _, v := range vals {
+		name, err := parseName(v)
+		if err != nil {
+			return nil, true, err
+		}
+		names = append(names, name)
+	}
+	return names, true, nil
+}
+
+// parsePrivileges parses the privileges of a role of the specified database. The privileges of roles except for
+// the admin database must target only the database of the role.
+func parsePrivileges(val bson.Value, roleDB string) ([]auth.Privilege, error) {
+	arr, ok := val.ArrayOK()
+	if !ok {
+		return nil, NewErrorf(ErrorCodeBadValue, "%s must be an array", privilegesElement)
+	}
+	vals, err := arr.Values()
+	if err != nil {
+		return nil, err
+	}
+	privileges := []auth.Privilege{}
+	for _, v := range vals {
+		doc, ok := v.DocumentOK()
+		if !ok {
+			return nil, NewErrorf(ErrorCodeBadValue, "invalid privilege : %s", v.String())
+		}
+		resDoc, ok := doc.Lookup(resourceElement).DocumentOK()
+		if !ok {
+			return nil, NewErrorf(ErrorCodeBadValue, "invalid privilege : %s", v.String())
+		}
+		db, okDB := resDoc.Lookup(dbElement).StringValueOK()
+		col, okCol := resDoc.Lookup(collectionElement).StringValueOK()
+		if !okDB || !okCol {
+			return nil, NewErrorf(ErrorCodeBadValue, "unsupported resource : %s", resDoc.String())
+		}
+		if roleDB != auth.AdminDatabase && db != roleDB {
+			return nil, NewErrorf(ErrorCodeUnauthorized, "roles on the '%s' database cannot be granted privileges that target other databases : %s", roleDB, resDoc.String())
+		}
+		actionsArr, ok := doc.Lookup(actionsElement).ArrayOK()
+		if !ok {
+			return nil, NewErrorf(ErrorCodeBadValue, "invalid privilege : %s", v.String())
+		}
+		actionVals, err := actionsArr.Values()
+		if err != nil {
+			return nil, err
+		}
+		actions := []auth.Action{}
+		for _, actionVal := range actionVals {
+			action, ok := actionVal.StringValueOK()
+			if !ok || !auth.Action(action).IsValid() {
+				return nil, NewErrorf(ErrorCodeBadValue, "invalid action : %s", actionVal.String())
+			}
+			actions = append(actions, auth.Action(action))
+		}
+		privileges = append(privileges, auth.NewPrivilege(auth.NewResource(db, col), actions...))
+	}
+	return privileges, nil
+}
+
+func roleNamesArray(names []auth.RoleName) bsoncore.Array {
+	builder := bsoncore.NewArrayBuilder()
+	for _, name := range names {
+		builder.AppendDocument(bsoncore.NewDocumentBuilder().
+			AppendString(roleElement, name.Role).
+			AppendString(dbElement, name.DB).
+			Build())
+	}
+	return builder.Build()
+}
+
+func privilegesArray(privileges []auth.Privilege) bsoncore.Array {
+	builder := bsoncore.NewArrayBuilder()
+	for _, privilege := range privileges {
+		actions := bsoncore.NewArrayBuilder()
+		for _, action := range privilege.Actions {
+			actions.AppendString(string(action))
+		}
+		builder.AppendDocument(bsoncore.NewDocumentBuilder().
+			AppendDocument(resourceElement, bsoncore.NewDocumentBuilder().
+				AppendString(dbElement, privilege.Resource.DB).
+				AppendString(collectionElement, privilege.Resource.Collection).
+				Build()).
+			AppendArray(actionsElement, actions.Build()).
+			Build())
+	}
+	return builder.Build()
+}
@@ -0,0 +1,105 @@
+// Copyright (C) 2019 The go-mongo Authors. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package mongo
+
+import (
+	"time"
+
+	"github.com/cybergarage/go-mongo/mongo/auth"
+	"github.com/cybergarage/go-mongo/mongo/bson"
+	"github.com/cybergarage/go-mongo/mongo/message"
+)
+
+// See : x.509 - MongoDB Manual
+// https://www.mongodb.com/docs/manual/core/security-x.509/
+// MongoDB : Authentication - MONGODB-X509
+// https://github.com/mongodb/specifications/blob/master/source/auth/auth.md#mongodb-x509
+
+const (
+	// MechanismX509 is the mechanism name of X.509 authentication.
+	MechanismX509 = "MONGODB-X509"
+)
+
+const (
+	mechanismElement = "mechanism"
+	dbnameElement    = "dbname"
+)
+
+// Authenticate handles 'authenticate' command with MONGODB-X509 mechanism.
+func (server *server) Authenticate(conn *Conn, cmd *Command) (bson.Document, error) {
+	mech := ""
+	if val, ok := cmd.Value(mechanismElement); ok {
+		mech, _ = val.StringValueOK()
+	}
+	if mech != MechanismX509 {
+		return nil, NewErrorf(ErrorCodeMechanismUnavailable, "unsupported mechanism : %s", mech)
+	}
+
+	if cmd.Database() != auth.ExternalDatabase {
+		return nil, NewErrorf(ErrorCodeBadValue, "X.509 authentication must always use the %s database", auth.ExternalDatabase)
+	}
+
+	// Failed attempts are limited as SASL authentication.
+
+	convMgr := server.ConversationManager()
+	connID := conn.UUID().String()
+	if err := convMgr.CheckAttempt(connID); err != nil {
+		return nil, message.NewErrorWith(ErrorCodeAuthenticationFailed, err)
+	}
+
+	name, err := server.authenticateX509(conn, cmd)
+	if err != nil {
+		waitFailureDelay(conn, convMgr.RecordFailure(connID, name))
+		return nil, err
+	}
+	convMgr.RecordSuccess(connID, name)
+
+	conn.SetAuthrized(true)
+	conn.SetUserName(name)
+	conn.SetAuthenticationExpiration(time.Time{})
+
+	res := message.NewOkResponse()
+	res.SetStringElement(dbnameElement, auth.ExternalDatabase)
+	res.SetStringElement(userElement, name.User)
+	return res.BSONBytes()
+}
+
+// authenticateX509 returns the user of the subject of the verified client certificate,
+// or the user of the request with an error if the user is not authenticated.
+func (server *server) authenticateX509(conn *Conn, cmd *Command) (auth.UserName, error) {
+	user := ""
+	if val, ok := cmd.Value(userElement); ok {
+		user, _ = val.StringValueOK()
+	}
+	name := auth.NewUserName(user, auth.ExternalDatabase)
+
+	// The subject of the verified client certificate is the user name.
+
+	state, ok := conn.TLSConnectionState()
+	if !ok || len(state.VerifiedChains) == 0 || len(state.PeerCertificates) == 0 {
+		return name, NewErrorf(ErrorCodeAuthenticationFailed, "no verified subject name available from client")
+	}
+	subject := state.PeerCertificates[0].Subject.String()
+
+	if _, ok := cmd.Value(userElement); ok && user != subject {
+		return name, NewErrorf(ErrorCodeAuthenticationFailed, "there is no x.509 client certificate matching the user")
+	}
+
+	name = auth.NewUserName(subject, auth.ExternalDatabase)
+	if _, ok := server.accessManager.LookupUser(name); !ok {
+		return name, NewErrorf(ErrorCodeAuthenticationFailed, "could not find user %s", name)
+	}
+	return name, nil
+}
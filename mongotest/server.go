@@ -15,7 +15,10 @@
 package mongotest
 
 import (
+	"sync"
+
 	"github.com/cybergarage/go-mongo/examples/go-mongod/server"
+	mongoauth "github.com/cybergarage/go-mongo/mongo/auth"
 	"github.com/cybergarage/go-mongo/mongo/auth/sasl"
 	"github.com/cybergarage/go-mongo/mongo/auth/tls"
 	"github.com/cybergarage/go-sasl/sasl/auth"
@@ -23,15 +26,23 @@ import (
 
 type Server struct {
 	*server.Server
+	mutex     sync.Mutex
+	passwords map[mongoauth.UserName]string
 }
 
 // NewServer returns a test server instance.
 func NewServer() *Server {
 	server := &Server{
-		Server: server.NewServer(),
+		Server:    server.NewServer(),
+		mutex:     sync.Mutex{},
+		passwords: map[mongoauth.UserName]string{mongoauth.NewUserName(TestUsername, mongoauth.AdminDatabase): TestPassword},
 	}
 	server.SetCertificateAuthenticator(server)
 	server.SetCredentialStore(server)
+	_ = server.AccessManager().CreateUser(
+		mongoauth.NewUser(
+			mongoauth.NewUserName(TestUsername, mongoauth.AdminDatabase),
+			mongoauth.NewRoleName(mongoauth.RoleRoot, mongoauth.AdminDatabase)))
 	return server
 }
 
@@ -41,16 +52,17 @@ func (server *Server) VerifyCertificate(conn tls.Conn) (bool, error) {
 }
 
 // LookupCredential looks up a credential by the query.
+// The query has no database, so the test server looks up the user of the name in any database.
 func (server *Server) LookupCredential(q auth.Query) (auth.Credential, bool, error) {
 	username := q.Username()
-	if username != TestUsername {
+	passwod, ok := server.lookupPassword(username)
+	if !ok {
 		return nil, false, auth.ErrNoCredential
 	}
-	passwod := TestPassword
 	mech := q.Mechanism()
 	if mech == "SCRAM-SHA-1" {
 		var err error
-		passwod, err = sasl.MongoPasswordDigest(TestUsername, TestPassword)
+		passwod, err = sasl.MongoPasswordDigest(username, passwod)
 		if err != nil {
 			return nil, false, auth.ErrNoCredential
 		}
@@ -61,3 +73,37 @@ func (server *Server) LookupCredential(q auth.Query) (auth.Credential, bool, err
 	)
 	return cred, true, nil
 }
+
+// lookupPassword returns the password of the user of the specified name in any database.
+func (server *Server) lookupPassword(username string) (string, bool) {
+	server.mutex.Lock()
+	defer server.mutex.Unlock()
+	for name, password := range server.passwords {
+		if name.User == username {
+			return password, true
+		}
+	}
+	return "", false
+}
+
+// UpdateCredential sets the password of the specified user.
+func (server *Server) UpdateCredential(name mongoauth.UserName, password string) error {
+	server.mutex.Lock()
+	defer server.mutex.Unlock()
+	server.passwords[name] = password
+	return nil
+}
+
+// RemoveCredential removes the password of the specified user.
+func (server *Server) RemoveCredential(name mongoauth.UserName) error {
+	server.mutex.Lock()
+	defer server.mutex.Unlock()
+	delete(server.passwords, name)
+	return nil
+}
+
+// VerifyPlain verifies the PLAIN credential as a local stand-in for an external directory.
+func (server *Server) VerifyPlain(username string, password string) (bool, error) {
+	passwd, ok := server.lookupPassword(username)
+	return ok && passwd == password, nil
+}
@@ -17,7 +17,9 @@ package mongotest
 import (
 	"testing"
 
+	"github.com/cybergarage/go-mongo/mongo/auth"
 	"github.com/cybergarage/go-mongo/mongo/auth/sasl"
+	mongoscram "github.com/cybergarage/go-mongo/mongo/auth/sasl/scram"
 	"github.com/cybergarage/go-sasl/sasl/scram"
 	xgoscram "github.com/xdg-go/scram"
 )
@@ -126,8 +128,91 @@ func SCRAMServerTest(t *testing.T) {
 	}
 }
 
+func SCRAMCredentialStoreTest(t *testing.T) {
+	t.Helper()
+
+	store := auth.NewSCRAMCredentialStore()
+	err := store.UpdateCredential(auth.NewUserName(TestUsername, auth.AdminDatabase), TestPassword)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	server := NewServer()
+	server.SetCredentialStore(store)
+
+	newClient := func(mechName string, password string) (*xgoscram.Client, error) {
+		if mechName == "SCRAM-SHA-1" {
+			digest, err := sasl.MongoPasswordDigest(TestUsername, password)
+			if err != nil {
+				return nil, err
+			}
+			return xgoscram.SHA1.NewClientUnprepped(TestUsername, digest, "")
+		}
+		return xgoscram.SHA256.NewClient(TestUsername, password, "")
+	}
+
+	authenticate := func(mechName string, password string) error {
+		client, err := newClient(mechName, password)
+		if err != nil {
+			return err
+		}
+		mech, err := server.Mechanism(mechName)
+		if err != nil {
+			return err
+		}
+		ctx, err := mech.Start()
+		if err != nil {
+			return err
+		}
+		ctx.SetValue(mongoscram.DatabaseID, auth.AdminDatabase)
+		conv := client.NewConversation()
+		clientMsg, err := conv.Step("")
+		if err != nil {
+			return err
+		}
+		for !ctx.Done() {
+			serverMsg, err := ctx.Next(sasl.SASLPayload(clientMsg))
+			if err != nil {
+				return err
+			}
+			clientMsg, err = conv.Step(serverMsg.String())
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	for _, mechName := range []string{"SCRAM-SHA-1", "SCRAM-SHA-256"} {
+		t.Run(mechName, func(t *testing.T) {
+			cred, ok, err := store.LookupSCRAMCredential(mechName, auth.AdminDatabase, TestUsername)
+			if err != nil || !ok {
+				t.Errorf("%s credential is not found (%v)", mechName, err)
+				return
+			}
+			if cred.IterationCount != auth.DefaultSCRAMIterationCount || len(cred.Salt) == 0 {
+				t.Errorf("invalid credential : %v", cred)
+			}
+
+			err = authenticate(mechName, TestPassword)
+			if err != nil {
+				t.Error(err)
+			}
+
+			err = authenticate(mechName, "invalid")
+			if err == nil {
+				t.Errorf("invalid password is authenticated")
+			}
+		})
+	}
+}
+
 func TestAuthMechanisms(t *testing.T) {
 	t.Run("SCRAM", func(t *testing.T) {
 		SCRAMServerTest(t)
 	})
+	t.Run("SCRAM credential store", func(t *testing.T) {
+		SCRAMCredentialStoreTest(t)
+	})
 }
@@ -0,0 +1,111 @@
+// Copyright (C) 2022 The go-mongo Authors All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package mongotest
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/cybergarage/go-logger/log"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+func TestAuthorizationServer(t *testing.T) {
+	log.EnableStdoutDebug(true)
+
+	server := NewServer()
+	server.SetAuthrizationEnabled(true)
+	startTestServer(t, server)
+
+	connect := func(t *testing.T, cred *options.Credential) *mongo.Client {
+		t.Helper()
+		clientOptions := options.Client().ApplyURI(testDBURL)
+		if cred != nil {
+			clientOptions.SetAuth(*cred)
+		}
+		client, err := mongo.Connect(context.TODO(), clientOptions)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return client
+	}
+
+	trainer := Trainer{"Ash", 10, "Pallet Town"}
+
+	// Unauthenticated clients can run only the allowed commands
+
+	t.Run("unauthenticated", func(t *testing.T) {
+		client := connect(t, nil)
+		defer client.Disconnect(context.TODO())
+
+		err := client.Ping(context.TODO(), nil)
+		if err != nil {
+			t.Error(err)
+			return
+		}
+
+		collection := client.Database("test").Collection("authorization")
+		_, err = collection.InsertOne(context.TODO(), trainer)
+		var cmdErr mongo.CommandError
+		if !errors.As(err, &cmdErr) || cmdErr.Code != 13 || cmdErr.Name != "Unauthorized" {
+			t.Errorf("%v is not an unauthorized error", err)
+			return
+		}
+
+		err = collection.FindOne(context.TODO(), bson.D{}).Err()
+		if !errors.As(err, &cmdErr) || cmdErr.Code != 13 {
+			t.Errorf("%v is not an unauthorized error", err)
+		}
+	})
+
+	// Clients with an invalid password can not authenticate
+
+	t.Run("invalid password", func(t *testing.T) {
+		client := connect(t, &options.Credential{Username: TestUsername, Password: "invalid"})
+		defer client.Disconnect(context.TODO())
+
+		err := client.Ping(context.TODO(), nil)
+		if err == nil {
+			t.Errorf("invalid password is authenticated")
+		}
+	})
+
+	// Authenticated clients can run all commands
+
+	t.Run("authenticated", func(t *testing.T) {
+		client := connect(t, &options.Credential{Username: TestUsername, Password: TestPassword})
+		defer client.Disconnect(context.TODO())
+
+		collection := client.Database("test").Collection("authorization")
+		_, err := collection.InsertOne(context.TODO(), trainer)
+		if err != nil {
+			t.Error(err)
+			return
+		}
+
+		var result Trainer
+		err = collection.FindOne(context.TODO(), bson.D{{Key: "name", Value: trainer.Name}}).Decode(&result)
+		if err != nil {
+			t.Error(err)
+			return
+		}
+		if result != trainer {
+			t.Errorf("%v != %v", result, trainer)
+		}
+	})
+}
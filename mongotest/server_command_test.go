@@ -0,0 +1,159 @@
+// Copyright (C) 2022 The go-mongo Authors All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package mongotest
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/cybergarage/go-logger/log"
+	mongod "github.com/cybergarage/go-mongo/mongo"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/x/bsonx/bsoncore"
+)
+
+func TestCommandRegistryServer(t *testing.T) {
+	log.EnableStdoutDebug(true)
+
+	server := NewServer()
+	server.RegisterCommand(
+		mongod.NewCommandSpec("echoTest",
+			func(conn *mongod.Conn, cmd *mongod.Command) (mongod.Document, error) {
+				return bsoncore.NewDocumentBuilder().
+					AppendString("echo", cmd.Type()).
+					AppendDouble("ok", 1).
+					Build(), nil
+			},
+			mongod.WithCommandHelp("echoes the command name"),
+			mongod.WithCommandAuthRequired(false),
+			mongod.WithCommandSecondaryAllowed(true)))
+	server.RegisterCommand(
+		mongod.NewCommandSpec("adminTest",
+			func(conn *mongod.Conn, cmd *mongod.Command) (mongod.Document, error) {
+				return bsoncore.NewDocumentBuilder().AppendDouble("ok", 1).Build(), nil
+			},
+			mongod.WithCommandAdminOnly(true)))
+	startTestServer(t, server)
+
+	client := connectTestClient(t)
+
+	isErrorCode := func(err error, code mongod.ErrorCode) bool {
+		var cmdErr mongo.CommandError
+		return errors.As(err, &cmdErr) && cmdErr.Code == int32(code)
+	}
+
+	t.Run("CommandNotFound", func(t *testing.T) {
+		err := client.Database("test").RunCommand(context.TODO(), bson.D{{Key: "unknownTest", Value: 1}}).Err()
+		if !isErrorCode(err, mongod.ErrorCodeCommandNotFound) {
+			t.Errorf("%v is not a CommandNotFound error", err)
+		}
+	})
+
+	t.Run("RegisterCommand", func(t *testing.T) {
+		var res bson.M
+		err := client.Database("test").RunCommand(context.TODO(), bson.D{{Key: "ECHOTEST", Value: 1}}).Decode(&res)
+		if err != nil {
+			t.Error(err)
+			return
+		}
+		if res["echo"] != "echotest" {
+			t.Errorf("%v", res)
+		}
+	})
+
+	t.Run("AdminOnly", func(t *testing.T) {
+		err := client.Database("test").RunCommand(context.TODO(), bson.D{{Key: "adminTest", Value: 1}}).Err()
+		if !isErrorCode(err, mongod.ErrorCodeUnauthorized) {
+			t.Errorf("%v is not an Unauthorized error", err)
+		}
+		err = client.Database("admin").RunCommand(context.TODO(), bson.D{{Key: "adminTest", Value: 1}}).Err()
+		if err != nil {
+			t.Error(err)
+		}
+	})
+
+	t.Run("listCommands", func(t *testing.T) {
+		var res struct {
+			Commands map[string]bson.M `bson:"commands"`
+		}
+		err := client.Database("admin").RunCommand(context.TODO(), bson.D{{Key: "listCommands", Value: 1}}).Decode(&res)
+		if err != nil {
+			t.Error(err)
+			return
+		}
+		for _, spec := range server.Commands() {
+			if _, ok := res.Commands[spec.Name()]; !ok {
+				t.Errorf("%s is not listed", spec.Name())
+			}
+		}
+		expected := map[string]bson.M{
+			"echoTest":    {"help": "echoes the command name", "adminOnly": false, "requiresAuth": false, "secondaryOk": true},
+			"adminTest":   {"adminOnly": true, "requiresAuth": true, "secondaryOk": false},
+			"hello":       {"requiresAuth": false, "secondaryOk": true},
+			"createUser":  {"adminOnly": false, "requiresAuth": true, "secondaryOk": false},
+			"find":        {"adminOnly": false, "requiresAuth": true, "secondaryOk": true},
+			"insert":      {"adminOnly": false, "requiresAuth": true, "secondaryOk": false},
+			"update":      {"secondaryOk": false},
+			"delete":      {"secondaryOk": false},
+			"getMore":     {"secondaryOk": true},
+			"killCursors": {"secondaryOk": true},
+		}
+		for name, fields := range expected {
+			cmd, ok := res.Commands[name]
+			if !ok {
+				t.Errorf("%s is not listed", name)
+				continue
+			}
+			for key, value := range fields {
+				if cmd[key] != value {
+					t.Errorf("%s.%s : %v != %v", name, key, cmd[key], value)
+				}
+			}
+		}
+	})
+
+	t.Run("UnregisterCommand", func(t *testing.T) {
+		server.UnregisterCommand("EchoTest")
+		if _, ok := server.LookupCommand("echoTest"); ok {
+			t.Errorf("echoTest is not unregistered")
+		}
+		err := client.Database("test").RunCommand(context.TODO(), bson.D{{Key: "echoTest", Value: 1}}).Err()
+		if !isErrorCode(err, mongod.ErrorCodeCommandNotFound) {
+			t.Errorf("%v is not a CommandNotFound error", err)
+		}
+	})
+
+	t.Run("QueryCommand", func(t *testing.T) {
+		coll := client.Database("test").Collection("registry")
+		_, err := coll.InsertOne(context.TODO(), bson.D{{Key: "name", Value: "ash"}})
+		if err != nil {
+			t.Error(err)
+			return
+		}
+		var doc bson.M
+		err = coll.FindOne(context.TODO(), bson.D{{Key: "name", Value: "ash"}}).Decode(&doc)
+		if err != nil {
+			t.Error(err)
+		}
+
+		server.UnregisterCommand("insert")
+		_, err = coll.InsertOne(context.TODO(), bson.D{{Key: "name", Value: "misty"}})
+		if !isErrorCode(err, mongod.ErrorCodeCommandNotFound) {
+			t.Errorf("%v is not a CommandNotFound error", err)
+		}
+	})
+}
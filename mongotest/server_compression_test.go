@@ -0,0 +1,165 @@
+// Copyright (C) 2022 The go-mongo Authors All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package mongotest
+
+import (
+	"context"
+	"io"
+	"sync"
+	"testing"
+
+	"github.com/cybergarage/go-logger/log"
+	mongod "github.com/cybergarage/go-mongo/mongo"
+	"github.com/cybergarage/go-mongo/mongo/protocol"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"go.mongodb.org/mongo-driver/x/bsonx/bsoncore"
+)
+
+func TestCompressionServer(t *testing.T) {
+	log.EnableStdoutDebug(true)
+
+	compressors := []string{
+		"snappy",
+		"zlib",
+		"zstd",
+	}
+
+	for _, compressor := range compressors {
+		t.Run(compressor, func(t *testing.T) {
+			listener := &testOpCodeListener{
+				mutex:   sync.Mutex{},
+				opCodes: map[protocol.OpCode]int{},
+			}
+
+			server := NewServer()
+			server.SetCompressions(compressors...)
+			server.SetMessageListener(listener)
+			server.AddInterceptor(mongod.InterceptorFunc(func(conn *mongod.Conn, req *mongod.Request, next mongod.RequestHandler) (mongod.Document, error) {
+				if _, ok := req.Command.Value("rejectTest"); ok {
+					return nil, mongod.NewErrorf(mongod.ErrorCodeUnauthorized, "%s is rejected", req.Command.Type())
+				}
+				return next(conn, req)
+			}))
+
+			err := server.Start()
+			if err != nil {
+				t.Error(err)
+				return
+			}
+
+			// Connect to MongoDB using the Go Driver
+
+			client := connectTestClient(t, options.Client().SetCompressors([]string{compressor}))
+
+			t.Run("Tutorial", func(t *testing.T) {
+				RunClientTest(t, client)
+			})
+
+			// The replies except the handshakes must be compressed
+
+			if n := listener.Count(protocol.OpCompressed); n == 0 {
+				t.Errorf("no replies are compressed : %v", listener.opCodes)
+			}
+			if n := listener.Count(protocol.OpMsg) + listener.Count(protocol.OpReply); n == 0 {
+				t.Errorf("handshake replies are compressed : %v", listener.opCodes)
+			}
+
+			// The replies to the rejected handshakes must not be compressed either
+
+			t.Run("RejectedHello", func(t *testing.T) {
+				conn := dialTestServer(t)
+				_, err := runTestCommand(conn, bsoncore.NewDocumentBuilder().
+					AppendInt32("hello", 1).
+					AppendArray("compression", bsoncore.NewArrayBuilder().AppendString(compressor).Build()).
+					AppendString("$db", "admin").
+					Build())
+				if err != nil {
+					t.Error(err)
+					return
+				}
+				readOpCode := func(cmd bsoncore.Document) protocol.OpCode {
+					_, err := conn.Write(protocol.NewMsgWithBody(cmd).Bytes())
+					if err != nil {
+						t.Error(err)
+						return 0
+					}
+					headerBytes := make([]byte, protocol.HeaderSize)
+					_, err = io.ReadFull(conn, headerBytes)
+					if err != nil {
+						t.Error(err)
+						return 0
+					}
+					header, err := protocol.NewHeaderWithBytes(headerBytes)
+					if err != nil {
+						t.Error(err)
+						return 0
+					}
+					_, err = io.CopyN(io.Discard, conn, int64(header.BodySize()))
+					if err != nil {
+						t.Error(err)
+					}
+					return header.OpCode()
+				}
+				for _, name := range []string{"ping", "hello", "saslStart"} {
+					expected := protocol.OpMsg
+					if name == "ping" {
+						expected = protocol.OpCompressed
+					}
+					cmd := bsoncore.NewDocumentBuilder().
+						AppendInt32(name, 1).
+						AppendBoolean("rejectTest", true).
+						AppendString("$db", "admin").
+						Build()
+					if opCode := readOpCode(cmd); opCode != expected {
+						t.Errorf("%s : %v != %v", name, opCode, expected)
+					}
+				}
+			})
+
+			err = client.Disconnect(context.TODO())
+			if err != nil {
+				t.Error(err)
+			}
+
+			err = server.Stop()
+			if err != nil {
+				t.Error(err)
+				return
+			}
+		})
+	}
+}
+
+// testOpCodeListener counts the response messages by the opcodes.
+type testOpCodeListener struct {
+	mutex   sync.Mutex
+	opCodes map[protocol.OpCode]int
+}
+
+func (l *testOpCodeListener) MessageReceived(msg mongod.OpMessage) {
+}
+
+func (l *testOpCodeListener) MessageRespond(msg mongod.OpMessage) {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	l.opCodes[msg.OpCode()]++
+}
+
+// Count returns the number of the response messages of the specified opcode.
+func (l *testOpCodeListener) Count(opCode protocol.OpCode) int {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	return l.opCodes[opCode]
+}
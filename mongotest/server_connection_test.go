@@ -0,0 +1,196 @@
+// Copyright (C) 2022 The go-mongo Authors All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package mongotest
+
+import (
+	"crypto/tls"
+	"errors"
+	"io"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/cybergarage/go-logger/log"
+	"github.com/cybergarage/go-mongo/mongo/protocol"
+	"go.mongodb.org/mongo-driver/x/bsonx/bsoncore"
+)
+
+func TestConnectionLimitServer(t *testing.T) {
+	log.EnableStdoutDebug(true)
+
+	hello := func(conn net.Conn) (bsoncore.Document, error) {
+		return runTestCommand(conn, bsoncore.NewDocumentBuilder().
+			AppendInt32("hello", 1).
+			AppendString("$db", "admin").
+			Build())
+	}
+
+	waitFor := func(cond func() bool) bool {
+		for n := 0; n < 100; n++ {
+			if cond() {
+				return true
+			}
+			time.Sleep(20 * time.Millisecond)
+		}
+		return false
+	}
+
+	// Connections over the limit are refused
+
+	t.Run("MaxIncomingConnections", func(t *testing.T) {
+		server := NewServer()
+		server.SetMaxIncomingConnections(2)
+		startTestServer(t, server)
+
+		conns := []net.Conn{dialTestServer(t), dialTestServer(t)}
+		for _, conn := range conns {
+			if _, err := hello(conn); err != nil {
+				t.Error(err)
+				return
+			}
+		}
+
+		if _, err := hello(dialTestServer(t)); err == nil {
+			t.Errorf("connection over the limit is accepted")
+		}
+
+		if n := server.NumConns(); n != 2 {
+			t.Errorf("%d != %d", n, 2)
+		}
+
+		conns[0].Close()
+		if !waitFor(func() bool { return server.NumConns() == 1 }) {
+			t.Errorf("%d != %d", server.NumConns(), 1)
+		}
+
+		if _, err := hello(dialTestServer(t)); err != nil {
+			t.Error(err)
+		}
+
+		if n := server.PeakNumConns(); n != 2 {
+			t.Errorf("%d != %d", n, 2)
+		}
+	})
+
+	// Idle connections are closed
+
+	t.Run("IdleTimeout", func(t *testing.T) {
+		idleTimeout := 300 * time.Millisecond
+		server := NewServer()
+		server.SetIdleTimeout(idleTimeout)
+		startTestServer(t, server)
+
+		conn := dialTestServer(t)
+		for n := 0; n < 3; n++ {
+			if _, err := hello(conn); err != nil {
+				t.Errorf("active connection is closed : %s", err)
+				return
+			}
+			time.Sleep(idleTimeout / 2)
+		}
+
+		if !waitFor(func() bool { return server.NumConns() == 0 }) {
+			t.Errorf("idle connection is not closed")
+		}
+		if _, err := readTestReplyDocument(conn); err == nil {
+			t.Errorf("idle connection is not closed")
+		}
+		if n := server.PeakNumConns(); n != 1 {
+			t.Errorf("%d != %d", n, 1)
+		}
+	})
+
+	// Stalled request messages are closed
+
+	t.Run("ReadTimeout", func(t *testing.T) {
+		readTimeout := 200 * time.Millisecond
+		server := NewServer()
+		server.SetReadTimeout(readTimeout)
+		addr := serveTestServer(t, server)
+
+		conn, err := net.Dial("tcp", addr)
+		if err != nil {
+			t.Error(err)
+			return
+		}
+		defer conn.Close()
+
+		// Waiting for a new request is not limited by the read timeout
+
+		time.Sleep(readTimeout * 2)
+		if _, err := hello(conn); err != nil {
+			t.Error(err)
+			return
+		}
+
+		// A partial message is closed after the read timeout
+
+		reqBytes := protocol.NewMsgWithBody(bsoncore.NewDocumentBuilder().
+			AppendInt32("hello", 1).
+			AppendString("$db", "admin").
+			Build()).Bytes()
+		if _, err := conn.Write(reqBytes[:protocol.HeaderSize]); err != nil {
+			t.Error(err)
+			return
+		}
+		conn.SetReadDeadline(time.Now().Add(readTimeout * 25))
+		if _, err := conn.Read(make([]byte, 1)); !errors.Is(err, io.EOF) {
+			t.Errorf("stalled connection is not closed : %v", err)
+		}
+	})
+
+	// Handshaking connections reserve the slots, and are closed when the server stops
+
+	t.Run("TLSHandshake", func(t *testing.T) {
+		server := NewServer()
+		server.SetTLSEnabled(true)
+		server.SetServerKey(TestSeverKey)
+		server.SetServerCert(TestServerCert)
+		server.SetRootCerts(TestCACert)
+		server.SetTLSHandshakeTimeout(time.Minute)
+		server.SetMaxIncomingConnections(1)
+		startTestServer(t, server)
+
+		stalledConn := dialTestServer(t)
+		if !waitFor(func() bool { return server.NumConns() == 1 }) {
+			t.Errorf("handshaking connection is not counted")
+		}
+
+		tlsConfig, err := server.TLSConfig()
+		if err != nil {
+			t.Error(err)
+			return
+		}
+		tlsConfig = tlsConfig.Clone()
+		tlsConfig.ServerName = "localhost"
+		conn, err := tls.Dial("tcp", "localhost:27017", tlsConfig)
+		if err == nil {
+			defer conn.Close()
+			if _, err := hello(conn); err == nil {
+				t.Errorf("connection over the limit is accepted")
+			}
+		}
+
+		err = server.Stop()
+		if err != nil {
+			t.Error(err)
+			return
+		}
+		stalledConn.SetReadDeadline(time.Now().Add(time.Second))
+		if _, err := stalledConn.Read(make([]byte, 1)); !errors.Is(err, io.EOF) {
+			t.Errorf("handshaking connection is not closed : %v", err)
+		}
+	})
+}
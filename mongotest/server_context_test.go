@@ -0,0 +1,168 @@
+// Copyright (C) 2022 The go-mongo Authors All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package mongotest
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/cybergarage/go-logger/log"
+	mongod "github.com/cybergarage/go-mongo/mongo"
+	"github.com/cybergarage/go-mongo/mongo/protocol"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"go.mongodb.org/mongo-driver/x/bsonx/bsoncore"
+)
+
+// testBlockingExecutor blocks find queries until the request context is cancelled.
+type testBlockingExecutor struct {
+	*Server
+	ops       chan *mongod.Operation
+	causes    chan error
+	completes atomic.Bool
+}
+
+func (executor *testBlockingExecutor) Find(conn *mongod.Conn, q *mongod.Query) ([]mongod.Document, error) {
+	op, ok := conn.Operation()
+	if !ok {
+		return nil, fmt.Errorf("no operation")
+	}
+	executor.ops <- op
+	ctx := conn.RequestContext()
+	<-ctx.Done()
+	executor.causes <- context.Cause(ctx)
+	if executor.completes.Load() {
+		return []mongod.Document{}, nil
+	}
+	return nil, ctx.Err()
+}
+
+func TestRequestContextServer(t *testing.T) {
+	log.EnableStdoutDebug(true)
+
+	server := NewServer()
+	executor := &testBlockingExecutor{
+		Server:    server,
+		ops:       make(chan *mongod.Operation, 1),
+		causes:    make(chan error, 1),
+		completes: atomic.Bool{},
+	}
+	server.SetUserCommandExecutor(executor)
+	startTestServer(t, server)
+
+	client := connectTestClient(t)
+
+	collection := client.Database("test").Collection("context")
+
+	waitForCause := func(t *testing.T, code mongod.ErrorCode) {
+		t.Helper()
+		select {
+		case cause := <-executor.causes:
+			if !errors.Is(cause, mongod.NewError(code, "")) {
+				t.Errorf("%v is not %s", cause, code.Name())
+			}
+		case <-time.After(5 * time.Second):
+			t.Errorf("request context is not cancelled")
+		}
+	}
+
+	// The request is cancelled when maxTimeMS expires
+
+	t.Run("maxTimeMS", func(t *testing.T) {
+		_, err := collection.Find(context.TODO(), bson.D{}, options.Find().SetMaxTime(100*time.Millisecond))
+		var cmdErr mongo.CommandError
+		if !errors.As(err, &cmdErr) || cmdErr.Code != int32(mongod.ErrorCodeMaxTimeMSExpired) {
+			t.Errorf("%v is not a MaxTimeMSExpired error", err)
+		}
+		<-executor.ops
+		waitForCause(t, mongod.ErrorCodeMaxTimeMSExpired)
+	})
+
+	// The result is replied if the executor completes after the cancellation
+
+	t.Run("completed", func(t *testing.T) {
+		executor.completes.Store(true)
+		defer executor.completes.Store(false)
+		_, err := collection.Find(context.TODO(), bson.D{}, options.Find().SetMaxTime(100*time.Millisecond))
+		if err != nil {
+			t.Error(err)
+		}
+		<-executor.ops
+		waitForCause(t, mongod.ErrorCodeMaxTimeMSExpired)
+	})
+
+	// The request is cancelled by killOp
+
+	t.Run("killOp", func(t *testing.T) {
+		findErr := make(chan error, 1)
+		go func() {
+			_, err := collection.Find(context.TODO(), bson.D{})
+			findErr <- err
+		}()
+
+		op := <-executor.ops
+		if op.ID() <= 0 || op.Document() == nil {
+			t.Errorf("invalid operation (%d)", op.ID())
+		}
+
+		err := client.Database("admin").RunCommand(context.TODO(), bson.D{{Key: "killOp", Value: 1}, {Key: "op", Value: op.ID()}}).Err()
+		if err != nil {
+			t.Error(err)
+			return
+		}
+
+		var cmdErr mongo.CommandError
+		if err := <-findErr; !errors.As(err, &cmdErr) || cmdErr.Code != int32(mongod.ErrorCodeInterrupted) {
+			t.Errorf("%v is not an Interrupted error", err)
+		}
+		waitForCause(t, mongod.ErrorCodeInterrupted)
+
+		// killOp requires the operation identifier
+
+		err = client.Database("admin").RunCommand(context.TODO(), bson.D{{Key: "killOp", Value: 1}}).Err()
+		if !errors.As(err, &cmdErr) || cmdErr.Code != int32(mongod.ErrorCodeBadValue) {
+			t.Errorf("%v is not a BadValue error", err)
+		}
+	})
+
+	// The request is cancelled when the client disconnects
+
+	t.Run("disconnect", func(t *testing.T) {
+		conn, err := net.Dial("tcp", "localhost:27017")
+		if err != nil {
+			t.Error(err)
+			return
+		}
+		reqMsg := protocol.NewMsgWithBody(bsoncore.NewDocumentBuilder().
+			AppendString("find", "context").
+			AppendString("$db", "test").
+			Build())
+		_, err = conn.Write(reqMsg.Bytes())
+		if err != nil {
+			t.Error(err)
+			return
+		}
+
+		<-executor.ops
+		conn.Close()
+		waitForCause(t, mongod.ErrorCodeClientDisconnect)
+	})
+}
@@ -0,0 +1,286 @@
+// Copyright (C) 2022 The go-mongo Authors All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package mongotest
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/cybergarage/go-logger/log"
+	"github.com/cybergarage/go-mongo/mongo/auth"
+	"github.com/cybergarage/go-mongo/mongo/protocol"
+	"go.mongodb.org/mongo-driver/x/bsonx/bsoncore"
+
+	xgoscram "github.com/xdg-go/scram"
+)
+
+func TestSASLConversationServer(t *testing.T) {
+	log.EnableStdoutDebug(true)
+
+	const (
+		otherUsername = "other"
+		otherPassword = "otherpasswd"
+	)
+
+	server := NewServer()
+	server.SetAuthrizationEnabled(true)
+	err := server.UpdateCredential(auth.NewUserName(otherUsername, auth.AdminDatabase), otherPassword)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	err = server.AccessManager().CreateUser(
+		auth.NewUser(auth.NewUserName(otherUsername, auth.AdminDatabase), auth.NewRoleName(auth.RoleRead, "test")))
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	convMgr := server.ConversationManager()
+	convMgr.SetConversationTimeout(200 * time.Millisecond)
+	convMgr.SetFailedAttemptLimit(2)
+	convMgr.SetFailedAttemptBackoff(500 * time.Millisecond)
+
+	startTestServer(t, server)
+
+	conn := dialTestServer(t)
+
+	requestID := int32(0)
+	runCommand := func(cmd bsoncore.Document) (bsoncore.Document, error) {
+		requestID++
+		reqMsg := protocol.NewMsgWithBody(cmd)
+		reqMsg.SetRequestID(requestID)
+		_, err := conn.Write(reqMsg.Bytes())
+		if err != nil {
+			return nil, err
+		}
+		return readTestReplyDocument(conn)
+	}
+
+	isOk := func(res bsoncore.Document) bool {
+		ok, _ := res.Lookup("ok").DoubleOK()
+		return ok == 1
+	}
+
+	// authenticate runs a SCRAM-SHA-256 conversation, and returns the last reply.
+
+	authenticate := func(username string, password string, delay time.Duration) (bsoncore.Document, error) {
+		client, err := xgoscram.SHA256.NewClient(username, password, "")
+		if err != nil {
+			return nil, err
+		}
+		conv := client.NewConversation()
+		clientMsg, err := conv.Step("")
+		if err != nil {
+			return nil, err
+		}
+		res, err := runCommand(bsoncore.NewDocumentBuilder().
+			AppendInt32("saslStart", 1).
+			AppendString("mechanism", "SCRAM-SHA-256").
+			AppendBinary("payload", 0, []byte(clientMsg)).
+			AppendDocument("options", bsoncore.NewDocumentBuilder().AppendBoolean("skipEmptyExchange", true).Build()).
+			AppendString("$db", auth.AdminDatabase).
+			Build())
+		if err != nil || !isOk(res) {
+			return res, err
+		}
+		conversationID, _ := res.Lookup("conversationId").Int32OK()
+		_, serverMsg, _ := res.Lookup("payload").BinaryOK()
+		clientMsg, err = conv.Step(string(serverMsg))
+		if err != nil {
+			return nil, err
+		}
+		time.Sleep(delay)
+		return runCommand(bsoncore.NewDocumentBuilder().
+			AppendInt32("saslContinue", 1).
+			AppendInt32("conversationId", conversationID).
+			AppendBinary("payload", 0, []byte(clientMsg)).
+			AppendString("$db", auth.AdminDatabase).
+			Build())
+	}
+
+	isAuthenticated := func(res bsoncore.Document) bool {
+		if !isOk(res) {
+			return false
+		}
+		done, _ := res.Lookup("done").BooleanOK()
+		_, payload, _ := res.Lookup("payload").BinaryOK()
+		return done && !strings.HasPrefix(string(payload), "e=")
+	}
+
+	usersInfo := func() (bsoncore.Document, error) {
+		return runCommand(bsoncore.NewDocumentBuilder().
+			AppendInt32("usersInfo", 1).
+			AppendString("$db", auth.AdminDatabase).
+			Build())
+	}
+
+	// Half-finished conversations expire after the timeout
+
+	res, err := authenticate(TestUsername, TestPassword, 300*time.Millisecond)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	if errmsg, _ := res.Lookup("errmsg").StringValueOK(); !strings.Contains(errmsg, "expired") {
+		t.Errorf("expired conversation is authenticated : %s", res.String())
+		return
+	}
+
+	// Failed attempts back off after the limit
+
+	for n := 0; n < 2; n++ {
+		res, err = authenticate(TestUsername, "invalid", 0)
+		if err != nil {
+			t.Error(err)
+			return
+		}
+		if isAuthenticated(res) {
+			t.Errorf("invalid password is authenticated : %s", res.String())
+			return
+		}
+	}
+
+	res, err = authenticate(TestUsername, TestPassword, 0)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	if errmsg, _ := res.Lookup("errmsg").StringValueOK(); !strings.Contains(errmsg, "too many failed") {
+		t.Errorf("failed attempts are not limited : %s", res.String())
+		return
+	}
+
+	time.Sleep(600 * time.Millisecond)
+
+	res, err = authenticate(TestUsername, TestPassword, 0)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	if !isAuthenticated(res) {
+		t.Errorf("authentication is not allowed after the back-off : %s", res.String())
+		return
+	}
+
+	res, err = usersInfo()
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	if !isOk(res) {
+		t.Errorf("connection is not authenticated : %s", res.String())
+		return
+	}
+
+	// The connection is re-authenticated as a different user
+
+	res, err = authenticate(otherUsername, otherPassword, 0)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	if !isAuthenticated(res) {
+		t.Errorf("connection is not re-authenticated : %s", res.String())
+		return
+	}
+
+	res, err = usersInfo()
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	if code, _ := res.Lookup("code").Int32OK(); code != 13 {
+		t.Errorf("privileges of the previous user are kept : %s", res.String())
+		return
+	}
+
+	// The connection is not authenticated after logout
+
+	res, err = runCommand(bsoncore.NewDocumentBuilder().
+		AppendInt32("logout", 1).
+		AppendString("$db", auth.AdminDatabase).
+		Build())
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	if !isOk(res) {
+		t.Errorf("logout is failed : %s", res.String())
+		return
+	}
+
+	res, err = runCommand(bsoncore.NewDocumentBuilder().
+		AppendString("find", "logout").
+		AppendString("$db", "test").
+		Build())
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	if errmsg, _ := res.Lookup("errmsg").StringValueOK(); !strings.Contains(errmsg, "requires authentication") {
+		t.Errorf("connection is authenticated after logout : %s", res.String())
+		return
+	}
+
+	// Failed attempts of a connection do not lock out the other connections from the same host
+
+	failedConn := dialTestServer(t)
+	authConn := conn
+	conn = failedConn
+
+	for n := 0; n < 2; n++ {
+		res, err = authenticate(TestUsername, "invalid", 0)
+		if err != nil {
+			t.Error(err)
+			return
+		}
+		if isAuthenticated(res) {
+			t.Errorf("invalid password is authenticated : %s", res.String())
+			return
+		}
+	}
+
+	res, err = authenticate(TestUsername, TestPassword, 0)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	if errmsg, _ := res.Lookup("errmsg").StringValueOK(); !strings.Contains(errmsg, "too many failed") {
+		t.Errorf("failed attempts are not limited : %s", res.String())
+		return
+	}
+
+	conn = authConn
+	res, err = authenticate(TestUsername, TestPassword, 0)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	if !isAuthenticated(res) {
+		t.Errorf("other connection from the same host is locked out : %s", res.String())
+		return
+	}
+
+	res, err = usersInfo()
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	if !isOk(res) {
+		t.Errorf("connection is not authenticated : %s", res.String())
+	}
+}
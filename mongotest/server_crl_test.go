@@ -0,0 +1,194 @@
+// Copyright (C) 2022 The go-mongo Authors All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package mongotest
+
+import (
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/pem"
+	"fmt"
+	"math/big"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/cybergarage/go-logger/log"
+	"go.mongodb.org/mongo-driver/x/bsonx/bsoncore"
+)
+
+func TestCRLServer(t *testing.T) {
+	log.EnableStdoutDebug(true)
+
+	// Generate a CA, and issue the server and client certificates
+
+	ca, err := newTestCA("go-mongo test CA")
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	serverCert, serverKey, err := ca.Issue(2, "localhost", x509.ExtKeyUsageServerAuth)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	clientCerts := map[int64]tls.Certificate{}
+	for _, serial := range []int64{3, 4} {
+		certPEM, keyPEM, err := ca.Issue(serial, fmt.Sprintf("client%d", serial), x509.ExtKeyUsageClientAuth)
+		if err != nil {
+			t.Error(err)
+			return
+		}
+		clientCerts[serial], err = tls.X509KeyPair(certPEM, keyPEM)
+		if err != nil {
+			t.Error(err)
+			return
+		}
+	}
+
+	// Revoke the client certificates, and write the CRL as PEM or DER
+
+	crlFile := filepath.Join(t.TempDir(), "crl.pem")
+	crlNumber := int64(0)
+	revoke := func(isPEM bool, serials ...int64) error {
+		crlNumber++
+		entries := []x509.RevocationListEntry{}
+		for _, serial := range serials {
+			entries = append(entries, x509.RevocationListEntry{SerialNumber: big.NewInt(serial), RevocationTime: time.Now()})
+		}
+		der, err := x509.CreateRevocationList(rand.Reader, &x509.RevocationList{
+			Number:                    big.NewInt(crlNumber),
+			ThisUpdate:                time.Now().Add(-time.Minute),
+			NextUpdate:                time.Now().Add(time.Hour),
+			RevokedCertificateEntries: entries,
+		}, ca.cert, ca.key)
+		if err != nil {
+			return err
+		}
+		if isPEM {
+			der = pem.EncodeToMemory(&pem.Block{Type: "X509 CRL", Bytes: der})
+		}
+		return os.WriteFile(crlFile, der, 0o600)
+	}
+
+	err = revoke(true, 4)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	server := NewServer()
+	server.SetTLSEnabled(true)
+	server.SetServerKey(serverKey)
+	server.SetServerCert(serverCert)
+	server.SetRootCerts(ca.CertPEM())
+	server.SetClientAuthType(tls.RequireAndVerifyClientCert)
+	err = server.SetCRLFiles(crlFile)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	server.CRLStore().SetCheckInterval(0)
+
+	startTestServer(t, server)
+
+	rootCAs := x509.NewCertPool()
+	rootCAs.AddCert(ca.cert)
+
+	hello := func(serial int64) error {
+		conn, err := tls.Dial("tcp", "localhost:27017", &tls.Config{
+			MinVersion:   tls.VersionTLS12,
+			RootCAs:      rootCAs,
+			Certificates: []tls.Certificate{clientCerts[serial]},
+			ServerName:   "localhost",
+		})
+		if err != nil {
+			return err
+		}
+		defer conn.Close()
+		_, err = runTestCommand(conn, bsoncore.NewDocumentBuilder().
+			AppendInt32("hello", 1).
+			AppendString("$db", "admin").
+			Build())
+		return err
+	}
+
+	// The revoked client certificate is rejected during the handshake
+
+	if err := hello(3); err != nil {
+		t.Errorf("valid certificate is rejected : %s", err)
+	}
+	if err := hello(4); err == nil {
+		t.Errorf("revoked certificate is accepted")
+	}
+
+	// The CRL is reloaded when the file changes
+
+	err = revoke(false, 3, 4)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	if err := hello(3); err == nil {
+		t.Errorf("certificate revoked by the reloaded CRL is accepted")
+	}
+
+	// The CRL is checked after the VerifyPeerCertificate of the TLS configuration
+
+	err = revoke(true, 4)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	serverKeyPair, err := tls.X509KeyPair(serverCert, serverKey)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	var verifiedCerts atomic.Int32
+	server.SetTLSConfig(&tls.Config{
+		MinVersion:   tls.VersionTLS12,
+		Certificates: []tls.Certificate{serverKeyPair},
+		ClientCAs:    rootCAs,
+		ClientAuth:   tls.RequireAndVerifyClientCert,
+		VerifyPeerCertificate: func(rawCerts [][]byte, verifiedChains [][]*x509.Certificate) error {
+			verifiedCerts.Add(1)
+			if verifiedChains[0][0].Subject.CommonName == "client3" {
+				return fmt.Errorf("client3 is rejected")
+			}
+			return nil
+		},
+	})
+	err = server.Restart()
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	if err := hello(3); err == nil {
+		t.Errorf("certificate rejected by VerifyPeerCertificate is accepted")
+	}
+	if err := hello(4); err == nil {
+		t.Errorf("revoked certificate is accepted")
+	}
+	if n := verifiedCerts.Load(); n != 2 {
+		t.Errorf("%d != %d", n, 2)
+	}
+}
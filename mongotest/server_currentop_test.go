@@ -0,0 +1,190 @@
+// Copyright (C) 2022 The go-mongo Authors All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package mongotest
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+
+	"github.com/cybergarage/go-logger/log"
+	mongod "github.com/cybergarage/go-mongo/mongo"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+func TestCurrentOpServer(t *testing.T) {
+	log.EnableStdoutDebug(true)
+
+	server := NewServer()
+	executor := &testBlockingExecutor{
+		Server:    server,
+		ops:       make(chan *mongod.Operation, 1),
+		causes:    make(chan error, 1),
+		completes: atomic.Bool{},
+	}
+	server.SetUserCommandExecutor(executor)
+	startTestServer(t, server)
+
+	client := connectTestClient(t)
+
+	// Keeps a find query in flight
+
+	findErr := make(chan error, 1)
+	go func() {
+		_, err := client.Database("test").Collection("currentop").Find(context.TODO(), bson.D{})
+		findErr <- err
+	}()
+	op := <-executor.ops
+
+	type inprog struct {
+		Inprog []bson.M `bson:"inprog"`
+	}
+
+	currentOp := func(t *testing.T, filter ...bson.E) []bson.M {
+		t.Helper()
+		var res inprog
+		cmd := append(bson.D{{Key: "currentOp", Value: 1}}, filter...)
+		err := client.Database("admin").RunCommand(context.TODO(), cmd).Decode(&res)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return res.Inprog
+	}
+
+	isInprog := func(ops []bson.M) bool {
+		for _, o := range ops {
+			if opid, ok := o["opid"].(int32); ok && opid == op.ID() {
+				return true
+			}
+		}
+		return false
+	}
+
+	t.Run("Operations", func(t *testing.T) {
+		var found *mongod.Operation
+		for _, o := range server.Operations() {
+			if o.ID() == op.ID() {
+				found = o
+			}
+		}
+		if found == nil {
+			t.Errorf("operation (%d) is not found", op.ID())
+			return
+		}
+		if found.CommandName() != "find" || found.Namespace() != "test.currentop" {
+			t.Errorf("%s (%s)", found.CommandName(), found.Namespace())
+		}
+		if found.Elapsed() <= 0 || found.Timestamp().IsZero() {
+			t.Errorf("%s (%s)", found.Timestamp(), found.Elapsed())
+		}
+		if len(found.ClientAddress()) == 0 {
+			t.Errorf("no client address")
+		}
+		if _, ok := found.UserName(); ok {
+			t.Errorf("unauthenticated operation has a user")
+		}
+	})
+
+	t.Run("currentOp", func(t *testing.T) {
+		var entry bson.M
+		for _, o := range currentOp(t) {
+			if opid, ok := o["opid"].(int32); ok && opid == op.ID() {
+				entry = o
+			}
+		}
+		if entry == nil {
+			t.Errorf("operation (%d) is not reported", op.ID())
+			return
+		}
+		expected := map[string]any{
+			"type":           "op",
+			"active":         true,
+			"op":             "query",
+			"ns":             "test.currentop",
+			"connectionUUID": op.ConnUUID().String(),
+			"client":         op.ClientAddress(),
+		}
+		for key, value := range expected {
+			if entry[key] != value {
+				t.Errorf("%s : %v != %v", key, entry[key], value)
+			}
+		}
+		for _, key := range []string{"startTime", "secs_running", "microsecs_running"} {
+			if _, ok := entry[key]; !ok {
+				t.Errorf("%s is not reported", key)
+			}
+		}
+		if cmd, ok := entry["command"].(bson.M); !ok || cmd["find"] != "currentop" {
+			t.Errorf("%v", entry["command"])
+		}
+	})
+
+	t.Run("filter", func(t *testing.T) {
+		filters := []struct {
+			filter     bson.D
+			isIncluded bool
+		}{
+			{bson.D{{Key: "ns", Value: "test.currentop"}}, true},
+			{bson.D{{Key: "ns", Value: "test.other"}}, false},
+			{bson.D{{Key: "ns", Value: bson.D{{Key: "$regex", Value: "^TEST\\."}, {Key: "$options", Value: "i"}}}}, true},
+			{bson.D{{Key: "command.find", Value: "currentop"}}, true},
+			{bson.D{{Key: "secs_running", Value: bson.D{{Key: "$gte", Value: 1000}}}}, false},
+			{bson.D{{Key: "microsecs_running", Value: bson.D{{Key: "$gt", Value: 0}}}}, true},
+			{bson.D{{Key: "op", Value: bson.D{{Key: "$in", Value: bson.A{"insert", "query"}}}}}, true},
+			{bson.D{{Key: "$or", Value: bson.A{bson.D{{Key: "op", Value: "insert"}}, bson.D{{Key: "active", Value: false}}}}}, false},
+			{bson.D{{Key: "effectiveUsers", Value: bson.D{{Key: "$exists", Value: false}}}}, true},
+		}
+		for _, f := range filters {
+			if isInprog(currentOp(t, f.filter...)) != f.isIncluded {
+				t.Errorf("%v : %t", f.filter, !f.isIncluded)
+			}
+		}
+
+		// Idle connections are reported with $all
+
+		if ops := currentOp(t, bson.E{Key: "active", Value: false}); len(ops) != 0 {
+			t.Errorf("idle connections are reported without $all : %v", ops)
+		}
+		if ops := currentOp(t, bson.E{Key: "$all", Value: true}, bson.E{Key: "active", Value: false}); len(ops) == 0 {
+			t.Errorf("idle connections are not reported with $all")
+		}
+
+		// Unauthenticated callers own no operations
+
+		if ops := currentOp(t, bson.E{Key: "$ownOps", Value: true}); len(ops) != 0 {
+			t.Errorf("operations are reported to the unauthenticated caller : %v", ops)
+		}
+
+		// Unknown operators are rejected
+
+		var cmdErr mongo.CommandError
+		err := client.Database("admin").RunCommand(context.TODO(), bson.D{{Key: "currentOp", Value: 1}, {Key: "ns", Value: bson.D{{Key: "$unknown", Value: 1}}}}).Err()
+		if !errors.As(err, &cmdErr) || cmdErr.Code != int32(mongod.ErrorCodeBadValue) {
+			t.Errorf("%v is not a BadValue error", err)
+		}
+	})
+
+	if !server.KillOperation(op.ID()) {
+		t.Errorf("operation (%d) is not killed", op.ID())
+	}
+	<-executor.causes
+	<-findErr
+
+	if isInprog(currentOp(t)) {
+		t.Errorf("finished operation (%d) is reported", op.ID())
+	}
+}
@@ -0,0 +1,323 @@
+// Copyright (C) 2022 The go-mongo Authors All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package mongotest
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/cybergarage/go-logger/log"
+	"github.com/cybergarage/go-mongo/mongo/protocol"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"go.mongodb.org/mongo-driver/x/bsonx/bsoncore"
+)
+
+func TestExhaustCursorServer(t *testing.T) {
+	log.EnableStdoutDebug(true)
+
+	server := NewServer()
+	startTestServer(t, server)
+
+	client := connectTestClient(t)
+
+	// Insert documents
+
+	collection := client.Database("test").Collection("exhaust")
+
+	trainers := []any{
+		Trainer{"Ash", 10, "Pallet Town"},
+		Trainer{"Misty", 10, "Cerulean City"},
+		Trainer{"Brock", 15, "Pewter City"},
+		Trainer{"Gary", 10, "Pallet Town"},
+		Trainer{"Erika", 18, "Celadon City"},
+	}
+
+	_, err := collection.InsertMany(context.TODO(), trainers)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	// Find the documents with getMore batches
+
+	cur, err := collection.Find(context.TODO(), bson.D{}, options.Find().SetBatchSize(2))
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	var results []Trainer
+	err = cur.All(context.TODO(), &results)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	if len(results) != len(trainers) {
+		t.Errorf("%d != %d", len(results), len(trainers))
+		return
+	}
+
+	// Stream the remaining batches with an exhaust getMore
+
+	conn := dialTestServer(t)
+
+	findCmd := bsoncore.NewDocumentBuilder().
+		AppendString("find", "exhaust").
+		AppendInt32("batchSize", 1).
+		AppendString("$db", "test").
+		Build()
+
+	reqMsg := protocol.NewMsgWithBody(findCmd)
+	reqMsg.SetRequestID(1)
+	resMsg, err := exchangeTestMessage(conn, reqMsg)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	cursorID, ok := bsoncore.Document(resMsg.Body()).Lookup("cursor", "id").AsInt64OK()
+	if !ok || cursorID == 0 {
+		t.Errorf("invalid cursor : %s", resMsg.String())
+		return
+	}
+
+	getMoreCmd := bsoncore.NewDocumentBuilder().
+		AppendInt64("getMore", cursorID).
+		AppendString("collection", "exhaust").
+		AppendInt32("batchSize", 1).
+		AppendString("$db", "test").
+		Build()
+
+	reqMsg = protocol.NewMsgWithBody(getMoreCmd)
+	reqMsg.SetRequestID(2)
+	reqMsg.SetExhaustAllowed(true)
+	resMsg, err = exchangeTestMessage(conn, reqMsg)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	if resMsg.ResponseTo() != reqMsg.RequestID() {
+		t.Errorf("%d != %d", resMsg.ResponseTo(), reqMsg.RequestID())
+		return
+	}
+
+	nBatches := 1
+	for {
+		cursorID, ok := bsoncore.Document(resMsg.Body()).Lookup("cursor", "id").AsInt64OK()
+		if !ok {
+			t.Errorf("invalid cursor : %s", resMsg.String())
+			return
+		}
+		if (cursorID != 0) != resMsg.IsMoreToCome() {
+			t.Errorf("invalid moreToCome : %s", resMsg.String())
+			return
+		}
+		if cursorID == 0 {
+			break
+		}
+		prevMsg := resMsg
+		resMsg, err = readTestMessage(conn)
+		if err != nil {
+			t.Error(err)
+			return
+		}
+		if resMsg.ResponseTo() != prevMsg.RequestID() {
+			t.Errorf("%d != %d", resMsg.ResponseTo(), prevMsg.RequestID())
+			return
+		}
+		nBatches++
+	}
+
+	if nBatches != (len(trainers) - 1) {
+		t.Errorf("%d != %d", nBatches, len(trainers)-1)
+	}
+}
+
+func TestCursorOwnershipServer(t *testing.T) {
+	log.EnableStdoutDebug(true)
+
+	server := NewServer()
+	startTestServer(t, server)
+
+	client := connectTestClient(t)
+
+	collection := client.Database("test").Collection("cursors")
+	trainers := []any{
+		Trainer{"Ash", 10, "Pallet Town"},
+		Trainer{"Misty", 10, "Cerulean City"},
+		Trainer{"Brock", 15, "Pewter City"},
+	}
+	_, err := collection.InsertMany(context.TODO(), trainers)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	requestID := int32(0)
+	runCommand := func(conn net.Conn, cmd bsoncore.Document) (bsoncore.Document, error) {
+		requestID++
+		reqMsg := protocol.NewMsgWithBody(cmd)
+		reqMsg.SetRequestID(requestID)
+		resMsg, err := exchangeTestMessage(conn, reqMsg)
+		if err != nil {
+			return nil, err
+		}
+		return bsoncore.Document(resMsg.Body()), nil
+	}
+
+	openCursor := func(conn net.Conn) (int64, error) {
+		findCmd := bsoncore.NewDocumentBuilder().
+			AppendString("find", "cursors").
+			AppendInt32("batchSize", 1).
+			AppendString("$db", "test").
+			Build()
+		res, err := runCommand(conn, findCmd)
+		if err != nil {
+			return 0, err
+		}
+		cursorID, ok := res.Lookup("cursor", "id").AsInt64OK()
+		if !ok || cursorID == 0 {
+			return 0, fmt.Errorf("invalid cursor : %s", res.String())
+		}
+		return cursorID, nil
+	}
+
+	getMore := func(conn net.Conn, cursorID int64, coll string) (bsoncore.Document, error) {
+		getMoreCmd := bsoncore.NewDocumentBuilder().
+			AppendInt64("getMore", cursorID).
+			AppendString("collection", coll).
+			AppendInt32("batchSize", 1).
+			AppendString("$db", "test").
+			Build()
+		return runCommand(conn, getMoreCmd)
+	}
+
+	hasCursor := func(cursorID int64) bool {
+		for _, cursor := range server.Cursors() {
+			if cursor.ID() == cursorID {
+				return true
+			}
+		}
+		return false
+	}
+
+	waitCursorKilled := func(cursorID int64) bool {
+		for range 100 {
+			if !hasCursor(cursorID) {
+				return true
+			}
+			time.Sleep(10 * time.Millisecond)
+		}
+		return false
+	}
+
+	ownerConn, err := net.Dial("tcp", "localhost:27017")
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	otherConn := dialTestServer(t)
+
+	cursorID, err := openCursor(ownerConn)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	t.Run("getMore from another connection", func(t *testing.T) {
+		res, err := getMore(otherConn, cursorID, "cursors")
+		if err != nil {
+			t.Error(err)
+			return
+		}
+		code, _ := res.Lookup("code").AsInt32OK()
+		if code != 43 {
+			t.Errorf("%d != %d : %s", code, 43, res.String())
+		}
+	})
+
+	t.Run("getMore with another collection", func(t *testing.T) {
+		res, err := getMore(ownerConn, cursorID, "others")
+		if err != nil {
+			t.Error(err)
+			return
+		}
+		code, _ := res.Lookup("code").AsInt32OK()
+		if code != 13 {
+			t.Errorf("%d != %d : %s", code, 13, res.String())
+		}
+	})
+
+	t.Run("killCursors from another connection", func(t *testing.T) {
+		killCursorsCmd := bsoncore.NewDocumentBuilder().
+			AppendString("killCursors", "cursors").
+			AppendArray("cursors", bsoncore.NewArrayBuilder().AppendInt64(cursorID).Build()).
+			AppendString("$db", "test").
+			Build()
+		_, err := runCommand(otherConn, killCursorsCmd)
+		if err != nil {
+			t.Error(err)
+			return
+		}
+		if !hasCursor(cursorID) {
+			t.Errorf("cursor (%d) is killed by another connection", cursorID)
+		}
+	})
+
+	t.Run("close connection", func(t *testing.T) {
+		ownerConn.Close()
+		if !waitCursorKilled(cursorID) {
+			t.Errorf("cursor (%d) is not killed", cursorID)
+		}
+	})
+
+	t.Run("idle timeout", func(t *testing.T) {
+		server.SetCursorTimeout(100 * time.Millisecond)
+		err := server.Restart()
+		if err != nil {
+			t.Error(err)
+			return
+		}
+
+		conn := dialTestServer(t)
+
+		cursorID, err := openCursor(conn)
+		if err != nil {
+			t.Error(err)
+			return
+		}
+		if !waitCursorKilled(cursorID) {
+			t.Errorf("cursor (%d) is not killed", cursorID)
+			return
+		}
+
+		res, err := getMore(conn, cursorID, "cursors")
+		if err != nil {
+			t.Error(err)
+			return
+		}
+		code, _ := res.Lookup("code").AsInt32OK()
+		if code != 43 {
+			t.Errorf("%d != %d : %s", code, 43, res.String())
+		}
+	})
+}
@@ -0,0 +1,176 @@
+// Copyright (C) 2022 The go-mongo Authors All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package mongotest
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"net"
+	"net/url"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/cybergarage/go-logger/log"
+	mongod "github.com/cybergarage/go-mongo/mongo"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"go.mongodb.org/mongo-driver/x/bsonx/bsoncore"
+)
+
+func TestMultiEndpointServer(t *testing.T) {
+	log.EnableStdoutDebug(true)
+
+	// The driver lowercases hosts, so the socket directory must not have uppercase letters as t.TempDir() does.
+	socketDir, err := os.MkdirTemp("", "go-mongo")
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	defer os.RemoveAll(socketDir)
+	socketFile := filepath.Join(socketDir, "mongodb-27017.sock")
+
+	server := NewServer()
+	server.SetServerKey(TestSeverKey)
+	server.SetServerCert(TestServerCert)
+	server.SetRootCerts(TestCACert)
+	server.AddEndpoint(mongod.NewTCPEndpoint("127.0.0.1", 27017))
+	server.AddEndpoint(mongod.NewUnixEndpoint(socketFile).SetFileMode(0o600))
+	server.AddEndpoint(mongod.NewTCPEndpoint("localhost", 27018).SetTLSEnabled(true))
+
+	// IPv6 is tested only when the loopback address is available.
+	isIPv6Available := false
+	if l, err := net.Listen("tcp", "[::1]:0"); err == nil {
+		l.Close()
+		isIPv6Available = true
+		server.AddEndpoint(mongod.NewTCPEndpoint("::1", 27019))
+	}
+
+	err = server.Start()
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	stopped := false
+	defer func() {
+		if stopped {
+			return
+		}
+		err := server.Stop()
+		if err != nil {
+			t.Error(err)
+		}
+	}()
+
+	fi, err := os.Stat(socketFile)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	if fi.Mode().Perm() != 0o600 {
+		t.Errorf("%o != %o", fi.Mode().Perm(), 0o600)
+	}
+
+	hello := func(conn net.Conn) error {
+		_, err := runTestCommand(conn, bsoncore.NewDocumentBuilder().
+			AppendInt32("hello", 1).
+			AppendString("$db", "admin").
+			Build())
+		return err
+	}
+
+	// The TLS endpoint accepts only TLS connections
+
+	tlsConfig, err := server.TLSConfig()
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	tlsConfig = tlsConfig.Clone()
+	tlsConfig.ServerName = "localhost"
+	tlsConn, err := tls.Dial("tcp", "localhost:27018", tlsConfig)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	defer tlsConn.Close()
+	if err := hello(tlsConn); err != nil {
+		t.Error(err)
+	}
+
+	if isIPv6Available {
+		conn, err := net.Dial("tcp", "[::1]:27019")
+		if err != nil {
+			t.Error(err)
+			return
+		}
+		defer conn.Close()
+		if err := hello(conn); err != nil {
+			t.Error(err)
+		}
+	}
+
+	// The connections of all endpoints share the same executors
+
+	connect := func(uri string) (*mongo.Client, error) {
+		return mongo.Connect(context.TODO(), options.Client().ApplyURI(uri))
+	}
+
+	unixClient, err := connect("mongodb://" + url.PathEscape(socketFile))
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	defer unixClient.Disconnect(context.TODO())
+
+	tcpClient, err := connect("mongodb://127.0.0.1:27017")
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	defer tcpClient.Disconnect(context.TODO())
+
+	trainer := Trainer{"Brock", 15, "Pewter City"}
+	_, err = unixClient.Database("test").Collection("endpoint").InsertOne(context.TODO(), trainer)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	var result Trainer
+	err = tcpClient.Database("test").Collection("endpoint").FindOne(context.TODO(), bson.D{{Key: "name", Value: trainer.Name}}).Decode(&result)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	if result != trainer {
+		t.Errorf("%v != %v", result, trainer)
+	}
+
+	// The socket file is removed when the server stops
+
+	stopped = true
+	err = server.Stop()
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	if _, err := os.Stat(socketFile); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("socket file is not removed : %v", err)
+	}
+}
@@ -0,0 +1,74 @@
+// Copyright (C) 2022 The go-mongo Authors All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package mongotest
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/cybergarage/go-logger/log"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+func TestErrorResponseServer(t *testing.T) {
+	log.EnableStdoutDebug(true)
+
+	server := NewServer()
+	startTestServer(t, server)
+
+	client := connectTestClient(t)
+
+	db := client.Database("test")
+
+	// Insert a duplicate document, the server must reply a write error
+
+	collection := db.Collection("errors")
+	doc := bson.D{{Key: "_id", Value: "ash"}, {Key: "name", Value: "Ash"}}
+	_, err := collection.InsertOne(context.TODO(), doc)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	_, err = collection.InsertOne(context.TODO(), doc)
+	if !mongo.IsDuplicateKeyError(err) {
+		t.Errorf("%v is not a duplicate key error", err)
+		return
+	}
+
+	var writeErr mongo.WriteException
+	if !errors.As(err, &writeErr) || len(writeErr.WriteErrors) != 1 {
+		t.Errorf("%v is not a write exception", err)
+		return
+	}
+	if writeErr.WriteErrors[0].Index != 0 || writeErr.WriteErrors[0].Code != 11000 {
+		t.Errorf("invalid write error : %v", writeErr.WriteErrors[0])
+	}
+
+	// Get more an unknown cursor, the server must reply a command error
+
+	cmd := bson.D{{Key: "getMore", Value: int64(12345)}, {Key: "collection", Value: "errors"}}
+	err = db.RunCommand(context.TODO(), cmd).Err()
+	var cmdErr mongo.CommandError
+	if !errors.As(err, &cmdErr) {
+		t.Errorf("%v is not a command error", err)
+		return
+	}
+	if cmdErr.Code != 43 || cmdErr.Name != "CursorNotFound" || len(cmdErr.Message) == 0 {
+		t.Errorf("invalid command error : %v", cmdErr)
+	}
+}
@@ -0,0 +1,234 @@
+// Copyright (C) 2022 The go-mongo Authors All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package mongotest
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"io"
+	"math/big"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/cybergarage/go-mongo/mongo/protocol"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"go.mongodb.org/mongo-driver/x/bsonx/bsoncore"
+)
+
+const testServerAddr = "localhost:27017"
+
+// startTestServer starts the server, and stops it when the test finishes.
+func startTestServer(t *testing.T, server *Server) *Server {
+	t.Helper()
+	err := server.Start()
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		err := server.Stop()
+		if err != nil {
+			t.Error(err)
+		}
+	})
+	return server
+}
+
+// serveTestServer serves the server on an ephemeral port, returns the bound address, and stops the server when the test finishes.
+func serveTestServer(t *testing.T, server *Server) string {
+	t.Helper()
+	l, err := net.Listen("tcp", "localhost:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	served := make(chan error, 1)
+	go func() {
+		served <- server.Serve(l)
+	}()
+	t.Cleanup(func() {
+		err := server.Stop()
+		if err != nil {
+			t.Error(err)
+		}
+		if err := <-served; err != nil {
+			t.Error(err)
+		}
+	})
+	return l.Addr().String()
+}
+
+// dialTestServer opens a raw connection to the test server, and closes it when the test finishes.
+func dialTestServer(t *testing.T) net.Conn {
+	t.Helper()
+	conn, err := net.Dial("tcp", testServerAddr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	return conn
+}
+
+// connectTestClient connects a driver client to the test server with the options, and disconnects it when the test finishes.
+func connectTestClient(t *testing.T, opts ...*options.ClientOptions) *mongo.Client {
+	t.Helper()
+	clientOptions := options.MergeClientOptions(append([]*options.ClientOptions{options.Client().ApplyURI(testDBURL)}, opts...)...)
+	client, err := mongo.Connect(context.TODO(), clientOptions)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { client.Disconnect(context.TODO()) })
+	return client
+}
+
+// runTestCommand sends the command as OP_MSG, and returns the reply document.
+func runTestCommand(conn net.Conn, cmd bsoncore.Document) (bsoncore.Document, error) {
+	_, err := conn.Write(protocol.NewMsgWithBody(cmd).Bytes())
+	if err != nil {
+		return nil, err
+	}
+	return readTestReplyDocument(conn)
+}
+
+func exchangeTestMessage(conn net.Conn, msg *protocol.Msg) (*protocol.Msg, error) {
+	_, err := conn.Write(msg.Bytes())
+	if err != nil {
+		return nil, err
+	}
+	return readTestMessage(conn)
+}
+
+func readTestMessage(conn net.Conn) (*protocol.Msg, error) {
+	headerBytes := make([]byte, protocol.HeaderSize)
+	_, err := io.ReadFull(conn, headerBytes)
+	if err != nil {
+		return nil, err
+	}
+	header, err := protocol.NewHeaderWithBytes(headerBytes)
+	if err != nil {
+		return nil, err
+	}
+	bodyBytes := make([]byte, header.BodySize())
+	_, err = io.ReadFull(conn, bodyBytes)
+	if err != nil {
+		return nil, err
+	}
+	return protocol.NewMsgWithHeaderAndBody(header, bodyBytes)
+}
+
+// readTestReplyDocument reads a reply of OP_MSG or OP_REPLY, and returns the first document.
+func readTestReplyDocument(conn net.Conn) (bsoncore.Document, error) {
+	headerBytes := make([]byte, protocol.HeaderSize)
+	_, err := io.ReadFull(conn, headerBytes)
+	if err != nil {
+		return nil, err
+	}
+	header, err := protocol.NewHeaderWithBytes(headerBytes)
+	if err != nil {
+		return nil, err
+	}
+	bodyBytes := make([]byte, header.BodySize())
+	_, err = io.ReadFull(conn, bodyBytes)
+	if err != nil {
+		return nil, err
+	}
+	if header.OpCode() == protocol.OpReply {
+		reply, err := protocol.NewReplyWithHeaderAndBody(header, bodyBytes)
+		if err != nil {
+			return nil, err
+		}
+		docs := reply.Documents()
+		if len(docs) == 0 {
+			return nil, io.ErrUnexpectedEOF
+		}
+		return bsoncore.Document(docs[0]), nil
+	}
+	msg, err := protocol.NewMsgWithHeaderAndBody(header, bodyBytes)
+	if err != nil {
+		return nil, err
+	}
+	return bsoncore.Document(msg.Body()), nil
+}
+
+// testCA represents a certificate authority generated for tests.
+type testCA struct {
+	cert *x509.Certificate
+	key  *ecdsa.PrivateKey
+}
+
+// newTestCA returns a new self-signed certificate authority.
+func newTestCA(cn string) (*testCA, error) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return nil, err
+	}
+	tmpl := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: cn},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		KeyUsage:              x509.KeyUsageCertSign | x509.KeyUsageCRLSign,
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, key.Public(), key)
+	if err != nil {
+		return nil, err
+	}
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		return nil, err
+	}
+	return &testCA{cert: cert, key: key}, nil
+}
+
+// CertPEM returns the PEM encoded CA certificate.
+func (ca *testCA) CertPEM() []byte {
+	return pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: ca.cert.Raw})
+}
+
+// Issue returns the PEM encoded certificate and key issued for localhost.
+func (ca *testCA) Issue(serial int64, cn string, usage x509.ExtKeyUsage) ([]byte, []byte, error) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return nil, nil, err
+	}
+	tmpl := &x509.Certificate{
+		SerialNumber: big.NewInt(serial),
+		Subject:      pkix.Name{CommonName: cn},
+		DNSNames:     []string{"localhost"},
+		IPAddresses:  []net.IP{net.ParseIP("127.0.0.1")},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{usage},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, ca.cert, key.Public(), ca.key)
+	if err != nil {
+		return nil, nil, err
+	}
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		return nil, nil, err
+	}
+	certPEM := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})
+	keyPEM := pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER})
+	return certPEM, keyPEM, nil
+}
@@ -0,0 +1,72 @@
+// Copyright (C) 2022 The go-mongo Authors All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package mongotest
+
+import (
+	"bytes"
+	"context"
+	"math"
+	"net"
+	"testing"
+
+	"github.com/cybergarage/go-logger/log"
+	"github.com/cybergarage/go-mongo/mongo/protocol"
+)
+
+func TestHostileClientServer(t *testing.T) {
+	log.EnableStdoutDebug(true)
+
+	server := NewServer()
+	startTestServer(t, server)
+
+	// Send hostile frames, the server must close only the connection
+
+	header := protocol.NewHeaderWithOpCode(protocol.OpMsg)
+	header.SetMessageLength(math.MaxInt32)
+	streams := [][]byte{
+		header.Bytes(),
+		bytes.Repeat([]byte{0xFF}, protocol.HeaderSize*2),
+	}
+
+	for _, stream := range streams {
+		conn, err := net.Dial("tcp", "localhost:27017")
+		if err != nil {
+			t.Error(err)
+			return
+		}
+		_, err = conn.Write(stream)
+		if err != nil {
+			t.Error(err)
+			conn.Close()
+			return
+		}
+		// The connection is closed with EOF or reset by the server
+		_, err = readTestMessage(conn)
+		conn.Close()
+		if err == nil {
+			t.Errorf("connection is not closed")
+			return
+		}
+	}
+
+	// Connect to the server again
+
+	client := connectTestClient(t)
+
+	err := client.Ping(context.TODO(), nil)
+	if err != nil {
+		t.Error(err)
+	}
+}
@@ -0,0 +1,228 @@
+// Copyright (C) 2022 The go-mongo Authors All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package mongotest
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"sync"
+	"testing"
+
+	"github.com/cybergarage/go-logger/log"
+	mongod "github.com/cybergarage/go-mongo/mongo"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/x/bsonx/bsoncore"
+)
+
+// testInsertRecordingExecutor records the collections of the executed inserts.
+type testInsertRecordingExecutor struct {
+	*Server
+	mutex       sync.Mutex
+	collections []string
+}
+
+func (executor *testInsertRecordingExecutor) Insert(conn *mongod.Conn, q *mongod.Query) (int32, error) {
+	executor.mutex.Lock()
+	executor.collections = append(executor.collections, q.FullCollectionName())
+	executor.mutex.Unlock()
+	return executor.Server.Insert(conn, q)
+}
+
+func (executor *testInsertRecordingExecutor) insertedCollections() []string {
+	executor.mutex.Lock()
+	defer executor.mutex.Unlock()
+	return append([]string{}, executor.collections...)
+}
+
+func TestInterceptorServer(t *testing.T) {
+	log.EnableStdoutDebug(true)
+
+	server := NewServer()
+	executor := &testInsertRecordingExecutor{
+		Server:      server,
+		mutex:       sync.Mutex{},
+		collections: []string{},
+	}
+	server.SetUserCommandExecutor(executor)
+
+	var mutex sync.Mutex
+	calls := []string{}
+	record := func(call string) {
+		mutex.Lock()
+		defer mutex.Unlock()
+		calls = append(calls, call)
+	}
+	recordedCalls := func() []string {
+		mutex.Lock()
+		defer mutex.Unlock()
+		return append([]string{}, calls...)
+	}
+
+	// Records the calls around the ping command
+
+	for _, name := range []string{"first", "second"} {
+		server.AddInterceptor(mongod.InterceptorFunc(func(conn *mongod.Conn, req *mongod.Request, next mongod.RequestHandler) (mongod.Document, error) {
+			if req.Command.Type() != "ping" {
+				return next(conn, req)
+			}
+			record(name + ".before")
+			defer record(name + ".after")
+			return next(conn, req)
+		}))
+	}
+
+	// Short-circuits the unregistered command with a response and the inserts to a collection with an error
+
+	server.AddInterceptor(mongod.InterceptorFunc(func(conn *mongod.Conn, req *mongod.Request, next mongod.RequestHandler) (mongod.Document, error) {
+		if req.Command.Type() == "shortcircuittest" {
+			return bsoncore.NewDocumentBuilder().AppendBoolean("intercepted", true).AppendDouble("ok", 1).Build(), nil
+		}
+		if q, _ := req.Query(); q != nil && q.Type() == "insert" && q.Collection() == "rejected" {
+			return nil, mongod.NewErrorf(mongod.ErrorCodeUnauthorized, "inserts to %s are rejected", q.Collection())
+		}
+		return next(conn, req)
+	}))
+
+	// Rewrites the inserts to the aliased collection into the redirected collection
+
+	server.AddInterceptor(mongod.InterceptorFunc(func(conn *mongod.Conn, req *mongod.Request, next mongod.RequestHandler) (mongod.Document, error) {
+		if req.Command.Type() == "insert" && req.Command.Elements[0].Value().StringValue() == "aliased" {
+			req.Command.Elements[0] = bsoncore.AppendStringElement(nil, "insert", "redirected")
+		}
+		return next(conn, req)
+	}))
+
+	// Rewrites the inserted documents of the stamped collection
+
+	server.AddInterceptor(mongod.InterceptorFunc(func(conn *mongod.Conn, req *mongod.Request, next mongod.RequestHandler) (mongod.Document, error) {
+		if req.Command.Type() != "insert" || req.Command.Elements[0].Value().StringValue() != "stamped" {
+			return next(conn, req)
+		}
+		for n, doc := range req.Documents {
+			var stamped bson.D
+			err := bson.Unmarshal(doc, &stamped)
+			if err != nil {
+				return nil, err
+			}
+			stamped = append(stamped, bson.E{Key: "stamped", Value: true})
+			req.Documents[n], err = bson.Marshal(stamped)
+			if err != nil {
+				return nil, err
+			}
+		}
+		return next(conn, req)
+	}))
+
+	// Post-processes the response of the buildInfo command
+
+	server.AddInterceptor(mongod.InterceptorFunc(func(conn *mongod.Conn, req *mongod.Request, next mongod.RequestHandler) (mongod.Document, error) {
+		resDoc, err := next(conn, req)
+		if err != nil || req.Command.Type() != "buildinfo" {
+			return resDoc, err
+		}
+		var res bson.D
+		err = bson.Unmarshal(resDoc, &res)
+		if err != nil {
+			return nil, err
+		}
+		res = append(res, bson.E{Key: "intercepted", Value: true})
+		return bson.Marshal(res)
+	}))
+
+	if n := len(server.Interceptors()); n != 6 {
+		t.Errorf("%d interceptors are registered", n)
+	}
+
+	startTestServer(t, server)
+
+	client := connectTestClient(t)
+
+	t.Run("Order", func(t *testing.T) {
+		err := client.Database("admin").RunCommand(context.TODO(), bson.D{{Key: "ping", Value: 1}}).Err()
+		if err != nil {
+			t.Error(err)
+			return
+		}
+		expected := []string{"first.before", "second.before", "second.after", "first.after"}
+		if calls := recordedCalls(); !slices.Equal(calls, expected) {
+			t.Errorf("%v != %v", calls, expected)
+		}
+	})
+
+	t.Run("ShortCircuit", func(t *testing.T) {
+		var res bson.M
+		err := client.Database("test").RunCommand(context.TODO(), bson.D{{Key: "shortCircuitTest", Value: 1}}).Decode(&res)
+		if err != nil {
+			t.Error(err)
+			return
+		}
+		if res["intercepted"] != true {
+			t.Errorf("%v", res)
+		}
+
+		_, err = client.Database("test").Collection("rejected").InsertOne(context.TODO(), bson.D{{Key: "a", Value: 1}})
+		var cmdErr mongo.CommandError
+		if !errors.As(err, &cmdErr) || cmdErr.Code != int32(mongod.ErrorCodeUnauthorized) {
+			t.Errorf("%v is not an Unauthorized error", err)
+		}
+
+		_, err = client.Database("test").Collection("accepted").InsertOne(context.TODO(), bson.D{{Key: "a", Value: 1}})
+		if err != nil {
+			t.Error(err)
+		}
+	})
+
+	t.Run("Rewrite", func(t *testing.T) {
+		_, err := client.Database("test").Collection("aliased").InsertOne(context.TODO(), bson.D{{Key: "a", Value: 1}})
+		if err != nil {
+			t.Error(err)
+			return
+		}
+		expected := "test.redirected"
+		if colls := executor.insertedCollections(); len(colls) == 0 || colls[len(colls)-1] != expected {
+			t.Errorf("%v != %s", colls, expected)
+		}
+
+		coll := client.Database("test").Collection("stamped")
+		_, err = coll.InsertOne(context.TODO(), bson.D{{Key: "name", Value: "stamped"}})
+		if err != nil {
+			t.Error(err)
+			return
+		}
+		var doc bson.M
+		err = coll.FindOne(context.TODO(), bson.D{{Key: "name", Value: "stamped"}}).Decode(&doc)
+		if err != nil {
+			t.Error(err)
+			return
+		}
+		if doc["stamped"] != true {
+			t.Errorf("%v is not rewritten", doc)
+		}
+	})
+
+	t.Run("PostProcess", func(t *testing.T) {
+		var res bson.M
+		err := client.Database("admin").RunCommand(context.TODO(), bson.D{{Key: "buildInfo", Value: 1}}).Decode(&res)
+		if err != nil {
+			t.Error(err)
+			return
+		}
+		if res["intercepted"] != true || res["version"] == nil {
+			t.Errorf("%v", res)
+		}
+	})
+}
@@ -0,0 +1,237 @@
+// Copyright (C) 2022 The go-mongo Authors All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package mongotest
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"testing"
+	"time"
+
+	"github.com/cybergarage/go-logger/log"
+	"github.com/cybergarage/go-mongo/mongo/auth"
+	"github.com/cybergarage/go-mongo/mongo/auth/sasl/oidc"
+	"github.com/cybergarage/go-mongo/mongo/protocol"
+	"go.mongodb.org/mongo-driver/x/bsonx/bsoncore"
+)
+
+func TestOIDCAuthenticationServer(t *testing.T) {
+	log.EnableStdoutDebug(true)
+
+	const (
+		issuer    = "https://issuer.example.com"
+		audience  = "go-mongo"
+		principal = "oidc-user"
+	)
+
+	signer, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	keys := oidc.NewKeySet()
+	err = keys.AddKey("test", signer.Public())
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	newToken := func(exp time.Time) (string, error) {
+		return oidc.NewSignedToken(signer, "test", oidc.Claims{
+			oidc.IssuerClaim:     issuer,
+			oidc.AudienceClaim:   audience,
+			oidc.SubjectClaim:    principal,
+			oidc.ExpirationClaim: float64(exp.UnixNano()) / float64(time.Second),
+		})
+	}
+
+	server := NewServer()
+	server.SetAuthrizationEnabled(true)
+	verifier, err := oidc.NewVerifier(
+		oidc.WithKeySet(keys),
+		oidc.WithIssuer(issuer),
+		oidc.WithAudience(audience),
+		oidc.WithClientID("go-mongo-client"),
+		oidc.WithClockSkew(0),
+	)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	server.SetOIDCVerifier(verifier)
+
+	err = server.AccessManager().CreateUser(
+		auth.NewUser(auth.NewUserName(principal, auth.ExternalDatabase), auth.NewRoleName(auth.RoleReadWrite, "test")))
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	startTestServer(t, server)
+
+	conn := dialTestServer(t)
+
+	requestID := int32(0)
+	runCommand := func(cmd bsoncore.Document) (bsoncore.Document, error) {
+		requestID++
+		reqMsg := protocol.NewMsgWithBody(cmd)
+		reqMsg.SetRequestID(requestID)
+		_, err := conn.Write(reqMsg.Bytes())
+		if err != nil {
+			return nil, err
+		}
+		return readTestReplyDocument(conn)
+	}
+
+	jwtPayload := func(jwt string) []byte {
+		return bsoncore.NewDocumentBuilder().AppendString("jwt", jwt).Build()
+	}
+
+	saslStart := func(payload []byte) (bsoncore.Document, error) {
+		return runCommand(bsoncore.NewDocumentBuilder().
+			AppendInt32("saslStart", 1).
+			AppendString("mechanism", "MONGODB-OIDC").
+			AppendBinary("payload", 0, payload).
+			AppendString("$db", auth.ExternalDatabase).
+			Build())
+	}
+
+	docID := int32(0)
+	insert := func() (bsoncore.Document, error) {
+		docID++
+		return runCommand(bsoncore.NewDocumentBuilder().
+			AppendString("insert", "oidc").
+			AppendArray("documents", bsoncore.NewArrayBuilder().
+				AppendDocument(bsoncore.NewDocumentBuilder().AppendInt32("_id", docID).AppendString("name", principal).Build()).
+				Build()).
+			AppendString("$db", "test").
+			Build())
+	}
+
+	errorCode := func(res bsoncore.Document) int32 {
+		code, _ := res.Lookup("code").Int32OK()
+		return code
+	}
+
+	isOk := func(res bsoncore.Document) bool {
+		ok, _ := res.Lookup("ok").DoubleOK()
+		return ok == 1
+	}
+
+	// Tokens which are expired or not signed by the key set are rejected
+
+	jwt, err := newToken(time.Now().Add(-time.Second))
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	res, err := saslStart(jwtPayload(jwt))
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	if code := errorCode(res); code != 18 {
+		t.Errorf("expired token is accepted : %s", res.String())
+	}
+
+	// The connection is authenticated with a short-lived token in one step
+
+	exp := time.Now().Add(time.Second)
+	jwt, err = newToken(exp)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	res, err = saslStart(jwtPayload(jwt))
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	if done, _ := res.Lookup("done").BooleanOK(); !done || !isOk(res) {
+		t.Errorf("authentication is not done : %s", res.String())
+		return
+	}
+
+	res, err = insert()
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	if !isOk(res) {
+		t.Errorf("connection is not authenticated : %s", res.String())
+		return
+	}
+
+	// Commands are refused with ReauthenticationRequired after the token expires
+
+	time.Sleep(time.Until(exp) + 100*time.Millisecond)
+
+	res, err = insert()
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	if code := errorCode(res); code != 391 {
+		t.Errorf("expired connection is not refused : %s", res.String())
+		return
+	}
+
+	// The connection is reauthenticated with the identity provider information step
+
+	res, err = saslStart(bsoncore.NewDocumentBuilder().AppendString("n", principal).Build())
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	conversationID, ok := res.Lookup("conversationId").Int32OK()
+	if !ok {
+		t.Errorf("no conversation ID : %s", res.String())
+		return
+	}
+	_, idpInfo, _ := res.Lookup("payload").BinaryOK()
+	if v, _ := bsoncore.Document(idpInfo).Lookup("issuer").StringValueOK(); v != issuer {
+		t.Errorf("%s != %s", v, issuer)
+	}
+
+	jwt, err = newToken(time.Now().Add(time.Hour))
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	res, err = runCommand(bsoncore.NewDocumentBuilder().
+		AppendInt32("saslContinue", 1).
+		AppendInt32("conversationId", conversationID).
+		AppendBinary("payload", 0, jwtPayload(jwt)).
+		AppendString("$db", auth.ExternalDatabase).
+		Build())
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	if done, _ := res.Lookup("done").BooleanOK(); !done || !isOk(res) {
+		t.Errorf("reauthentication is not done : %s", res.String())
+		return
+	}
+
+	res, err = insert()
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	if !isOk(res) {
+		t.Errorf("connection is not reauthenticated : %s", res.String())
+	}
+}
@@ -0,0 +1,166 @@
+// Copyright (C) 2022 The go-mongo Authors All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package mongotest
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/cybergarage/go-logger/log"
+	"github.com/cybergarage/go-mongo/mongo/auth"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+func TestPlainAuthenticationServer(t *testing.T) {
+	log.EnableStdoutDebug(true)
+
+	server := NewServer()
+	server.SetTLSEnabled(true)
+	server.SetServerKey(TestSeverKey)
+	server.SetServerCert(TestServerCert)
+	server.SetRootCerts(TestCACert)
+	server.SetAuthrizationEnabled(true)
+	server.SetPlainVerifier(server)
+
+	err := server.AccessManager().CreateUser(
+		auth.NewUser(auth.NewUserName(TestUsername, auth.ExternalDatabase), auth.NewRoleName(auth.RoleReadWrite, "test")))
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	startTestServer(t, server)
+
+	tlsConfig, err := server.TLSConfig()
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	connect := func(t *testing.T, password string) *mongo.Client {
+		t.Helper()
+		clientOptions := options.Client().ApplyURI(testTLSDBURL).SetTLSConfig(tlsConfig)
+		clientOptions.SetAuth(options.Credential{AuthMechanism: "PLAIN", Username: TestUsername, Password: password})
+		client, err := mongo.Connect(context.TODO(), clientOptions)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return client
+	}
+
+	trainer := Trainer{"Sabrina", 21, "Saffron City"}
+
+	// PLAIN credentials are passed to the verifier
+
+	t.Run("authenticated", func(t *testing.T) {
+		client := connect(t, TestPassword)
+		defer client.Disconnect(context.TODO())
+
+		collection := client.Database("test").Collection("plain")
+		_, err := collection.InsertOne(context.TODO(), trainer)
+		if err != nil {
+			t.Error(err)
+		}
+	})
+
+	t.Run("invalid password", func(t *testing.T) {
+		client := connect(t, "invalid")
+		defer client.Disconnect(context.TODO())
+
+		err := client.Database("test").Collection("plain").FindOne(context.TODO(), bson.D{}).Err()
+		if err == nil || errors.Is(err, mongo.ErrNoDocuments) {
+			t.Errorf("invalid password is authenticated")
+		}
+	})
+}
+
+func TestPlainWithoutTLSServer(t *testing.T) {
+	log.EnableStdoutDebug(true)
+
+	server := NewServer()
+	server.SetAuthrizationEnabled(true)
+	server.SetPlainVerifier(server)
+
+	err := server.AccessManager().CreateUser(
+		auth.NewUser(auth.NewUserName(TestUsername, auth.ExternalDatabase), auth.NewRoleName(auth.RoleReadWrite, "test")))
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	startTestServer(t, server)
+
+	supportedMechs := func(client *mongo.Client) ([]string, error) {
+		var res struct {
+			Mechs []string `bson:"saslSupportedMechs"`
+		}
+		err := client.Database("admin").RunCommand(context.TODO(),
+			bson.D{{Key: "hello", Value: 1}, {Key: "saslSupportedMechs", Value: "$external." + TestUsername}}).Decode(&res)
+		return res.Mechs, err
+	}
+
+	find := func(client *mongo.Client) error {
+		err := client.Database("test").Collection("plain").FindOne(context.TODO(), bson.D{}).Err()
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return nil
+		}
+		return err
+	}
+
+	client := connectTestClient(t)
+
+	// PLAIN is refused on non-TLS connections unless it is allowed
+
+	mechs, err := supportedMechs(client)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	if slices.Contains(mechs, "PLAIN") {
+		t.Errorf("PLAIN is advertised on non-TLS connection : %v", mechs)
+	}
+
+	plainClient := connectTestClient(t,
+		options.Client().SetAuth(options.Credential{AuthMechanism: "PLAIN", Username: TestUsername, Password: TestPassword}))
+
+	// The driver wraps the authentication error in a connection error.
+	err = find(plainClient)
+	if err == nil || !strings.Contains(err.Error(), "MechanismUnavailable") {
+		t.Errorf("%v is not a mechanism unavailable error", err)
+	}
+
+	// PLAIN is accepted on non-TLS connections when it is explicitly allowed
+
+	server.SetPlainWithoutTLSAllowed(true)
+
+	mechs, err = supportedMechs(client)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	if !slices.Contains(mechs, "PLAIN") {
+		t.Errorf("PLAIN is not advertised : %v", mechs)
+	}
+
+	err = find(plainClient)
+	if err != nil {
+		t.Error(err)
+	}
+}
@@ -0,0 +1,327 @@
+// Copyright (C) 2022 The go-mongo Authors All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package mongotest
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/cybergarage/go-logger/log"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+func TestRoleBasedAccessControlServer(t *testing.T) {
+	log.EnableStdoutDebug(true)
+
+	server := NewServer()
+	server.SetAuthrizationEnabled(true)
+	startTestServer(t, server)
+
+	connect := func(t *testing.T, username string, password string) *mongo.Client {
+		t.Helper()
+		clientOptions := options.Client().ApplyURI(testDBURL)
+		clientOptions.SetAuth(options.Credential{Username: username, Password: password, AuthSource: "test"})
+		client, err := mongo.Connect(context.TODO(), clientOptions)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return client
+	}
+
+	isUnauthorized := func(err error) bool {
+		var cmdErr mongo.CommandError
+		return errors.As(err, &cmdErr) && cmdErr.Code == 13
+	}
+
+	rootClient, err := mongo.Connect(context.TODO(),
+		options.Client().ApplyURI(testDBURL).SetAuth(options.Credential{Username: TestUsername, Password: TestPassword}))
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	defer rootClient.Disconnect(context.TODO())
+
+	db := rootClient.Database("test")
+	trainer := Trainer{"Misty", 12, "Cerulean City"}
+
+	// The root user can manage users and roles
+
+	cmds := []bson.D{
+		{{Key: "createUser", Value: "reader"}, {Key: "pwd", Value: "readerpass"}, {Key: "roles", Value: bson.A{"read"}}},
+		{{Key: "createUser", Value: "writer"}, {Key: "pwd", Value: "writerpass"}, {Key: "roles", Value: bson.A{bson.D{{Key: "role", Value: "readWrite"}, {Key: "db", Value: "test"}}}}},
+		{{Key: "createRole", Value: "inserter"}, {Key: "privileges", Value: bson.A{
+			bson.D{{Key: "resource", Value: bson.D{{Key: "db", Value: "test"}, {Key: "collection", Value: "rbac"}}}, {Key: "actions", Value: bson.A{"insert"}}},
+		}}, {Key: "roles", Value: bson.A{}}},
+	}
+	for _, cmd := range cmds {
+		err := db.RunCommand(context.TODO(), cmd).Err()
+		if err != nil {
+			t.Error(err)
+			return
+		}
+	}
+
+	err = db.RunCommand(context.TODO(), bson.D{{Key: "createUser", Value: "reader"}, {Key: "roles", Value: bson.A{}}}).Err()
+	var cmdErr mongo.CommandError
+	if !errors.As(err, &cmdErr) || cmdErr.Code != 51003 {
+		t.Errorf("%v is not a user already exists error", err)
+	}
+
+	err = db.RunCommand(context.TODO(), bson.D{{Key: "createUser", Value: "unknown"}, {Key: "roles", Value: bson.A{"unknown"}}}).Err()
+	if !errors.As(err, &cmdErr) || cmdErr.Code != 31 {
+		t.Errorf("%v is not a role not found error", err)
+	}
+
+	var usersInfo struct {
+		Users []struct {
+			User  string `bson:"user"`
+			DB    string `bson:"db"`
+			Roles []struct {
+				Role string `bson:"role"`
+				DB   string `bson:"db"`
+			} `bson:"roles"`
+		} `bson:"users"`
+	}
+	err = db.RunCommand(context.TODO(), bson.D{{Key: "usersInfo", Value: 1}}).Decode(&usersInfo)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	if len(usersInfo.Users) != 2 || usersInfo.Users[0].User != "reader" || usersInfo.Users[0].Roles[0].Role != "read" {
+		t.Errorf("invalid users : %v", usersInfo)
+	}
+
+	var rolesInfo struct {
+		Roles []struct {
+			Role      string `bson:"role"`
+			IsBuiltin bool   `bson:"isBuiltin"`
+		} `bson:"roles"`
+	}
+	err = db.RunCommand(context.TODO(), bson.D{{Key: "rolesInfo", Value: "inserter"}, {Key: "showPrivileges", Value: true}}).Decode(&rolesInfo)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	if len(rolesInfo.Roles) != 1 || rolesInfo.Roles[0].Role != "inserter" || rolesInfo.Roles[0].IsBuiltin {
+		t.Errorf("invalid roles : %v", rolesInfo)
+	}
+
+	// The read role can find documents but can not insert them
+
+	t.Run("read", func(t *testing.T) {
+		client := connect(t, "reader", "readerpass")
+		defer client.Disconnect(context.TODO())
+
+		collection := client.Database("test").Collection("rbac")
+		_, err := collection.InsertOne(context.TODO(), trainer)
+		if !isUnauthorized(err) {
+			t.Errorf("%v is not an unauthorized error", err)
+		}
+
+		err = collection.FindOne(context.TODO(), bson.D{}).Err()
+		if err != nil && !errors.Is(err, mongo.ErrNoDocuments) {
+			t.Error(err)
+		}
+
+		err = client.Database("test").RunCommand(context.TODO(), bson.D{{Key: "usersInfo", Value: 1}}).Err()
+		if !isUnauthorized(err) {
+			t.Errorf("%v is not an unauthorized error", err)
+		}
+
+		// Commands are denied without the privilege actions, and unmapped commands are denied by default
+
+		for _, cmd := range []bson.D{
+			{{Key: "drop", Value: "rbac"}},
+			{{Key: "dropDatabase", Value: 1}},
+			{{Key: "createIndexes", Value: "rbac"}},
+			{{Key: "unknownTest", Value: 1}},
+		} {
+			err = client.Database("test").RunCommand(context.TODO(), cmd).Err()
+			if !isUnauthorized(err) {
+				t.Errorf("%v : %v is not an unauthorized error", cmd, err)
+			}
+		}
+
+		err = client.Database("test").RunCommand(context.TODO(), bson.D{{Key: "listCollections", Value: 1}}).Err()
+		if isUnauthorized(err) {
+			t.Errorf("listCollections is not allowed : %v", err)
+		}
+
+		// currentOp requires the inprog privilege except for the own operations
+
+		err = client.Database("admin").RunCommand(context.TODO(), bson.D{{Key: "currentOp", Value: 1}}).Err()
+		if !isUnauthorized(err) {
+			t.Errorf("%v is not an unauthorized error", err)
+		}
+		err = client.Database("admin").RunCommand(context.TODO(), bson.D{{Key: "currentOp", Value: 1}, {Key: "$ownOps", Value: true}}).Err()
+		if err != nil {
+			t.Errorf("currentOp with $ownOps is not allowed : %v", err)
+		}
+	})
+
+	// The readWrite role can insert and find documents
+
+	t.Run("readWrite", func(t *testing.T) {
+		client := connect(t, "writer", "writerpass")
+		defer client.Disconnect(context.TODO())
+
+		collection := client.Database("test").Collection("rbac")
+		_, err := collection.InsertOne(context.TODO(), trainer)
+		if err != nil {
+			t.Error(err)
+			return
+		}
+
+		var result Trainer
+		err = collection.FindOne(context.TODO(), bson.D{{Key: "name", Value: trainer.Name}}).Decode(&result)
+		if err != nil {
+			t.Error(err)
+			return
+		}
+		if result != trainer {
+			t.Errorf("%v != %v", result, trainer)
+		}
+
+		// The following batches of the cursor are allowed
+
+		for _, trainer := range []Trainer{{"Tracey", 10, "Pallet Town"}, {"Todd", 12, "Pallet Town"}} {
+			_, err = collection.InsertOne(context.TODO(), trainer)
+			if err != nil {
+				t.Error(err)
+				return
+			}
+		}
+		cursor, err := collection.Find(context.TODO(), bson.D{}, options.Find().SetBatchSize(1))
+		if err != nil {
+			t.Error(err)
+			return
+		}
+		n := 0
+		for cursor.Next(context.TODO()) {
+			n++
+		}
+		if err := cursor.Err(); err != nil || n != 3 {
+			t.Errorf("%d != %d (%v)", n, 3, err)
+		}
+		if err := cursor.Close(context.TODO()); err != nil {
+			t.Error(err)
+		}
+	})
+
+	// Granted roles take effect and revoked roles are removed
+
+	t.Run("grant and revoke", func(t *testing.T) {
+		err := db.RunCommand(context.TODO(), bson.D{{Key: "grantRolesToUser", Value: "reader"}, {Key: "roles", Value: bson.A{"inserter"}}}).Err()
+		if err != nil {
+			t.Error(err)
+			return
+		}
+
+		client := connect(t, "reader", "readerpass")
+		defer client.Disconnect(context.TODO())
+
+		collection := client.Database("test").Collection("rbac")
+		_, err = collection.InsertOne(context.TODO(), Trainer{"Brock", 15, "Pewter City"})
+		if err != nil {
+			t.Error(err)
+			return
+		}
+
+		err = db.RunCommand(context.TODO(), bson.D{{Key: "revokeRolesFromUser", Value: "reader"}, {Key: "roles", Value: bson.A{"inserter"}}}).Err()
+		if err != nil {
+			t.Error(err)
+			return
+		}
+
+		_, err = collection.InsertOne(context.TODO(), Trainer{"Gary", 10, "Pallet Town"})
+		if !isUnauthorized(err) {
+			t.Errorf("%v is not an unauthorized error", err)
+		}
+	})
+
+	// The userAdmin role can manage the users and roles of the database, but can not grant roles of other databases
+
+	t.Run("userAdmin", func(t *testing.T) {
+		err := db.RunCommand(context.TODO(), bson.D{{Key: "createUser", Value: "useradmin"}, {Key: "pwd", Value: "useradminpass"}, {Key: "roles", Value: bson.A{"userAdmin"}}}).Err()
+		if err != nil {
+			t.Error(err)
+			return
+		}
+
+		client := connect(t, "useradmin", "useradminpass")
+		defer client.Disconnect(context.TODO())
+
+		userAdminDB := client.Database("test")
+		err = userAdminDB.RunCommand(context.TODO(), bson.D{{Key: "createUser", Value: "viewer"}, {Key: "pwd", Value: "viewerpass"}, {Key: "roles", Value: bson.A{"read"}}}).Err()
+		if err != nil {
+			t.Error(err)
+			return
+		}
+
+		rootRole := bson.D{{Key: "role", Value: "root"}, {Key: "db", Value: "admin"}}
+		privilege := func(db string, collection string, action string) bson.D {
+			return bson.D{{Key: "resource", Value: bson.D{{Key: "db", Value: db}, {Key: "collection", Value: collection}}}, {Key: "actions", Value: bson.A{action}}}
+		}
+		for _, cmd := range []bson.D{
+			{{Key: "grantRolesToUser", Value: "useradmin"}, {Key: "roles", Value: bson.A{rootRole}}},
+			{{Key: "grantRolesToUser", Value: "viewer"}, {Key: "roles", Value: bson.A{rootRole}}},
+			{{Key: "createUser", Value: "escalated"}, {Key: "pwd", Value: "escalatedpass"}, {Key: "roles", Value: bson.A{rootRole}}},
+			{{Key: "updateUser", Value: "viewer"}, {Key: "roles", Value: bson.A{rootRole}}},
+			{{Key: "createRole", Value: "escalated"}, {Key: "privileges", Value: bson.A{}}, {Key: "roles", Value: bson.A{rootRole}}},
+			{{Key: "createRole", Value: "escalated"}, {Key: "privileges", Value: bson.A{privilege("", "", "anyAction")}}, {Key: "roles", Value: bson.A{}}},
+			{{Key: "createRole", Value: "escalated"}, {Key: "privileges", Value: bson.A{privilege("admin", "", "find")}}, {Key: "roles", Value: bson.A{}}},
+		} {
+			err = userAdminDB.RunCommand(context.TODO(), cmd).Err()
+			if !isUnauthorized(err) {
+				t.Errorf("%v : %v is not an unauthorized error", cmd, err)
+			}
+		}
+
+		// Unknown actions are rejected
+
+		err = userAdminDB.RunCommand(context.TODO(), bson.D{{Key: "createRole", Value: "unknown"}, {Key: "privileges", Value: bson.A{privilege("test", "", "unknownAction")}}, {Key: "roles", Value: bson.A{}}}).Err()
+		if !errors.As(err, &cmdErr) || cmdErr.Code != 2 {
+			t.Errorf("%v is not a bad value error", err)
+		}
+
+		// Only roles on the admin database can target other databases
+
+		err = rootClient.Database("admin").RunCommand(context.TODO(), bson.D{{Key: "createRole", Value: "finder"}, {Key: "privileges", Value: bson.A{privilege("", "", "find")}}, {Key: "roles", Value: bson.A{}}}).Err()
+		if err != nil {
+			t.Error(err)
+		}
+	})
+
+	// Dropped users can not authenticate
+
+	t.Run("drop", func(t *testing.T) {
+		err := db.RunCommand(context.TODO(), bson.D{{Key: "dropUser", Value: "writer"}}).Err()
+		if err != nil {
+			t.Error(err)
+			return
+		}
+
+		client := connect(t, "writer", "writerpass")
+		defer client.Disconnect(context.TODO())
+
+		err = client.Ping(context.TODO(), nil)
+		if err == nil {
+			t.Errorf("dropped user is authenticated")
+		}
+	})
+}
@@ -0,0 +1,264 @@
+// Copyright (C) 2022 The go-mongo Authors All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package mongotest
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/cybergarage/go-logger/log"
+	"github.com/cybergarage/go-mongo/mongo/auth"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"go.mongodb.org/mongo-driver/x/bsonx/bsoncore"
+
+	xgoscram "github.com/xdg-go/scram"
+)
+
+func TestSCRAMCredentialStoreServer(t *testing.T) {
+	log.EnableStdoutDebug(true)
+
+	const iterationCount = 5000
+
+	store := auth.NewSCRAMCredentialStore()
+	err := store.UpdateCredential(auth.NewUserName(TestUsername, auth.AdminDatabase), TestPassword)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	server := NewServer()
+	server.SetCredentialStore(store)
+	err = server.SetSCRAMIterationCount(iterationCount)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	server.SetAuthrizationEnabled(true)
+	startTestServer(t, server)
+
+	// Iteration counts less than the minimum are rejected
+
+	if err := server.SetSCRAMIterationCount(4095); err == nil {
+		t.Errorf("invalid iteration count is accepted")
+	}
+	if n := server.SCRAMIterationCount(); n != iterationCount {
+		t.Errorf("%d != %d", n, iterationCount)
+	}
+
+	ping := func(username string, password string, mech string) error {
+		clientOptions := options.Client().ApplyURI(testDBURL)
+		clientOptions.SetAuth(options.Credential{Username: username, Password: password, AuthMechanism: mech})
+		client, err := mongo.Connect(context.TODO(), clientOptions)
+		if err != nil {
+			return err
+		}
+		defer client.Disconnect(context.TODO())
+		return client.Database("admin").RunCommand(context.TODO(), bson.D{{Key: "usersInfo", Value: 1}}).Err()
+	}
+
+	mechs := []string{"SCRAM-SHA-1", "SCRAM-SHA-256"}
+
+	for _, mech := range mechs {
+		err := ping(TestUsername, TestPassword, mech)
+		if err != nil {
+			t.Errorf("%s : %s", mech, err)
+		}
+		err = ping(TestUsername, "invalid", mech)
+		if err == nil {
+			t.Errorf("%s : invalid password is authenticated", mech)
+		}
+	}
+
+	// Passwords of new users are stored as salted credentials with the configured iteration count
+
+	rootClient := connectTestClient(t, options.Client().SetAuth(options.Credential{Username: TestUsername, Password: TestPassword}))
+
+	const username = "scramuser"
+	const password = "scrampass"
+
+	err = rootClient.Database("admin").RunCommand(context.TODO(),
+		bson.D{{Key: "createUser", Value: username}, {Key: "pwd", Value: password}, {Key: "roles", Value: bson.A{"root"}}}).Err()
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	for _, mech := range mechs {
+		cred, ok, err := store.LookupSCRAMCredential(mech, auth.AdminDatabase, username)
+		if err != nil || !ok {
+			t.Errorf("%s credential is not found (%v)", mech, err)
+			continue
+		}
+		if cred.IterationCount != iterationCount {
+			t.Errorf("%d != %d", cred.IterationCount, iterationCount)
+		}
+		err = ping(username, password, mech)
+		if err != nil {
+			t.Errorf("%s : %s", mech, err)
+		}
+	}
+
+	// Non-ASCII passwords are prepared with SASLprep for SCRAM-SHA-256 as the clients do
+
+	const preppedUsername = "preppeduser"
+	const preppedPassword = "I\u2168\u00A0pass"
+
+	err = rootClient.Database("admin").RunCommand(context.TODO(),
+		bson.D{{Key: "createUser", Value: preppedUsername}, {Key: "pwd", Value: preppedPassword}, {Key: "roles", Value: bson.A{"root"}}}).Err()
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	for _, mech := range mechs {
+		err = ping(preppedUsername, preppedPassword, mech)
+		if err != nil {
+			t.Errorf("%s : %s", mech, err)
+		}
+	}
+
+	// Saved credentials have no cleartext passwords and can be loaded again
+
+	credFile := filepath.Join(t.TempDir(), "credentials.json")
+	err = store.SaveFile(credFile)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	credBytes, err := os.ReadFile(credFile)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	for _, password := range []string{TestPassword, password} {
+		if strings.Contains(string(credBytes), password) {
+			t.Errorf("saved credentials have a cleartext password : %s", password)
+		}
+	}
+
+	loadedStore := auth.NewSCRAMCredentialStore()
+	err = loadedStore.LoadFile(credFile)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	server.SetCredentialStore(loadedStore)
+
+	for _, mech := range mechs {
+		err := ping(username, password, mech)
+		if err != nil {
+			t.Errorf("%s : %s", mech, err)
+		}
+	}
+
+	// Dropped users are removed from the store
+
+	err = rootClient.Database("admin").RunCommand(context.TODO(), bson.D{{Key: "dropUser", Value: username}}).Err()
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	if _, ok, _ := loadedStore.LookupSCRAMCredential(mechs[0], auth.AdminDatabase, username); ok {
+		t.Errorf("%s credential is not removed", username)
+	}
+
+	// Users of the same name in other databases have their own credentials
+
+	for _, db := range []string{"admin", "test"} {
+		err = rootClient.Database(db).RunCommand(context.TODO(),
+			bson.D{{Key: "createUser", Value: username}, {Key: "pwd", Value: db + password}, {Key: "roles", Value: bson.A{bson.D{{Key: "role", Value: "root"}, {Key: "db", Value: "admin"}}}}}).Err()
+		if err != nil {
+			t.Error(err)
+			return
+		}
+	}
+	err = rootClient.Database("test").RunCommand(context.TODO(), bson.D{{Key: "dropUser", Value: username}}).Err()
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	if _, ok, _ := loadedStore.LookupSCRAMCredential(mechs[0], "test", username); ok {
+		t.Errorf("%s@test credential is not removed", username)
+	}
+	for _, mech := range mechs {
+		err := ping(username, "admin"+password, mech)
+		if err != nil {
+			t.Errorf("%s : %s@admin is not authenticated (%s)", mech, username, err)
+		}
+	}
+
+	// Unknown users receive the same salt and the configured iteration count as the stored users
+
+	serverFirstMessage := func(username string) (map[string]string, error) {
+		client, err := xgoscram.SHA256.NewClient(username, "invalid", "")
+		if err != nil {
+			return nil, err
+		}
+		clientMsg, err := client.NewConversation().Step("")
+		if err != nil {
+			return nil, err
+		}
+		res, err := runTestCommand(dialTestServer(t), bsoncore.NewDocumentBuilder().
+			AppendInt32("saslStart", 1).
+			AppendString("mechanism", "SCRAM-SHA-256").
+			AppendBinary("payload", 0, []byte(clientMsg)).
+			AppendString("$db", auth.AdminDatabase).
+			Build())
+		if err != nil {
+			return nil, err
+		}
+		_, payload, ok := res.Lookup("payload").BinaryOK()
+		if !ok {
+			return nil, fmt.Errorf("no server first message : %s", res.String())
+		}
+		attrs := map[string]string{}
+		for _, attr := range strings.Split(string(payload), ",") {
+			if key, val, ok := strings.Cut(attr, "="); ok {
+				attrs[key] = val
+			}
+		}
+		return attrs, nil
+	}
+
+	firstMsg, err := serverFirstMessage("unknown")
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	if firstMsg["i"] != strconv.Itoa(iterationCount) || len(firstMsg["s"]) == 0 {
+		t.Errorf("unknown user is disclosed : %v", firstMsg)
+	}
+	secondMsg, err := serverFirstMessage("unknown")
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	if secondMsg["s"] != firstMsg["s"] {
+		t.Errorf("%s != %s", secondMsg["s"], firstMsg["s"])
+	}
+	for _, mech := range mechs {
+		if err := ping("unknown", "invalid", mech); err == nil {
+			t.Errorf("%s : unknown user is authenticated", mech)
+		}
+	}
+}
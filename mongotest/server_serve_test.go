@@ -0,0 +1,200 @@
+// Copyright (C) 2022 The go-mongo Authors All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package mongotest
+
+import (
+	"context"
+	"net"
+	"runtime"
+	"testing"
+	"time"
+
+	"github.com/cybergarage/go-logger/log"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"go.mongodb.org/mongo-driver/x/bsonx/bsoncore"
+)
+
+func TestServeServer(t *testing.T) {
+	log.EnableStdoutDebug(true)
+
+	server := NewServer()
+
+	hello := func(conn net.Conn) error {
+		_, err := runTestCommand(conn, bsoncore.NewDocumentBuilder().
+			AppendInt32("hello", 1).
+			AppendString("$db", "admin").
+			Build())
+		return err
+	}
+
+	// A connection of net.Pipe is served without listening
+
+	t.Run("ServeConn", func(t *testing.T) {
+		clientConn, serverConn := net.Pipe()
+		served := make(chan error, 1)
+		go func() {
+			served <- server.ServeConn(serverConn)
+		}()
+
+		err := hello(clientConn)
+		if err != nil {
+			t.Error(err)
+			return
+		}
+
+		clientConn.Close()
+		select {
+		case err := <-served:
+			if err != nil {
+				t.Error(err)
+			}
+		case <-time.After(time.Second):
+			t.Errorf("ServeConn does not return after the connection is closed")
+		}
+	})
+
+	// A caller-provided listener is served, and closed when the server stops
+
+	t.Run("Serve", func(t *testing.T) {
+		l, err := net.Listen("tcp", "127.0.0.1:0")
+		if err != nil {
+			t.Error(err)
+			return
+		}
+		served := make(chan error, 1)
+		go func() {
+			served <- server.Serve(l)
+		}()
+
+		client, err := mongo.Connect(context.TODO(), options.Client().ApplyURI("mongodb://"+l.Addr().String()))
+		if err != nil {
+			t.Error(err)
+			return
+		}
+		defer client.Disconnect(context.TODO())
+
+		trainer := Trainer{"Misty", 16, "Cerulean City"}
+		collection := client.Database("test").Collection("serve")
+		_, err = collection.InsertOne(context.TODO(), trainer)
+		if err != nil {
+			t.Error(err)
+			return
+		}
+		var result Trainer
+		err = collection.FindOne(context.TODO(), bson.D{{Key: "name", Value: trainer.Name}}).Decode(&result)
+		if err != nil {
+			t.Error(err)
+			return
+		}
+		if result != trainer {
+			t.Errorf("%v != %v", result, trainer)
+		}
+
+		err = server.Stop()
+		if err != nil {
+			t.Error(err)
+			return
+		}
+		select {
+		case err := <-served:
+			if err != nil {
+				t.Error(err)
+			}
+		case <-time.After(time.Second):
+			t.Errorf("Serve does not return after the server stops")
+		}
+	})
+
+	// A listener served before Start is kept, and closed when the server stops
+
+	t.Run("Serve and Start", func(t *testing.T) {
+		l, err := net.Listen("tcp", "127.0.0.1:0")
+		if err != nil {
+			t.Error(err)
+			return
+		}
+		served := make(chan error, 1)
+		go func() {
+			served <- server.Serve(l)
+		}()
+
+		conn, err := net.Dial("tcp", l.Addr().String())
+		if err != nil {
+			t.Error(err)
+			return
+		}
+		defer conn.Close()
+		err = hello(conn)
+		if err != nil {
+			t.Error(err)
+			return
+		}
+
+		err = server.Start()
+		if err != nil {
+			t.Error(err)
+			return
+		}
+		err = server.Stop()
+		if err != nil {
+			t.Error(err)
+			return
+		}
+		select {
+		case err := <-served:
+			if err != nil {
+				t.Error(err)
+			}
+		case <-time.After(time.Second):
+			t.Errorf("Serve does not return after the server stops")
+		}
+	})
+}
+
+func TestStartFailureServer(t *testing.T) {
+	log.EnableStdoutDebug(true)
+
+	// The port of the default endpoint is occupied to fail the start
+
+	l, err := net.Listen("tcp", "localhost:27017")
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	defer l.Close()
+
+	nGoroutines := runtime.NumGoroutine()
+
+	server := NewServer()
+	server.SetIdleTimeout(time.Minute)
+	server.SetCursorTimeout(time.Minute)
+	if err := server.Start(); err == nil {
+		server.Stop()
+		t.Errorf("server is started on the occupied port")
+		return
+	}
+
+	// The reapers started before the failure are stopped
+
+	for range 100 {
+		if runtime.NumGoroutine() <= nGoroutines {
+			return
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	t.Errorf("%d goroutines are leaked", runtime.NumGoroutine()-nGoroutines)
+}
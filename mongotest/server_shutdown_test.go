@@ -0,0 +1,182 @@
+// Copyright (C) 2022 The go-mongo Authors All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package mongotest
+
+import (
+	"context"
+	"errors"
+	"net"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/cybergarage/go-logger/log"
+	mongod "github.com/cybergarage/go-mongo/mongo"
+	"github.com/cybergarage/go-mongo/mongo/protocol"
+	"go.mongodb.org/mongo-driver/x/bsonx/bsoncore"
+)
+
+// testBlockingListener blocks the armed request for the delay to keep it in flight.
+type testBlockingListener struct {
+	armed   atomic.Bool
+	entered chan struct{}
+	delay   time.Duration
+}
+
+func (l *testBlockingListener) MessageReceived(msg mongod.OpMessage) {
+	if l.armed.CompareAndSwap(true, false) {
+		l.entered <- struct{}{}
+		time.Sleep(l.delay)
+	}
+}
+
+func (l *testBlockingListener) MessageRespond(msg mongod.OpMessage) {
+}
+
+func TestShutdownServer(t *testing.T) {
+	log.EnableStdoutDebug(true)
+
+	listener := &testBlockingListener{
+		armed:   atomic.Bool{},
+		entered: make(chan struct{}, 1),
+		delay:   0,
+	}
+
+	server := NewServer()
+	server.SetMessageListener(listener)
+
+	helloMsg := func() []byte {
+		return protocol.NewMsgWithBody(bsoncore.NewDocumentBuilder().
+			AppendInt32("hello", 1).
+			AppendString("$db", "admin").
+			Build()).Bytes()
+	}
+
+	hello := func(conn net.Conn) (bsoncore.Document, error) {
+		_, err := conn.Write(helloMsg())
+		if err != nil {
+			return nil, err
+		}
+		return readTestReplyDocument(conn)
+	}
+
+	type shutdownResult struct {
+		res *mongod.ShutdownResult
+		err error
+	}
+
+	// startShutdown starts the server, keeps a request of the first connection in flight for the delay,
+	// and shuts down the server with the timeout while the request is in flight.
+	startShutdown := func(t *testing.T, delay time.Duration, timeout time.Duration) (net.Conn, net.Conn, chan shutdownResult) {
+		t.Helper()
+		err := server.Start()
+		if err != nil {
+			t.Fatal(err)
+		}
+		conns := []net.Conn{}
+		for n := 0; n < 2; n++ {
+			conn, err := net.Dial("tcp", "localhost:27017")
+			if err != nil {
+				t.Fatal(err)
+			}
+			t.Cleanup(func() { conn.Close() })
+			if _, err := hello(conn); err != nil {
+				t.Fatal(err)
+			}
+			conns = append(conns, conn)
+		}
+
+		listener.delay = delay
+		listener.armed.Store(true)
+		if _, err := conns[0].Write(helloMsg()); err != nil {
+			t.Fatal(err)
+		}
+		<-listener.entered
+
+		done := make(chan shutdownResult, 1)
+		go func() {
+			ctx, cancel := context.WithTimeout(context.Background(), timeout)
+			defer cancel()
+			res, err := server.Shutdown(ctx)
+			done <- shutdownResult{res: res, err: err}
+		}()
+		time.Sleep(100 * time.Millisecond)
+
+		return conns[0], conns[1], done
+	}
+
+	// The in-flight request is drained, and new requests are refused
+
+	t.Run("Drain", func(t *testing.T) {
+		inFlightConn, idleConn, done := startShutdown(t, time.Second, 5*time.Second)
+
+		res, err := hello(idleConn)
+		if err != nil {
+			t.Error(err)
+			return
+		}
+		if code, _ := res.Lookup("code").Int32OK(); code != int32(mongod.ErrorCodeShutdownInProgress) {
+			t.Errorf("new request is not refused : %s", res.String())
+		}
+
+		conn, err := net.Dial("tcp", "localhost:27017")
+		if err == nil {
+			conn.Close()
+			t.Errorf("new connection is accepted while shutting down")
+		}
+
+		res, err = readTestReplyDocument(inFlightConn)
+		if err != nil {
+			t.Error(err)
+			return
+		}
+		if _, ok := res.Lookup("code").Int32OK(); ok {
+			t.Errorf("in-flight request is not drained : %s", res.String())
+		}
+
+		result := <-done
+		if result.err != nil {
+			t.Error(result.err)
+			return
+		}
+		if result.res.Drained != 1 || result.res.ForceClosed != 0 {
+			t.Errorf("%+v", *result.res)
+		}
+
+		_, err = readTestReplyDocument(idleConn)
+		if err == nil {
+			t.Errorf("remaining connection is not closed")
+		}
+	})
+
+	// The in-flight request is force closed when the context is done
+
+	t.Run("ForceClose", func(t *testing.T) {
+		inFlightConn, _, done := startShutdown(t, 2*time.Second, 200*time.Millisecond)
+
+		result := <-done
+		if !errors.Is(result.err, context.DeadlineExceeded) {
+			t.Errorf("%v", result.err)
+		}
+		if result.res == nil || result.res.Drained != 0 || result.res.ForceClosed != 1 {
+			t.Errorf("%+v", result.res)
+		}
+
+		_, err := readTestReplyDocument(inFlightConn)
+		if err == nil {
+			t.Errorf("in-flight connection is not closed")
+		}
+	})
+}
@@ -0,0 +1,167 @@
+// Copyright (C) 2022 The go-mongo Authors All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package mongotest
+
+import (
+	"testing"
+
+	"github.com/cybergarage/go-logger/log"
+	"github.com/cybergarage/go-mongo/mongo/auth"
+	"github.com/cybergarage/go-mongo/mongo/protocol"
+	"go.mongodb.org/mongo-driver/x/bsonx/bsoncore"
+
+	xgoscram "github.com/xdg-go/scram"
+)
+
+func TestSpeculativeAuthenticationServer(t *testing.T) {
+	log.EnableStdoutDebug(true)
+
+	// The SCRAM credentials are looked up in the database of the user
+
+	store := auth.NewSCRAMCredentialStore()
+	err := store.UpdateCredential(auth.NewUserName(TestUsername, auth.AdminDatabase), TestPassword)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	server := NewServer()
+	server.SetCredentialStore(store)
+	server.SetAuthrizationEnabled(true)
+	startTestServer(t, server)
+
+	conn := dialTestServer(t)
+
+	requestID := int32(0)
+	runCommand := func(cmd bsoncore.Document) (bsoncore.Document, error) {
+		requestID++
+		reqMsg := protocol.NewMsgWithBody(cmd)
+		reqMsg.SetRequestID(requestID)
+		_, err := conn.Write(reqMsg.Bytes())
+		if err != nil {
+			return nil, err
+		}
+		return readTestReplyDocument(conn)
+	}
+
+	client, err := xgoscram.SHA256.NewClient(TestUsername, TestPassword, "")
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	conv := client.NewConversation()
+	clientMsg, err := conv.Step("")
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	// Unsupported mechanisms are omitted from the hello reply
+
+	res, err := runCommand(bsoncore.NewDocumentBuilder().
+		AppendInt32("hello", 1).
+		AppendDocument("speculativeAuthenticate", bsoncore.NewDocumentBuilder().
+			AppendInt32("saslStart", 1).
+			AppendString("mechanism", "UNKNOWN").
+			AppendBinary("payload", 0, []byte(clientMsg)).
+			AppendString("db", "admin").
+			Build()).
+		AppendString("$db", "admin").
+		Build())
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	if _, err := res.LookupErr("speculativeAuthenticate"); err == nil {
+		t.Errorf("unsupported mechanism is authenticated : %s", res.String())
+		return
+	}
+
+	// The hello reply has the server first message of the embedded saslStart, which runs on the database of hello without 'db'
+
+	res, err = runCommand(bsoncore.NewDocumentBuilder().
+		AppendInt32("hello", 1).
+		AppendDocument("speculativeAuthenticate", bsoncore.NewDocumentBuilder().
+			AppendInt32("saslStart", 1).
+			AppendString("mechanism", "SCRAM-SHA-256").
+			AppendBinary("payload", 0, []byte(clientMsg)).
+			AppendDocument("options", bsoncore.NewDocumentBuilder().AppendBoolean("skipEmptyExchange", true).Build()).
+			Build()).
+		AppendString("$db", "admin").
+		Build())
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	specAuth, ok := res.Lookup("speculativeAuthenticate").DocumentOK()
+	if !ok {
+		t.Errorf("no speculative authentication : %s", res.String())
+		return
+	}
+	conversationID, ok := specAuth.Lookup("conversationId").Int32OK()
+	if !ok {
+		t.Errorf("no conversation ID : %s", specAuth.String())
+		return
+	}
+	_, serverMsg, ok := specAuth.Lookup("payload").BinaryOK()
+	if !ok {
+		t.Errorf("no payload : %s", specAuth.String())
+		return
+	}
+
+	// The conversation continues with saslContinue
+
+	clientMsg, err = conv.Step(string(serverMsg))
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	res, err = runCommand(bsoncore.NewDocumentBuilder().
+		AppendInt32("saslContinue", 1).
+		AppendInt32("conversationId", conversationID).
+		AppendBinary("payload", 0, []byte(clientMsg)).
+		AppendString("$db", "admin").
+		Build())
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	if done, _ := res.Lookup("done").BooleanOK(); !done {
+		t.Errorf("authentication is not done : %s", res.String())
+		return
+	}
+	_, serverMsg, _ = res.Lookup("payload").BinaryOK()
+	_, err = conv.Step(string(serverMsg))
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	// The connection is authenticated
+
+	res, err = runCommand(bsoncore.NewDocumentBuilder().
+		AppendInt32("usersInfo", 1).
+		AppendString("$db", "admin").
+		Build())
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	if ok, _ := res.Lookup("ok").DoubleOK(); ok != 1 {
+		t.Errorf("connection is not authenticated : %s", res.String())
+	}
+}
@@ -0,0 +1,117 @@
+// Copyright (C) 2022 The go-mongo Authors All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package mongotest
+
+import (
+	"crypto/tls"
+	"errors"
+	"io"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/cybergarage/go-logger/log"
+	"go.mongodb.org/mongo-driver/x/bsonx/bsoncore"
+)
+
+// testHandshakeListener reports failed TLS handshakes to the channel.
+type testHandshakeListener struct {
+	errs chan error
+}
+
+func (l *testHandshakeListener) HandshakeFailed(conn net.Conn, err error) {
+	l.errs <- err
+}
+
+func TestTLSHandshakeServer(t *testing.T) {
+	log.EnableStdoutDebug(true)
+
+	listener := &testHandshakeListener{errs: make(chan error, 10)}
+
+	server := NewServer()
+	server.SetTLSEnabled(true)
+	server.SetServerKey(TestSeverKey)
+	server.SetServerCert(TestServerCert)
+	server.SetRootCerts(TestCACert)
+	server.SetTLSHandshakeTimeout(300 * time.Millisecond)
+	server.SetHandshakeListener(listener)
+
+	startTestServer(t, server)
+
+	tlsConfig, err := server.TLSConfig()
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	tlsConfig = tlsConfig.Clone()
+	tlsConfig.ServerName = "localhost"
+
+	hello := func() error {
+		conn, err := tls.Dial("tcp", "localhost:27017", tlsConfig)
+		if err != nil {
+			return err
+		}
+		defer conn.Close()
+		_, err = runTestCommand(conn, bsoncore.NewDocumentBuilder().
+			AppendInt32("hello", 1).
+			AppendString("$db", "admin").
+			Build())
+		return err
+	}
+
+	// A stalled handshake does not block other clients
+
+	stalledConn := dialTestServer(t)
+
+	err = hello()
+	if err != nil {
+		t.Errorf("client is blocked by the stalled handshake : %s", err)
+	}
+
+	// The stalled handshake is closed after the timeout, and reported to the listener
+
+	select {
+	case err := <-listener.errs:
+		if err == nil {
+			t.Errorf("handshake failure is not reported")
+		}
+	case <-time.After(2 * time.Second):
+		t.Errorf("stalled handshake is not timed out")
+	}
+	stalledConn.SetReadDeadline(time.Now().Add(time.Second))
+	if _, err := stalledConn.Read(make([]byte, 1)); !errors.Is(err, io.EOF) {
+		t.Errorf("stalled connection is not closed : %v", err)
+	}
+
+	// A failed handshake closes only the connection
+
+	badConn := dialTestServer(t)
+	_, err = badConn.Write([]byte("not a TLS client hello"))
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	select {
+	case <-listener.errs:
+	case <-time.After(2 * time.Second):
+		t.Errorf("failed handshake is not reported")
+	}
+
+	err = hello()
+	if err != nil {
+		t.Errorf("server stops after the failed handshake : %s", err)
+	}
+}
@@ -0,0 +1,326 @@
+// Copyright (C) 2022 The go-mongo Authors All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package mongotest
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/cybergarage/go-logger/log"
+	"go.mongodb.org/mongo-driver/x/bsonx/bsoncore"
+)
+
+func TestTLSReloadServer(t *testing.T) {
+	log.EnableStdoutDebug(true)
+
+	oldCA, err := newTestCA("go-mongo old CA")
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	newCA, err := newTestCA("go-mongo new CA")
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	dir := t.TempDir()
+	keyFile := filepath.Join(dir, "key.pem")
+	certFile := filepath.Join(dir, "cert.pem")
+	caFile := filepath.Join(dir, "ca.pem")
+
+	// writeCerts writes the server key and certificate issued by the CA, and the root certificates.
+	writeCerts := func(ca *testCA, serial int64, roots ...*testCA) error {
+		certPEM, keyPEM, err := ca.Issue(serial, "localhost", x509.ExtKeyUsageServerAuth)
+		if err != nil {
+			return err
+		}
+		rootPEM := []byte{}
+		for _, root := range roots {
+			rootPEM = append(rootPEM, root.CertPEM()...)
+		}
+		for file, data := range map[string][]byte{keyFile: keyPEM, certFile: certPEM, caFile: rootPEM} {
+			if err := os.WriteFile(file, data, 0o600); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	err = writeCerts(oldCA, 2, oldCA)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	newClientCert := func(ca *testCA) (tls.Certificate, error) {
+		certPEM, keyPEM, err := ca.Issue(100, "client", x509.ExtKeyUsageClientAuth)
+		if err != nil {
+			return tls.Certificate{}, err
+		}
+		return tls.X509KeyPair(certPEM, keyPEM)
+	}
+	oldClientCert, err := newClientCert(oldCA)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	newCAClientCert, err := newClientCert(newCA)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	server := NewServer()
+	server.SetTLSEnabled(true)
+	server.SetClientAuthType(tls.RequireAndVerifyClientCert)
+	for _, set := range []func() error{
+		func() error { return server.SetServerKeyFile(keyFile) },
+		func() error { return server.SetServerCertFile(certFile) },
+		func() error { return server.SetRootCertFiles(caFile) },
+	} {
+		if err := set(); err != nil {
+			t.Error(err)
+			return
+		}
+	}
+
+	startTestServer(t, server)
+
+	rootCAs := x509.NewCertPool()
+	rootCAs.AddCert(oldCA.cert)
+	rootCAs.AddCert(newCA.cert)
+
+	dial := func(clientCert tls.Certificate) (*tls.Conn, error) {
+		return tls.Dial("tcp", "localhost:27017", &tls.Config{
+			MinVersion:   tls.VersionTLS12,
+			RootCAs:      rootCAs,
+			Certificates: []tls.Certificate{clientCert},
+			ServerName:   "localhost",
+		})
+	}
+
+	hello := func(conn *tls.Conn) error {
+		_, err := runTestCommand(conn, bsoncore.NewDocumentBuilder().
+			AppendInt32("hello", 1).
+			AppendString("$db", "admin").
+			Build())
+		return err
+	}
+
+	// serverSerial returns the serial number of the server certificate of a new connection.
+	serverSerial := func(clientCert tls.Certificate) (int64, error) {
+		conn, err := dial(clientCert)
+		if err != nil {
+			return 0, err
+		}
+		defer conn.Close()
+		if err := hello(conn); err != nil {
+			return 0, err
+		}
+		return conn.ConnectionState().PeerCertificates[0].SerialNumber.Int64(), nil
+	}
+
+	existingConn, err := dial(oldClientCert)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	defer existingConn.Close()
+	if err := hello(existingConn); err != nil {
+		t.Error(err)
+		return
+	}
+
+	if _, err := serverSerial(newCAClientCert); err == nil {
+		t.Errorf("client certificate of the untrusted CA is accepted")
+	}
+
+	// New handshakes use the reloaded certificates after the explicit reload
+
+	err = writeCerts(newCA, 3, oldCA, newCA)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	err = server.ReloadTLSConfig()
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	serial, err := serverSerial(newCAClientCert)
+	if err != nil {
+		t.Errorf("client certificate of the reloaded CA is rejected : %s", err)
+	} else if serial != 3 {
+		t.Errorf("%d != %d", serial, 3)
+	}
+
+	// Invalid files keep the current configuration
+
+	err = os.WriteFile(keyFile, []byte("invalid"), 0o600)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	if err := server.ReloadTLSConfig(); err == nil {
+		t.Errorf("invalid key is reloaded")
+	}
+	if serial, err := serverSerial(oldClientCert); err != nil || serial != 3 {
+		t.Errorf("current configuration is not kept : %d (%v)", serial, err)
+	}
+
+	// Existing connections are not interrupted
+
+	if err := hello(existingConn); err != nil {
+		t.Errorf("existing connection is interrupted : %s", err)
+	}
+
+	// A key which does not match the certificate keeps all of the current files
+
+	_, otherKeyPEM, err := newCA.Issue(4, "localhost", x509.ExtKeyUsageServerAuth)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	for file, data := range map[string][]byte{keyFile: otherKeyPEM, caFile: oldCA.CertPEM()} {
+		if err := os.WriteFile(file, data, 0o600); err != nil {
+			t.Error(err)
+			return
+		}
+	}
+	if err := server.ReloadTLSConfig(); err == nil {
+		t.Errorf("mismatched key is reloaded")
+	}
+
+	// A root certificate file which has no certificates keeps all of the current files
+
+	err = writeCerts(newCA, 5, newCA)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	err = os.WriteFile(caFile, []byte("invalid"), 0o600)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	if err := server.ReloadTLSConfig(); err == nil {
+		t.Errorf("invalid root certificate is reloaded")
+	}
+
+	// The configuration is rebuilt from the current files on restart
+
+	err = server.Restart()
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	if serial, err := serverSerial(newCAClientCert); err != nil || serial != 3 {
+		t.Errorf("current configuration is not kept : %d (%v)", serial, err)
+	}
+}
+
+func TestTLSReloadWatchServer(t *testing.T) {
+	log.EnableStdoutDebug(true)
+
+	ca, err := newTestCA("go-mongo test CA")
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	dir := t.TempDir()
+	keyFile := filepath.Join(dir, "key.pem")
+	certFile := filepath.Join(dir, "cert.pem")
+
+	writeCerts := func(serial int64) error {
+		certPEM, keyPEM, err := ca.Issue(serial, "localhost", x509.ExtKeyUsageServerAuth)
+		if err != nil {
+			return err
+		}
+		if err := os.WriteFile(keyFile, keyPEM, 0o600); err != nil {
+			return err
+		}
+		return os.WriteFile(certFile, certPEM, 0o600)
+	}
+
+	err = writeCerts(2)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	server := NewServer()
+	server.SetTLSEnabled(true)
+	server.SetClientAuthType(tls.NoClientCert)
+	server.SetTLSReloadInterval(50 * time.Millisecond)
+	err = server.SetServerKeyFile(keyFile)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	err = server.SetServerCertFile(certFile)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	startTestServer(t, server)
+
+	rootCAs := x509.NewCertPool()
+	rootCAs.AddCert(ca.cert)
+
+	serverSerial := func() (int64, error) {
+		conn, err := tls.Dial("tcp", "localhost:27017", &tls.Config{
+			MinVersion: tls.VersionTLS12,
+			RootCAs:    rootCAs,
+			ServerName: "localhost",
+		})
+		if err != nil {
+			return 0, err
+		}
+		defer conn.Close()
+		return conn.ConnectionState().PeerCertificates[0].SerialNumber.Int64(), nil
+	}
+
+	serial, err := serverSerial()
+	if err != nil || serial != 2 {
+		t.Errorf("%d != %d (%v)", serial, 2, err)
+		return
+	}
+
+	// The watcher reloads the changed files
+
+	err = writeCerts(3)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	deadline := time.Now().Add(2 * time.Second)
+	for time.Now().Before(deadline) {
+		serial, err = serverSerial()
+		if err == nil && serial == 3 {
+			return
+		}
+		time.Sleep(50 * time.Millisecond)
+	}
+	t.Errorf("certificate files are not reloaded : %d (%v)", serial, err)
+}
@@ -0,0 +1,70 @@
+// Copyright (C) 2022 The go-mongo Authors All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package mongotest
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/cybergarage/go-logger/log"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"go.mongodb.org/mongo-driver/mongo/writeconcern"
+)
+
+func TestUnacknowledgedWriteServer(t *testing.T) {
+	log.EnableStdoutDebug(true)
+
+	server := NewServer()
+	startTestServer(t, server)
+
+	// Connect to MongoDB using the Go Driver with a single connection
+
+	client := connectTestClient(t, options.Client().SetMaxPoolSize(1))
+
+	// Insert documents with w:0, the server must not reply to them
+
+	collOpts := options.Collection().SetWriteConcern(writeconcern.New(writeconcern.W(0)))
+	collection := client.Database("test").Collection("unacknowledged", collOpts)
+
+	trainers := []Trainer{
+		{"Ash", 10, "Pallet Town"},
+		{"Misty", 10, "Cerulean City"},
+	}
+
+	for _, trainer := range trainers {
+		_, err := collection.InsertOne(context.TODO(), trainer)
+		if err != nil && !errors.Is(err, mongo.ErrUnacknowledgedWrite) {
+			t.Error(err)
+			return
+		}
+	}
+
+	// Find the inserted documents with the same connection
+
+	for _, trainer := range trainers {
+		var result Trainer
+		err := collection.FindOne(context.TODO(), bson.D{{Key: "name", Value: trainer.Name}}).Decode(&result)
+		if err != nil {
+			t.Error(err)
+			return
+		}
+		if result != trainer {
+			t.Errorf("Found result is not matched : (%v != %v)", result, trainer)
+		}
+	}
+}
@@ -0,0 +1,178 @@
+// Copyright (C) 2022 The go-mongo Authors All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package mongotest
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/cybergarage/go-logger/log"
+	"github.com/cybergarage/go-mongo/mongo/auth"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+func TestX509AuthenticationServer(t *testing.T) {
+	log.EnableStdoutDebug(true)
+
+	server := NewServer()
+	server.SetTLSEnabled(true)
+	server.SetServerKey(TestSeverKey)
+	server.SetServerCert(TestServerCert)
+	server.SetRootCerts(TestCACert)
+	server.SetAuthrizationEnabled(true)
+
+	subject := "CN=localhost"
+	err := server.AccessManager().CreateUser(
+		auth.NewUser(auth.NewUserName(subject, auth.ExternalDatabase), auth.NewRoleName(auth.RoleReadWrite, "test")))
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	startTestServer(t, server)
+
+	tlsConfig, err := server.TLSConfig()
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	connect := func(t *testing.T, cred *options.Credential) *mongo.Client {
+		t.Helper()
+		clientOptions := options.Client().ApplyURI(testTLSDBURL).SetTLSConfig(tlsConfig).SetMaxPoolSize(1)
+		if cred != nil {
+			clientOptions.SetAuth(*cred)
+		}
+		client, err := mongo.Connect(context.TODO(), clientOptions)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return client
+	}
+
+	trainer := Trainer{"Erika", 19, "Celadon City"}
+
+	// The certificate subject is authenticated as the $external user
+
+	t.Run("authenticated", func(t *testing.T) {
+		client := connect(t, &options.Credential{AuthMechanism: "MONGODB-X509"})
+		defer client.Disconnect(context.TODO())
+
+		collection := client.Database("test").Collection("x509")
+		_, err := collection.InsertOne(context.TODO(), trainer)
+		if err != nil {
+			t.Error(err)
+			return
+		}
+
+		err = client.Database("admin").RunCommand(context.TODO(), bson.D{{Key: "usersInfo", Value: 1}}).Err()
+		var cmdErr mongo.CommandError
+		if !errors.As(err, &cmdErr) || cmdErr.Code != 13 {
+			t.Errorf("%v is not an unauthorized error", err)
+		}
+	})
+
+	// The authenticate command marks the connection authenticated
+
+	t.Run("authenticate command", func(t *testing.T) {
+		client := connect(t, nil)
+		defer client.Disconnect(context.TODO())
+
+		collection := client.Database("test").Collection("x509")
+		err := collection.FindOne(context.TODO(), bson.D{}).Err()
+		var cmdErr mongo.CommandError
+		if !errors.As(err, &cmdErr) || cmdErr.Code != 13 {
+			t.Errorf("%v is not an unauthorized error", err)
+			return
+		}
+
+		external := client.Database(auth.ExternalDatabase)
+		err = external.RunCommand(context.TODO(),
+			bson.D{{Key: "authenticate", Value: 1}, {Key: "mechanism", Value: "PLAIN"}}).Err()
+		if !errors.As(err, &cmdErr) || cmdErr.Code != 334 {
+			t.Errorf("%v is not a mechanism unavailable error", err)
+		}
+
+		// The user field must match the certificate subject
+
+		err = external.RunCommand(context.TODO(),
+			bson.D{{Key: "authenticate", Value: 1}, {Key: "mechanism", Value: "MONGODB-X509"}, {Key: "user", Value: "CN=other"}}).Err()
+		if !errors.As(err, &cmdErr) || cmdErr.Code != 18 {
+			t.Errorf("%v is not an authentication failed error", err)
+		}
+
+		var res struct {
+			User   string `bson:"user"`
+			DBName string `bson:"dbname"`
+		}
+		err = external.RunCommand(context.TODO(),
+			bson.D{{Key: "authenticate", Value: 1}, {Key: "mechanism", Value: "MONGODB-X509"}, {Key: "user", Value: subject}}).Decode(&res)
+		if err != nil {
+			t.Error(err)
+			return
+		}
+		if res.User != subject || res.DBName != auth.ExternalDatabase {
+			t.Errorf("invalid response : %v", res)
+		}
+
+		var result Trainer
+		err = collection.FindOne(context.TODO(), bson.D{{Key: "name", Value: trainer.Name}}).Decode(&result)
+		if err != nil {
+			t.Error(err)
+			return
+		}
+		if result != trainer {
+			t.Errorf("%v != %v", result, trainer)
+		}
+	})
+
+	// Failed attempts lock only the connection out as SASL authentication
+
+	t.Run("failed attempts", func(t *testing.T) {
+		convMgr := server.ConversationManager()
+		convMgr.SetFailedAttemptLimit(2)
+		convMgr.SetFailedAttemptBackoff(time.Minute)
+
+		authenticate := func(client *mongo.Client, user string) error {
+			return client.Database(auth.ExternalDatabase).RunCommand(context.TODO(),
+				bson.D{{Key: "authenticate", Value: 1}, {Key: "mechanism", Value: "MONGODB-X509"}, {Key: "user", Value: user}}).Err()
+		}
+
+		failedClient := connect(t, nil)
+		defer failedClient.Disconnect(context.TODO())
+
+		for _, user := range []string{"CN=other1", "CN=other2"} {
+			if err := authenticate(failedClient, user); err == nil {
+				t.Errorf("%s is authenticated", user)
+			}
+		}
+		var cmdErr mongo.CommandError
+		err := authenticate(failedClient, subject)
+		if !errors.As(err, &cmdErr) || cmdErr.Code != 18 || !strings.Contains(cmdErr.Message, "too many failed") {
+			t.Errorf("%v is not a too many failed attempts error", err)
+		}
+
+		client := connect(t, nil)
+		defer client.Disconnect(context.TODO())
+		if err := authenticate(client, subject); err != nil {
+			t.Error(err)
+		}
+	})
+}